@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addRunFlags(applyCmd)
+	applyCmd.Flags().StringVar(&PlanBundle, "plan-bundle", "", "Directory of the plan bundle created by 'runiac plan'")
+	applyCmd.MarkFlagRequired("plan-bundle")
+
+	rootCmd.AddCommand(applyCmd)
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply the plans in a plan bundle",
+	Long: `This will apply exactly the plans saved by 'runiac plan'. A step is refused if its files or the outputs
+of the steps before it changed since it was planned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		run(cmd, "apply")
+	},
+}
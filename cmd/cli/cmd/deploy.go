@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -19,208 +20,284 @@ import (
 )
 
 var (
-	AppVersion      string
-	Environment     string
-	PrimaryRegions  []string
-	RegionalRegions []string
-	DryRun          bool
-	SelfDestroy     bool
-	Account         string
-	LogLevel        string
-	Interactive     bool
-	Container       string = "docker.io/runiac/deploy:latest-alpine-full"
-	Namespace       string
-	DeploymentRing  string
-	Local           bool
-	Runner          string
-	PullRequest     string
-	StepWhitelist   []string
-	Dockerfile      string = ".runiac/Dockerfile"
-	ContainerEngine string = "docker"
-	Test            bool   = false
+	AppVersion       string
+	Environment      string
+	PrimaryRegions   []string
+	RegionalRegions  []string
+	DryRun           bool
+	SelfDestroy      bool
+	Account          string
+	LogLevel         string
+	Interactive      bool
+	Container        string = "docker.io/runiac/deploy:latest-alpine-full"
+	Namespace        string
+	DeploymentRing   string
+	Local            bool
+	Runner           string
+	PullRequest      string
+	StepWhitelist    []string
+	TrackWhitelist   []string
+	Approval         string
+	ApprovalRegional bool
+	ApprovalTimeout  string
+	Dockerfile       string = ".runiac/Dockerfile"
+	ContainerEngine  string = "docker"
+	Test             bool   = false
+	LockTimeout      string
 )
 
+// containerPlanBundleDir is where the plan bundle is mounted within the runiac container
+const containerPlanBundleDir = "/runiac/planbundle"
+
+// containerHostname is the hostname of the runiac container. Terraform records it in the holder of state locks,
+// which is how 'runiac unlock' recognizes the locks left behind by interrupted runiac executions.
+const containerHostname = "runiac"
+
 func init() {
-	deployCmd.Flags().StringVarP(&AppVersion, "version", "v", "", "Version of the iac code")
-	deployCmd.Flags().StringVarP(&Environment, "environment", "e", "", "Targeted environment")
-	deployCmd.Flags().StringVarP(&Account, "account", "a", "", "Targeted Cloud Account (ie. azure subscription, gcp project or aws account)")
-	deployCmd.Flags().StringArrayVarP(&PrimaryRegions, "primary-regions", "p", []string{}, "Primary regions")
-	deployCmd.Flags().StringArrayVarP(&RegionalRegions, "regional-regions", "r", []string{}, "Runiac will concurrently execute the ./regional directory across these regions setting the runiac_region input variable")
-	deployCmd.Flags().BoolVar(&DryRun, "dry-run", false, "Dry Run")
+	addRunFlags(deployCmd)
 	deployCmd.Flags().BoolVar(&SelfDestroy, "self-destroy", false, "Teardown after running deploy")
-	deployCmd.Flags().StringVar(&LogLevel, "log-level", "", "Log level")
-	deployCmd.Flags().BoolVar(&Interactive, "interactive", false, "Run Docker container in interactive mode")
-	deployCmd.Flags().StringVarP(&Container, "container", "c", Container, "The runiac deploy container to execute in.")
-	deployCmd.Flags().StringVarP(&DeploymentRing, "deployment-ring", "d", "", "The deployment ring to configure")
-	deployCmd.Flags().BoolVar(&Local, "local", false, "Pre-configure settings to create an isolated configuration specific to the executing machine")
-	deployCmd.Flags().StringVarP(&Runner, "runner", "", "terraform", "The deployment tool to use for deploying infrastructure")
-	deployCmd.Flags().StringSliceVarP(&StepWhitelist, "steps", "s", []string{}, "Only run the specified steps. To specify steps inside a track: -s {trackName}/{stepName}.  To run multiple steps, separate with a comma.  If empty, it will run all steps. To run no steps, specify a non-existent step.")
-	deployCmd.Flags().StringVar(&PullRequest, "pull-request", "", "Pre-configure settings to create an isolated configuration specific to a pull request, provide pull request identifier")
-	deployCmd.Flags().StringVarP(&Dockerfile, "dockerfile", "f", Dockerfile, "The dockerfile runiac builds to execute the deploy in, defaults to the autogenerated '%s' and must derive from runiac/deploy:{version}-alpine. Runiac official dockerfiles are here: https://github.com/runiac/docker")
-	deployCmd.Flags().StringVar(&ContainerEngine, "container-engine", ContainerEngine, "Container engine (ie. podman or docker)")
-	deployCmd.Flags().BoolVar(&Test, "test", Test, "Hidden flag only set during unit testing")
-	deployCmd.Flags().MarkHidden("test")
+	deployCmd.Flags().StringVar(&Approval, "approval", "", "Pause for approval after planning and before applying. Approvals are received from: tty, file (drop {gate}.approved or {gate}.rejected in .runiac/approvals) or http (POST localhost:8484/approvals/{gate}/approve)")
+	deployCmd.Flags().BoolVar(&ApprovalRegional, "approval-regional", false, "Also pause for approval between the primary region and regional deployments of each track")
+	deployCmd.Flags().StringVar(&ApprovalTimeout, "approval-timeout", "", "How long to wait for approval before skipping the remaining steps, e.g. 30m (default 1h)")
 
 	rootCmd.AddCommand(deployCmd)
 }
 
+// addRunFlags registers the flags shared by the commands that execute the runiac container
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&AppVersion, "version", "v", "", "Version of the iac code")
+	cmd.Flags().StringVarP(&Environment, "environment", "e", "", "Targeted environment")
+	cmd.Flags().StringVarP(&Account, "account", "a", "", "Targeted Cloud Account (ie. azure subscription, gcp project or aws account)")
+	cmd.Flags().StringArrayVarP(&PrimaryRegions, "primary-regions", "p", []string{}, "Primary regions")
+	cmd.Flags().StringArrayVarP(&RegionalRegions, "regional-regions", "r", []string{}, "Runiac will concurrently execute the ./regional directory across these regions setting the runiac_region input variable")
+	cmd.Flags().BoolVar(&DryRun, "dry-run", false, "Dry Run")
+	cmd.Flags().StringVar(&LogLevel, "log-level", "", "Log level")
+	cmd.Flags().BoolVar(&Interactive, "interactive", false, "Run Docker container in interactive mode")
+	cmd.Flags().StringVarP(&Container, "container", "c", Container, "The runiac deploy container to execute in.")
+	cmd.Flags().StringVarP(&DeploymentRing, "deployment-ring", "d", "", "The deployment ring to configure")
+	cmd.Flags().BoolVar(&Local, "local", false, "Pre-configure settings to create an isolated configuration specific to the executing machine")
+	cmd.Flags().StringVarP(&Runner, "runner", "", "terraform", "The deployment tool to use for steps whose runner is not set in their runiac.yml or detected from their files")
+	cmd.Flags().StringSliceVarP(&StepWhitelist, "steps", "s", []string{}, "Only run the specified steps. To specify steps inside a track: -s {trackName}/{stepName}.  To run multiple steps, separate with a comma.  If empty, it will run all steps. To run no steps, specify a non-existent step.")
+	cmd.Flags().StringSliceVarP(&TrackWhitelist, "tracks", "t", []string{}, "Only run the specified tracks. To run multiple tracks, separate with a comma.  If empty, it will run all tracks.")
+	cmd.Flags().StringVar(&PullRequest, "pull-request", "", "Pre-configure settings to create an isolated configuration specific to a pull request, provide pull request identifier")
+	cmd.Flags().StringVarP(&Dockerfile, "dockerfile", "f", Dockerfile, "The dockerfile runiac builds to execute the deploy in, defaults to the autogenerated '%s' and must derive from runiac/deploy:{version}-alpine. Runiac official dockerfiles are here: https://github.com/runiac/docker")
+	cmd.Flags().StringVar(&ContainerEngine, "container-engine", ContainerEngine, "Container engine (ie. podman or docker)")
+	cmd.Flags().StringVar(&LockTimeout, "lock-timeout", "", "How long terraform waits for a held state lock before failing, e.g. 5m (default fail immediately)")
+	cmd.Flags().BoolVar(&Test, "test", Test, "Hidden flag only set during unit testing")
+	cmd.Flags().MarkHidden("test")
+}
+
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploy configurations",
 	Long:  `This will execute the deploy action for each step.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// These options can be set via config file.
-		// The command line option, if set, always takes precendence.
-		setStringFlag(cmd, &ContainerEngine, "container-engine", "container_engine")
-		setStringFlag(cmd, &Container, "container", "container")
-		setStringFlag(cmd, &Dockerfile, "dockerfile", "dockerfile")
-
-		// This condition is only met during unit testing.
-		// It should come after any setup / option parsing and precendence steps.
-		if Test {
-			return
-		}
+		run(cmd, "deploy")
+	},
+}
 
-		checkDockerExists()
+// run builds the project container and executes the action for each targeted step within it
+func run(cmd *cobra.Command, action string) {
+	// These options can be set via config file.
+	// The command line option, if set, always takes precendence.
+	setStringFlag(cmd, &ContainerEngine, "container-engine", "container_engine")
+	setStringFlag(cmd, &Container, "container", "container")
+	setStringFlag(cmd, &Dockerfile, "dockerfile", "dockerfile")
+
+	// This condition is only met during unit testing.
+	// It should come after any setup / option parsing and precendence steps.
+	if Test {
+		return
+	}
 
-		ok := checkInitialized()
-		if !ok {
-			fmt.Printf("You need to run 'runiac init' before you can use the CLI in this directory\n")
-			return
-		}
+	checkDockerExists()
 
-		buildKit := "DOCKER_BUILDKIT=1"
-		containerTag := viper.GetString("project")
+	ok := checkInitialized()
+	if !ok {
+		fmt.Printf("You need to run 'runiac init' before you can use the CLI in this directory\n")
+		return
+	}
 
-		cmdd := exec.Command(ContainerEngine, "build", "-t", containerTag, "-f", Dockerfile)
+	buildKit := "DOCKER_BUILDKIT=1"
+	containerTag := viper.GetString("project")
 
-		cmdd.Args = append(cmdd.Args, getBuildArguments()...)
+	cmdd := exec.Command(ContainerEngine, "build", "-t", containerTag, "-f", Dockerfile)
 
-		logrus.Info(strings.Join(cmdd.Args, " "))
+	cmdd.Args = append(cmdd.Args, getBuildArguments()...)
 
-		var stdoutBuf, stderrBuf bytes.Buffer
+	logrus.Info(strings.Join(cmdd.Args, " "))
 
-		cmdd.Env = append(os.Environ(), buildKit)
-		s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
-		s.Suffix = " Building project container..."
+	var stdoutBuf, stderrBuf bytes.Buffer
 
-		if Dockerfile != "" {
-			cmdd.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
-			cmdd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
+	cmdd.Env = append(os.Environ(), buildKit)
+	s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
+	s.Suffix = " Building project container..."
 
-			err := cmdd.Run()
-			if err != nil {
-				log.Fatalf("Runiac failed to build %s", Dockerfile)
-			}
-		} else {
-			s.Start()
-			b, err := cmdd.CombinedOutput()
-			if err != nil {
-				s.Stop()
-				logrus.Error(string(b))
-				logrus.WithError(err).Fatalf("Building project container failed with %s\n", err)
-			}
+	if Dockerfile != "" {
+		cmdd.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
+		cmdd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
 
+		err := cmdd.Run()
+		if err != nil {
+			log.Fatalf("Runiac failed to build %s", Dockerfile)
+		}
+	} else {
+		s.Start()
+		b, err := cmdd.CombinedOutput()
+		if err != nil {
 			s.Stop()
+			logrus.Error(string(b))
+			logrus.WithError(err).Fatalf("Building project container failed with %s\n", err)
 		}
 
-		logrus.Info("Completed build, lets run!")
+		s.Stop()
+	}
 
-		cmd2 := exec.Command(ContainerEngine, "run", "--rm")
+	logrus.Info("Completed build, lets run!")
 
-		cmd2.Env = append(os.Environ(), buildKit)
+	cmd2 := exec.Command(ContainerEngine, "run", "--rm", "--hostname", containerHostname)
 
-		// pre-configure for local development experience
-		if Local {
-			namespace, err := getMachineName()
+	cmd2.Env = append(os.Environ(), buildKit)
 
-			if err != nil {
-				logrus.WithError(err).Fatal(err)
-			}
+	// pre-configure for local development experience
+	if Local {
+		namespace, err := getMachineName()
 
-			Namespace = namespace
-			DeploymentRing = "local"
-		} else if PullRequest != "" {
-			Namespace = PullRequest
-			DeploymentRing = "pr"
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
 		}
 
-		cmd2.Args = appendEIfSet(cmd2.Args, "DEPLOYMENT_RING", DeploymentRing)
-		cmd2.Args = appendEIfSet(cmd2.Args, "RUNNER", Runner)
-		cmd2.Args = appendEIfSet(cmd2.Args, "NAMESPACE", Namespace)
-		cmd2.Args = appendEIfSet(cmd2.Args, "VERSION", AppVersion)
-		cmd2.Args = appendEIfSet(cmd2.Args, "ENVIRONMENT", Environment)
-		cmd2.Args = appendEIfSet(cmd2.Args, "DRY_RUN", fmt.Sprintf("%v", DryRun))
-		cmd2.Args = appendEIfSet(cmd2.Args, "SELF_DESTROY", fmt.Sprintf("%v", SelfDestroy))
-		cmd2.Args = appendEIfSet(cmd2.Args, "STEP_WHITELIST", strings.Join(StepWhitelist, ","))
-
-		if len(PrimaryRegions) > 0 {
-			cmd2.Args = appendEIfSet(cmd2.Args, "PRIMARY_REGION", PrimaryRegions[0])
+		Namespace = namespace
+		DeploymentRing = "local"
+	} else if PullRequest != "" {
+		Namespace = PullRequest
+		DeploymentRing = "pr"
+	}
+
+	cmd2.Args = appendEIfSet(cmd2.Args, "DEPLOYMENT_RING", DeploymentRing)
+	cmd2.Args = appendEIfSet(cmd2.Args, "RUNNER", Runner)
+	cmd2.Args = appendEIfSet(cmd2.Args, "NAMESPACE", Namespace)
+	cmd2.Args = appendEIfSet(cmd2.Args, "VERSION", AppVersion)
+	cmd2.Args = appendEIfSet(cmd2.Args, "ENVIRONMENT", Environment)
+	cmd2.Args = appendEIfSet(cmd2.Args, "DRY_RUN", fmt.Sprintf("%v", DryRun))
+	cmd2.Args = appendEIfSet(cmd2.Args, "SELF_DESTROY", fmt.Sprintf("%v", SelfDestroy))
+	cmd2.Args = appendEIfSet(cmd2.Args, "STEP_WHITELIST", strings.Join(StepWhitelist, ","))
+	cmd2.Args = appendEIfSet(cmd2.Args, "TRACK_WHITELIST", strings.Join(TrackWhitelist, ","))
+	cmd2.Args = appendEIfSet(cmd2.Args, "ACTION", action)
+	cmd2.Args = appendEIfSet(cmd2.Args, "DRIFT_FAIL_SEVERITY", DriftFailSeverity)
+	cmd2.Args = appendEIfSet(cmd2.Args, "TERRAFORM_LOCK_TIMEOUT", LockTimeout)
+	cmd2.Args = appendEIfSet(cmd2.Args, "TERRAFORM_UNLOCK_OLDER_THAN", UnlockOlderThan)
+
+	if len(PrimaryRegions) > 0 {
+		cmd2.Args = appendEIfSet(cmd2.Args, "PRIMARY_REGION", PrimaryRegions[0])
+	}
+
+	if len(RegionalRegions) > 0 {
+		cmd2.Args = appendEIfSet(cmd2.Args, "REGIONAL_REGIONS", strings.Join(RegionalRegions, ","))
+	}
+	cmd2.Args = appendEIfSet(cmd2.Args, "ACCOUNT_ID", Account)
+	cmd2.Args = appendEIfSet(cmd2.Args, "LOG_LEVEL", LogLevel)
+
+	cmd2.Args = appendEIfSet(cmd2.Args, "APPROVAL_SOURCE", Approval)
+	cmd2.Args = appendEIfSet(cmd2.Args, "APPROVAL_TIMEOUT", ApprovalTimeout)
+
+	if ApprovalRegional {
+		cmd2.Args = appendE(cmd2.Args, "APPROVAL_REGIONAL", "true")
+	}
+
+	// approval prompts are answered through the container's terminal
+	if Approval == "tty" {
+		Interactive = true
+	}
+
+	// expose the approval endpoint on the same port locally
+	if Approval == "http" {
+		cmd2.Args = append(cmd2.Args, "-p", "8484:8484")
+	}
+
+	if Interactive {
+		cmd2.Args = append(cmd2.Args, "-it")
+	}
+
+	// TODO: how best to allow consumer whitelist environment variables or simply pass all in?
+	for _, env := range cmd2.Env {
+		if strings.HasPrefix(env, "TF_VAR_") {
+			cmd2.Args = append(cmd2.Args, "-e", env)
 		}
 
-		if len(RegionalRegions) > 0 {
-			cmd2.Args = appendEIfSet(cmd2.Args, "REGIONAL_REGIONS", strings.Join(RegionalRegions, ","))
+		if strings.HasPrefix(env, "ARM_") {
+			cmd2.Args = append(cmd2.Args, "-e", env)
 		}
-		cmd2.Args = appendEIfSet(cmd2.Args, "ACCOUNT_ID", Account)
-		cmd2.Args = appendEIfSet(cmd2.Args, "LOG_LEVEL", LogLevel)
 
-		if Interactive {
-			cmd2.Args = append(cmd2.Args, "-it")
+		if strings.HasPrefix(env, "RUNIAC_") {
+			cmd2.Args = append(cmd2.Args, "-e", env)
 		}
 
-		// TODO: how best to allow consumer whitelist environment variables or simply pass all in?
-		for _, env := range cmd2.Env {
-			if strings.HasPrefix(env, "TF_VAR_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+		if strings.HasPrefix(env, "AWS_") {
+			cmd2.Args = append(cmd2.Args, "-e", env)
+		}
+	}
 
-			if strings.HasPrefix(env, "ARM_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+	// handle local volume maps
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-			if strings.HasPrefix(env, "RUNIAC_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+	// persist azure cli between container executions
+	cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.azure:/root/.azure", dir))
 
-			if strings.HasPrefix(env, "AWS_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
-		}
+	// persist Azure Az Powershell module between container executions. .azure and .Azure are treated as two separate folders on linux, and they can't both be bound to same location on host
+	cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.azurepowershell:/root/.Azure", dir))
 
-		// handle local volume maps
-		dir, err := os.Getwd()
-		if err != nil {
-			log.Fatal(err)
-		}
+	// persist gcloud cli
+	cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.config/gcloud:/root/.config/gcloud", dir))
 
-		// persist azure cli between container executions
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.azure:/root/.azure", dir))
+	// persist aws cli
+	cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.aws:/root/.aws", dir))
 
-		// persist Azure Az Powershell module between container executions. .azure and .Azure are treated as two separate folders on linux, and they can't both be bound to same location on host
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.azurepowershell:/root/.Azure", dir))
+	// persist local terraform state between container executions
+	cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/tfstate:/runiac/tfstate", dir))
 
-		// persist gcloud cli
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.config/gcloud:/root/.config/gcloud", dir))
+	// approval files are dropped in the project's .runiac directory
+	if Approval == "file" {
+		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/approvals:/runiac/approvals", dir))
+	}
+
+	// share the plan bundle between plan and apply executions
+	if PlanBundle != "" {
+		planBundleDir, err := filepath.Abs(PlanBundle)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		// persist aws cli
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.aws:/root/.aws", dir))
+		err = os.MkdirAll(planBundleDir, 0755)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		// persist local terraform state between container executions
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/tfstate:/runiac/tfstate", dir))
+		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s:%s", planBundleDir, containerPlanBundleDir))
+		cmd2.Args = appendE(cmd2.Args, "PLAN_BUNDLE_DIR", containerPlanBundleDir)
+	}
 
-		cmd2.Args = append(cmd2.Args, containerTag)
+	cmd2.Args = append(cmd2.Args, containerTag)
 
-		logrus.Info(strings.Join(cmd2.Args, " "))
+	logrus.Info(strings.Join(cmd2.Args, " "))
 
-		cmd2.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
-		cmd2.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-		cmd2.Stdin = os.Stdin
+	cmd2.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
+	cmd2.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
+	cmd2.Stdin = os.Stdin
 
-		err2 := cmd2.Run()
-		if err2 != nil {
-			log.Fatalf("Running iac failed with %s\n", err2)
+	err2 := cmd2.Run()
+	if err2 != nil {
+		// preserve the container's exit code, drift detection reports drift with a distinct code
+		if exitErr, ok := err2.(*exec.ExitError); ok {
+			logrus.Errorf("Running iac failed with %s", err2)
+			os.Exit(exitErr.ExitCode())
 		}
-	},
+
+		log.Fatalf("Running iac failed with %s\n", err2)
+	}
 }
 
 // setStringFlag - If flag is changed via command line, do nothing, else check config file for value.
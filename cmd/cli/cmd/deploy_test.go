@@ -67,3 +67,63 @@ func Test_DeployCommand(t *testing.T) {
 	require.Equal(t, "mockofseagulls", ContainerEngine)
 	require.Equal(t, "mockofseagulls", Container)
 }
+
+func Test_DestroyCommand(t *testing.T) {
+	cmd := rootCmd
+
+	// Assert config values are used when command line is not present
+	cmd.SetArgs([]string{"destroy", "--test", "--tracks=network,compute"})
+	viper.Set("container_engine", "mock")
+	viper.Set("container", "mock")
+	viper.Set("dockerfile", "mock")
+
+	cmd.Execute()
+	require.Equal(t, "mock", Dockerfile)
+	require.Equal(t, "mock", ContainerEngine)
+	require.Equal(t, "mock", Container)
+	require.Equal(t, []string{"network", "compute"}, TrackWhitelist)
+}
+
+func Test_DriftCommand(t *testing.T) {
+	cmd := rootCmd
+
+	cmd.SetArgs([]string{"drift", "--test", "--fail-severity=deleted"})
+	viper.Set("container_engine", "mock")
+	viper.Set("container", "mock")
+	viper.Set("dockerfile", "mock")
+
+	cmd.Execute()
+	require.Equal(t, "mock", ContainerEngine)
+	require.Equal(t, "deleted", DriftFailSeverity)
+}
+
+func Test_PlanAndApplyCommands_ShouldSetPlanBundle(t *testing.T) {
+	cmd := rootCmd
+
+	cmd.SetArgs([]string{"plan", "--test", "--plan-bundle=./bundle"})
+	cmd.Execute()
+	require.Equal(t, "./bundle", PlanBundle)
+
+	cmd.SetArgs([]string{"apply", "--test", "--plan-bundle=./other-bundle"})
+	cmd.Execute()
+	require.Equal(t, "./other-bundle", PlanBundle)
+}
+
+func Test_DeployCommand_ShouldSetApproval(t *testing.T) {
+	cmd := rootCmd
+
+	cmd.SetArgs([]string{"deploy", "--test", "--approval=file", "--approval-regional", "--approval-timeout=30m"})
+	cmd.Execute()
+	require.Equal(t, "file", Approval)
+	require.True(t, ApprovalRegional)
+	require.Equal(t, "30m", ApprovalTimeout)
+}
+
+func Test_UnlockCommand(t *testing.T) {
+	cmd := rootCmd
+
+	cmd.SetArgs([]string{"unlock", "--test", "--older-than=2h", "--lock-timeout=5m"})
+	cmd.Execute()
+	require.Equal(t, "2h", UnlockOlderThan)
+	require.Equal(t, "5m", LockTimeout)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+var Yes bool
+
+func init() {
+	addRunFlags(destroyCmd)
+	destroyCmd.Flags().BoolVarP(&Yes, "yes", "y", false, "Skip the confirmation prompt and destroy immediately")
+
+	rootCmd.AddCommand(destroyCmd)
+}
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "Destroy configurations",
+	Long: `This will execute the destroy action for each step without deploying first.
+Step outputs are read from existing state so downstream steps receive the values they were deployed with.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !Yes && !Test {
+			exitUnlessConfirmed("Destroy", confirmDestroy)
+		}
+
+		run(cmd, "destroy")
+	},
+}
+
+func confirmDestroy() (bool, error) {
+	prompt := &survey.Confirm{
+		Message: "This will destroy all resources managed by the targeted steps. Do you want to continue?",
+	}
+
+	confirm := false
+	err := survey.AskOne(prompt, &confirm)
+	if err != nil {
+		return false, err
+	}
+
+	return confirm, nil
+}
+
+// exitUnlessConfirmed exits non-zero when the prompt fails or is declined, so CI can tell a cancelled run from a successful one
+func exitUnlessConfirmed(action string, confirm func() (bool, error)) {
+	ok, err := confirm()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s cancelled, unable to confirm: %s\n", action, err)
+		os.Exit(1)
+	}
+
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s cancelled\n", action)
+		os.Exit(1)
+	}
+}
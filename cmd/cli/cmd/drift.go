@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var DriftFailSeverity string
+
+func init() {
+	addRunFlags(driftCmd)
+	driftCmd.Flags().StringVar(&DriftFailSeverity, "fail-severity", "", "The minimum drift severity that results in a non-zero exit code: changed, deleted or never (default changed)")
+
+	rootCmd.AddCommand(driftCmd)
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Detect drift in deployed configurations",
+	Long: `This will check each step for resources changed outside of runiac without applying any changes.
+Drifted steps are reported, and the exit code is 2 when drift at or above the fail severity is detected.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		run(cmd, "drift")
+	},
+}
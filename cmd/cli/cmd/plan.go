@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var PlanBundle string
+
+func init() {
+	addRunFlags(planCmd)
+	planCmd.Flags().StringVar(&PlanBundle, "plan-bundle", "", "Directory to save the plan bundle to, must be empty")
+	planCmd.MarkFlagRequired("plan-bundle")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Plan configurations into a plan bundle",
+	Long: `This will plan each step without applying and save every plan, along with a manifest and checksums,
+to the plan bundle directory. Apply the bundle later with 'runiac apply --plan-bundle'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		run(cmd, "plan")
+	},
+}
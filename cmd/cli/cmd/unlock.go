@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+var UnlockOlderThan string
+
+func init() {
+	addRunFlags(unlockCmd)
+	unlockCmd.Flags().StringVar(&UnlockOlderThan, "older-than", "", "Only release locks held longer than this, e.g. 2h (default 1h)")
+	unlockCmd.Flags().BoolVarP(&Yes, "yes", "y", false, "Skip the confirmation prompt and release stale locks immediately")
+
+	rootCmd.AddCommand(unlockCmd)
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Release stale terraform state locks",
+	Long: `This will check the state of each step for a lock left behind by an interrupted runiac execution and force-unlock it.
+Only locks held by a runiac container, on the step's own workspace and older than --older-than are released.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !Yes && !Test {
+			exitUnlessConfirmed("Unlock", confirmUnlock)
+		}
+
+		run(cmd, "unlock")
+	},
+}
+
+func confirmUnlock() (bool, error) {
+	prompt := &survey.Confirm{
+		Message: "This will force-unlock stale state locks of the targeted steps. Make sure no other runiac execution is running against them. Do you want to continue?",
+	}
+
+	confirm := false
+	err := survey.AskOne(prompt, &confirm)
+	if err != nil {
+		return false, err
+	}
+
+	return confirm, nil
+}
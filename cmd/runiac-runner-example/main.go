@@ -0,0 +1,91 @@
+// Command runiac-runner-example is a reference runner plugin. It registers the "example" runner, which deploys a step
+// by publishing the contents of the step's outputs.json as its output variables.
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/runnerplugin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// outputsFile holds the output variables of a step deployed by the example runner
+const outputsFile = "outputs.json"
+
+func main() {
+	err := runnerplugin.Serve("example", ExampleStepper{}, ExamplePlugin{})
+	if err != nil {
+		logrus.WithError(err).Error("Unable to serve runner plugin request")
+		os.Exit(1)
+	}
+}
+
+type ExamplePlugin struct{}
+
+func (ExamplePlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac example runner plugin")
+}
+
+type ExampleStepper struct{}
+
+func (ExampleStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+func (stepper ExampleStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	if exec.DryRun {
+		exec.Logger.Infof("Dry run, step %s would publish %s", exec.StepName, outputsFile)
+	} else {
+		exec.Logger.Infof("Deploying step %s", exec.StepName)
+	}
+
+	return stepper.ExecuteStepOutputs(exec)
+}
+
+func (ExampleStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return config.StepTestOutput{
+		StepName: exec.StepName,
+	}
+}
+
+func (ExampleStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	exec.Logger.Infof("Destroying step %s", exec.StepName)
+
+	return config.StepOutput{
+		Status:           config.Success,
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+	}
+}
+
+func (ExampleStepper) ExecuteStepOutputs(exec config.StepExecution) config.StepOutput {
+	output := config.StepOutput{
+		Status:           config.Success,
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		OutputVariables:  map[string]interface{}{},
+	}
+
+	data, err := afero.ReadFile(exec.Fs, filepath.Join(exec.Dir, outputsFile))
+	if os.IsNotExist(err) {
+		return output
+	}
+
+	if err == nil {
+		err = json.Unmarshal(data, &output.OutputVariables)
+	}
+
+	if err != nil {
+		exec.Logger.WithError(err).Error(err)
+		output.Status = config.Fail
+		output.Err = err
+	}
+
+	return output
+}
@@ -1,21 +1,33 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/optum/runiac/pkg/config"
 	"github.com/optum/runiac/pkg/logging"
+	"github.com/optum/runiac/pkg/planbundle"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/pkg/runnerplugin"
 	"github.com/optum/runiac/pkg/tracks"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 
-	pluginsarm "github.com/optum/runiac/plugins/arm"
-	pluginsterraform "github.com/optum/runiac/plugins/terraform"
+	// runners register themselves when imported
+	_ "github.com/optum/runiac/plugins/arm"
+	_ "github.com/optum/runiac/plugins/bicep"
+	_ "github.com/optum/runiac/plugins/cloudformation"
+	_ "github.com/optum/runiac/plugins/helm"
+	_ "github.com/optum/runiac/plugins/pulumi"
+	_ "github.com/optum/runiac/plugins/shell"
+	_ "github.com/optum/runiac/plugins/terraform"
 )
 
 var fs afero.Fs
@@ -28,12 +40,55 @@ func main() {
 
 	log.Debugf("Beginning Account Deployment: %s", deployment.Config.AccountID)
 
+	if deployment.Config.Action == config.DestroyAction {
+		destroy()
+		return
+	}
+
+	if deployment.Config.Action == config.DriftAction {
+		drift()
+		return
+	}
+
+	if deployment.Config.Action == config.UnlockAction {
+		unlock()
+		return
+	}
+
+	switch deployment.Config.Action {
+	case config.PlanAction:
+		if err := planbundle.Prepare(fs, deployment.Config.PlanBundleDir); err != nil {
+			log.WithError(err).Fatal("Unable to create plan bundle")
+		}
+	case config.ApplyAction:
+		manifest, err := planbundle.ReadManifest(fs, deployment.Config.PlanBundleDir)
+		if err == nil {
+			err = manifest.Verify(deployment.Config)
+		}
+
+		if err != nil {
+			log.WithError(err).Fatal("Unable to apply plan bundle")
+		}
+	}
+
 	log.Debug("Executing tracks...")
 
 	output := tracker.ExecuteTracks(deployment.Config)
 
 	log.Debug("Completed executing tracks...")
 
+	var planBundleErr error
+	if deployment.Config.Action == config.PlanAction {
+		var manifest planbundle.Manifest
+		manifest, planBundleErr = planbundle.WriteManifest(fs, deployment.Config.PlanBundleDir, planbundle.NewManifest(deployment.Config))
+
+		if planBundleErr != nil {
+			log.WithError(planBundleErr).Error("Unable to write plan bundle manifest")
+		} else {
+			log.Infof("Saved %d plan(s) to plan bundle %s", len(manifest.Entries), deployment.Config.PlanBundleDir)
+		}
+	}
+
 	trackCount := len(output.Tracks)
 	failedSteps := []string{}
 	skippedSteps := []string{}
@@ -93,6 +148,11 @@ func main() {
 		result = "fail"
 	}
 
+	if planBundleErr != nil {
+		resultMessage += "  Failed to write plan bundle manifest."
+		result = "fail"
+	}
+
 	slog := log.WithFields(logrus.Fields{
 		"type":          "summary",
 		"skipped":       strings.Join(skippedSteps, ","),
@@ -109,6 +169,235 @@ func main() {
 	}
 }
 
+// destroy destroys the targeted tracks without deploying them and summarizes the result
+func destroy() {
+	log.Debug("Destroying tracks...")
+
+	output := tracker.DestroyTracks(deployment.Config)
+
+	log.Debug("Completed destroying tracks...")
+
+	trackCount := len(output.Tracks)
+	failedDestroySteps := []string{}
+	skippedTracks := []string{}
+	stepCount := 0
+	executedStepCount := 0
+
+	for _, t := range output.Tracks {
+		if t.Skipped {
+			skippedTracks = append(skippedTracks, t.Name)
+		}
+
+		for _, tExecution := range t.DestroyOutput.Executions {
+			executedStepCount += tExecution.Output.ExecutedCount
+			stepCount += tExecution.Output.ExecutedCount + tExecution.Output.SkippedCount
+
+			for _, fStep := range tExecution.Output.FailedSteps {
+				failedDestroySteps = append(failedDestroySteps, fmt.Sprintf("%v/%v/%v/%v", t.Name, fStep.Name, tExecution.RegionDeployType, tExecution.Region))
+			}
+		}
+	}
+
+	resultMessage := fmt.Sprintf("Destroyed %v/%v steps successfully across %v track(s).",
+		executedStepCount-len(failedDestroySteps), stepCount, trackCount-len(skippedTracks))
+
+	result := "success"
+
+	if len(failedDestroySteps) > 0 {
+		resultMessage += fmt.Sprintf("  Failed to destroy: %v.", strings.Join(failedDestroySteps, ", "))
+		result = "fail"
+	}
+
+	slog := log.WithFields(logrus.Fields{
+		"type":   "summary",
+		"action": config.DestroyAction,
+		"failed": strings.Join(failedDestroySteps, ","),
+		"result": result,
+	})
+
+	if result == "success" {
+		slog.Info(resultMessage)
+	} else {
+		slog.Error(resultMessage)
+		os.Exit(1)
+	}
+}
+
+// driftDetectedExitCode is returned when drift at or above the configured severity is detected, matching terraform's detailed exit code
+const driftDetectedExitCode = 2
+
+// drift checks the targeted tracks for drift without applying changes and reports the drifted steps
+func drift() {
+	log.Debug("Detecting drift...")
+
+	output := tracker.ExecuteTracks(deployment.Config)
+
+	log.Debug("Completed detecting drift...")
+
+	failSeverity, err := config.ParseDriftSeverity(deployment.Config.DriftFailSeverity)
+	failOnDrift := err == nil
+
+	trackCount := len(output.Tracks)
+	failedSteps := []string{}
+	skippedSteps := []string{}
+	skippedTracks := []string{}
+	driftedSteps := []string{}
+	stepCount := 0
+	failingDrift := false
+
+	for _, t := range output.Tracks {
+		if t.Skipped {
+			skippedTracks = append(skippedTracks, t.Name)
+		}
+
+		for _, tExecution := range t.Output.Executions {
+			stepCount += tExecution.Output.ExecutedCount + tExecution.Output.SkippedCount
+
+			for _, s := range tExecution.Output.Steps {
+				stepID := fmt.Sprintf("%v/%v/%v/%v", t.Name, s.Name, tExecution.RegionDeployType, tExecution.Region)
+
+				switch s.Output.Status {
+				case config.Fail:
+					failedSteps = append(failedSteps, stepID)
+				case config.Skipped:
+					skippedSteps = append(skippedSteps, stepID)
+				}
+
+				if s.Output.Drift == nil || s.Output.Drift.Severity == config.DriftNone {
+					continue
+				}
+
+				resources := []string{}
+				for _, r := range s.Output.Drift.Resources {
+					resources = append(resources, r.Address)
+				}
+
+				log.WithFields(logrus.Fields{
+					"type":      "drift",
+					"step":      stepID,
+					"severity":  s.Output.Drift.Severity.String(),
+					"resources": strings.Join(resources, ","),
+				}).Warnf("Drift detected in %s: %s", stepID, strings.Join(resources, ", "))
+
+				driftedSteps = append(driftedSteps, fmt.Sprintf("%v (%v)", stepID, s.Output.Drift.Severity))
+
+				if failOnDrift && s.Output.Drift.Severity >= failSeverity {
+					failingDrift = true
+				}
+			}
+		}
+	}
+
+	resultMessage := fmt.Sprintf("Checked %v steps for drift across %v track(s), %v drifted.",
+		stepCount-len(skippedSteps), trackCount-len(skippedTracks), len(driftedSteps))
+
+	result := "success"
+	exitCode := 0
+
+	if len(driftedSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Drifted: %v.", strings.Join(driftedSteps, ", "))
+
+		if failingDrift {
+			result = "drift"
+			exitCode = driftDetectedExitCode
+		}
+	}
+
+	if len(skippedSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Skipped: %v.", strings.Join(skippedSteps, ", "))
+		result = "fail"
+		exitCode = 1
+	}
+
+	if len(failedSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Failed: %v.", strings.Join(failedSteps, ", "))
+		result = "fail"
+		exitCode = 1
+	}
+
+	slog := log.WithFields(logrus.Fields{
+		"type":    "summary",
+		"action":  config.DriftAction,
+		"drifted": strings.Join(driftedSteps, ","),
+		"skipped": strings.Join(skippedSteps, ","),
+		"failed":  strings.Join(failedSteps, ","),
+		"result":  result,
+	})
+
+	if exitCode == 0 {
+		slog.Info(resultMessage)
+	} else {
+		slog.Error(resultMessage)
+		os.Exit(exitCode)
+	}
+}
+
+// unlock releases the stale state locks of the targeted tracks and reports the steps whose locks could not be released
+func unlock() {
+	log.Debug("Releasing stale state locks...")
+
+	output := tracker.ExecuteTracks(deployment.Config)
+
+	log.Debug("Completed releasing stale state locks...")
+
+	trackCount := len(output.Tracks)
+	failedSteps := []string{}
+	skippedSteps := []string{}
+	skippedTracks := []string{}
+	stepCount := 0
+
+	for _, t := range output.Tracks {
+		if t.Skipped {
+			skippedTracks = append(skippedTracks, t.Name)
+		}
+
+		for _, tExecution := range t.Output.Executions {
+			stepCount += tExecution.Output.ExecutedCount + tExecution.Output.SkippedCount
+
+			for _, s := range tExecution.Output.Steps {
+				stepID := fmt.Sprintf("%v/%v/%v/%v", t.Name, s.Name, tExecution.RegionDeployType, tExecution.Region)
+
+				switch s.Output.Status {
+				case config.Fail:
+					failedSteps = append(failedSteps, stepID)
+				case config.Skipped:
+					skippedSteps = append(skippedSteps, stepID)
+				}
+			}
+		}
+	}
+
+	resultMessage := fmt.Sprintf("Checked %v steps for stale state locks across %v track(s).",
+		stepCount-len(skippedSteps), trackCount-len(skippedTracks))
+
+	result := "success"
+
+	if len(skippedSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Skipped: %v.", strings.Join(skippedSteps, ", "))
+		result = "fail"
+	}
+
+	if len(failedSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Failed: %v.", strings.Join(failedSteps, ", "))
+		result = "fail"
+	}
+
+	slog := log.WithFields(logrus.Fields{
+		"type":    "summary",
+		"action":  config.UnlockAction,
+		"skipped": strings.Join(skippedSteps, ","),
+		"failed":  strings.Join(failedSteps, ","),
+		"result":  result,
+	})
+
+	if result == "success" {
+		slog.Info(resultMessage)
+	} else {
+		slog.Error(resultMessage)
+		os.Exit(1)
+	}
+}
+
 func initFunc() {
 	// Log as JSON instead of the default ASCII formatter.
 	logger := logrus.New()
@@ -130,12 +419,24 @@ func initFunc() {
 
 	fs = afero.NewOsFs()
 
+	// register runner plugins before the configured runner is validated
+	runnerplugin.Register(log, runnerplugin.DefaultPluginDir)
+
 	deployment.Config, err = config.GetConfig()
 
 	if err != nil {
 		log.WithError(err).Fatal(err.Error())
 	}
 
+	// stopping the container stops retries and approval gates, and skips the steps that have not started. Signals are
+	// only caught once, so a second one exits without waiting on the steps that are running.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	deployment.Config.Context = ctx
+
 	// Only log the warning severity or above.
 	lvl, err := logrus.ParseLevel(deployment.Config.LogLevel)
 
@@ -179,29 +480,34 @@ func initFunc() {
 		"uniqueExternalExecutionID": deployment.Config.UniqueExternalExecutionID,
 	})
 
+	// share one retry budget across every step so a systemic outage fails the run quickly
+	if deployment.Config.RetryBudget > 0 {
+		retry.SetBudget(retry.NewBudget(deployment.Config.RetryBudget))
+	}
+
+	// approval gates only pause deployments
+	var approver tracks.Approver
+	if deployment.Config.Action == config.DeployAction {
+		approver, err = tracks.NewApprover(deployment.Config, log, fs)
+
+		if err != nil {
+			log.WithError(err).Fatal("Unable to configure approval gates")
+		}
+	}
+
 	// init tracker last to ensure log configuration is set correctly
 	tracker = tracks.DirectoryBasedTracker{
-		Log: log,
-		Fs:  fs,
+		Log:      log,
+		Fs:       fs,
+		Approver: approver,
 	}
 
 	// initialize the runner plugin
-	plugin, err := getRunnerPlugin(deployment.Config)
-	if err != nil {
-		log.WithError(err).Error("Could not determine runner plugin")
+	err = config.InitializeRunner(deployment.Config.Runner, deployment.Config, log)
+	if errors.Is(err, config.ErrRunnerNotRegistered) {
+		log.WithError(err).Errorf("Could not determine runner plugin, registered runners: %v", config.RunnerNames())
 		return
-	}
-
-	plugin.Initialize(log)
-}
-
-func getRunnerPlugin(config config.Config) (config.RunnerPlugin, error) {
-	switch config.Runner {
-	case "arm":
-		return pluginsarm.ArmPlugin{}, nil
-	case "terraform":
-		return pluginsterraform.TerraformPlugin{}, nil
-	default:
-		return nil, errors.New("Invalid runner")
+	} else if err != nil {
+		log.WithError(err).Fatalf("Unable to initialize the %s runner", deployment.Config.Runner)
 	}
 }
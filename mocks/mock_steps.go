@@ -61,6 +61,20 @@ func (mr *MockStepperMockRecorder) ExecuteStepDestroy(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteStepDestroy", reflect.TypeOf((*MockStepper)(nil).ExecuteStepDestroy), arg0)
 }
 
+// ExecuteStepOutputs mocks base method
+func (m *MockStepper) ExecuteStepOutputs(arg0 config.StepExecution) config.StepOutput {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecuteStepOutputs", arg0)
+	ret0, _ := ret[0].(config.StepOutput)
+	return ret0
+}
+
+// ExecuteStepOutputs indicates an expected call of ExecuteStepOutputs
+func (mr *MockStepperMockRecorder) ExecuteStepOutputs(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteStepOutputs", reflect.TypeOf((*MockStepper)(nil).ExecuteStepOutputs), arg0)
+}
+
 // ExecuteStepTests mocks base method
 func (m *MockStepper) ExecuteStepTests(arg0 config.StepExecution) config.StepTestOutput {
 	m.ctrl.T.Helper()
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
@@ -23,18 +24,30 @@ type Config struct {
 	TargetAccountID string   `mapstructure:"account_id"`       // The target account being deployed to using the delivery framework (use ACCOUNT_ID env for compatibility)
 	RegionalRegions []string `mapstructure:"regional_regions"` // runiac will apply regional step deployments across these regions
 	PrimaryRegion   string   `mapstructure:"primary_region" required:"true"`
-	DryRun          bool     `mapstructure:"dry_run"` // DryRun will only execute up to Terraform plan, describing what will happen if deployed
-	Runner          string   `mapstructure:"runner"`  // Delivery framework to invoke for executing steps
+	DryRun          bool     `mapstructure:"dry_run"`         // DryRun will only execute up to Terraform plan, describing what will happen if deployed
+	Runner          string   `mapstructure:"runner"`          // Delivery framework to invoke for executing steps
+	Action          string   `mapstructure:"action"`          // The action to execute against the targeted tracks (deploy, destroy, drift, plan, apply or unlock)
+	PlanBundleDir   string   `mapstructure:"plan_bundle_dir"` // The plan bundle written by the plan action and applied by the apply action
+
+	ApprovalSource   string        `mapstructure:"approval_source"`   // Where approvals are received from at approval gates (tty, file or http), gates are disabled when empty
+	ApprovalRegional bool          `mapstructure:"approval_regional"` // Pause for approval between the primary region and regional deployments of each track
+	ApprovalTimeout  time.Duration `mapstructure:"approval_timeout"`  // How long to wait at an approval gate before skipping the remaining steps
+	ApprovalDir      string        `mapstructure:"approval_dir"`      // The directory watched for approval files when the approval source is file
+	ApprovalAddr     string        `mapstructure:"approval_addr"`     // The address the approval endpoint listens on when the approval source is http
+
+	DriftFailSeverity string `mapstructure:"drift_fail_severity"` // The minimum drift severity that fails a drift run (changed, deleted or never)
 
 	UniqueExternalExecutionID string
 	DeploymentRing            string `mapstructure:"deployment_ring"`
 	SelfDestroy               bool   `mapstructure:"self_destroy"` // Destroy will automatically execute Terraform Destroy after running deployments & tests
 	RegionGroup               string
-	StepWhitelist             []string        `mapstructure:"step_whitelist"` // Target_Steps is a comma separated list of step ids to reflect the whitelisted steps to be executed, e.g. core#logging#final_destination_bucket, core#logging#bridge_azu
+	StepWhitelist             []string        `mapstructure:"step_whitelist"`  // Target_Steps is a comma separated list of step ids to reflect the whitelisted steps to be executed, e.g. core#logging#final_destination_bucket, core#logging#bridge_azu
+	TrackWhitelist            []string        `mapstructure:"track_whitelist"` // A comma separated list of track names to be executed, e.g. _pretrack,networking
 	TargetAll                 bool            // This is a global whitelist and overrules targeted tracks and targeted steps, primarily for dev and testing
 	Version                   string          `mapstructure:"version"` // Version override
 	MaxRetries                int             `mapstructure:"max_retries"`
 	MaxTestRetries            int             `mapstructure:"max_test_retries"`
+	RetryBudget               int             `mapstructure:"retry_budget"` // The maximum number of retries across every step of the run, unlimited when zero
 	LogLevel                  string          `mapstructure:"log_level"`
 	CoreAccounts              CoreAccountsMap `mapstructure:"core_accounts"`
 	RegionGroups              RegionGroupsMap `mapstructure:"region_groups"`
@@ -42,6 +55,8 @@ type Config struct {
 	Namespace   string `mapstructure:"namespace"`                   // The namespace to use in the Terraform run.
 	Environment string `mapstructure:"environment" required:"true"` // The name of the environment (e.g. pr, nonprod, prod)
 	Project     string `mapstructure:"project" required:"true"`
+
+	Context context.Context `mapstructure:"-" json:"-"` // Cancelled when the run is stopped, e.g. by SIGTERM, which stops retries and approval gates
 }
 
 type RegionGroupsMap map[string]map[string][]string
@@ -77,6 +92,28 @@ type Deployment struct {
 	//DeployMetadata        DeployMetadata
 }
 
+const (
+	DeployAction  = "deploy"  // Deploy executes each targeted step, followed by tests and an optional self destroy
+	DestroyAction = "destroy" // Destroy tears down each targeted step in reverse order without deploying first
+	DriftAction   = "drift"   // Drift refreshes each targeted step against its deployed resources and reports changes without applying
+	PlanAction    = "plan"    // Plan executes each targeted step as a dry run and saves the plans to a plan bundle
+	ApplyAction   = "apply"   // Apply applies the plans saved in a plan bundle without planning again
+	UnlockAction  = "unlock"  // Unlock releases stale state locks left behind by interrupted runiac executions
+)
+
+// LockHolderHostname is the hostname of runiac containers, which terraform records in the holder of state locks.
+// Only locks held by this hostname are released by the unlock action.
+const LockHolderHostname = "runiac"
+
+const (
+	TTYApprovalSource  = "tty"  // Prompt for approval on the interactive terminal
+	FileApprovalSource = "file" // Wait for an approval file to be dropped in the approval directory
+	HTTPApprovalSource = "http" // Wait for approval through the local approval endpoint
+)
+
+// NeverFailOnDrift disables failing a drift run regardless of the drift detected
+const NeverFailOnDrift = "never"
+
 // DeployMetadata ...
 type DeployMetadata struct {
 	Version   string `json:"version"`
@@ -132,9 +169,19 @@ func GetConfig() (Config, error) {
 	_ = viper.BindEnv("regional_regions")
 	_ = viper.BindEnv("max_retries")
 	_ = viper.BindEnv("max_test_retries")
+	_ = viper.BindEnv("retry_budget")
 	_ = viper.BindEnv("account_id")
 	_ = viper.BindEnv("runner")
 	_ = viper.BindEnv("step_whitelist")
+	_ = viper.BindEnv("track_whitelist")
+	_ = viper.BindEnv("action")
+	_ = viper.BindEnv("drift_fail_severity")
+	_ = viper.BindEnv("plan_bundle_dir")
+	_ = viper.BindEnv("approval_source")
+	_ = viper.BindEnv("approval_regional")
+	_ = viper.BindEnv("approval_timeout")
+	_ = viper.BindEnv("approval_dir")
+	_ = viper.BindEnv("approval_addr")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -150,6 +197,13 @@ func GetConfig() (Config, error) {
 		LogLevel:       logrus.InfoLevel.String(),
 		Project:        "runiac",
 		TargetAll:      true,
+		Action:         DeployAction,
+
+		DriftFailSeverity: DriftChanged.String(),
+
+		ApprovalTimeout: time.Hour,
+		ApprovalDir:     "/runiac/approvals",
+		ApprovalAddr:    ":8484",
 	}
 	err := viper.Unmarshal(conf)
 
@@ -166,8 +220,13 @@ func GetConfig() (Config, error) {
 		return *conf, err
 	}
 
-	// if step whitelist is set, respect it
-	if conf.TargetAll && len(conf.StepWhitelist) > 0 {
+	// plans are only saved, never applied, when creating a plan bundle
+	if conf.Action == PlanAction {
+		conf.DryRun = true
+	}
+
+	// if step or track whitelist is set, respect it
+	if conf.TargetAll && (len(conf.StepWhitelist) > 0 || len(conf.TrackWhitelist) > 0) {
 		conf.TargetAll = false
 	}
 
@@ -185,7 +244,35 @@ func InputValidation(sl validator.StructLevel) {
 		sl.ReportError(input.Namespace, "primary_region", "primaryRegion", "required-primary-region", "")
 	}
 
-	if input.Runner != "terraform" && input.Runner != "arm" {
+	if _, ok := GetRunner(input.Runner); !ok {
 		sl.ReportError(input.Runner, "runner", "runner", "invalid-runner", "")
 	}
+
+	switch input.Action {
+	case DeployAction, DestroyAction, DriftAction, UnlockAction:
+	case PlanAction, ApplyAction:
+		if input.PlanBundleDir == "" {
+			sl.ReportError(input.PlanBundleDir, "plan_bundle_dir", "planBundleDir", "required-plan-bundle-dir", "")
+		}
+
+		if input.Action == ApplyAction && input.DryRun {
+			sl.ReportError(input.DryRun, "dry_run", "dryRun", "invalid-dry-run-apply", "")
+		}
+	default:
+		sl.ReportError(input.Action, "action", "action", "invalid-action", "")
+	}
+
+	if input.RetryBudget < 0 {
+		sl.ReportError(input.RetryBudget, "retry_budget", "retryBudget", "invalid-retry-budget", "")
+	}
+
+	switch input.ApprovalSource {
+	case "", TTYApprovalSource, FileApprovalSource, HTTPApprovalSource:
+	default:
+		sl.ReportError(input.ApprovalSource, "approval_source", "approvalSource", "invalid-approval-source", "")
+	}
+
+	if _, err := ParseDriftSeverity(input.DriftFailSeverity); err != nil && input.DriftFailSeverity != NeverFailOnDrift {
+		sl.ReportError(input.DriftFailSeverity, "drift_fail_severity", "driftFailSeverity", "invalid-drift-fail-severity", "")
+	}
 }
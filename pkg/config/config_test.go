@@ -10,7 +10,12 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 )
 
@@ -30,6 +35,24 @@ eyes : brown
 beard: true
 `)
 
+type stubStepper struct {
+	Stepper
+}
+
+func TestMain(m *testing.M) {
+	// runners register themselves from their own packages, which cannot be imported here
+	RegisterRunner(Runner{
+		Name:       "terraform",
+		NewStepper: func() Stepper { return stubStepper{} },
+		Detect: func(fs afero.Fs, dir string) bool {
+			exists, _ := afero.Exists(fs, filepath.Join(dir, "main.tf"))
+			return exists
+		},
+	})
+
+	os.Exit(m.Run())
+}
+
 func TestGetConfig_EnvironmentVariablesShouldMatch(t *testing.T) {
 	t.Parallel()
 
@@ -78,20 +101,30 @@ func TestConfigMapstructureTags_ShouldMatchBindEnvKeys(t *testing.T) {
 
 	// These are the keys registered via viper.BindEnv() in GetConfig()
 	boundKeys := map[string]bool{
-		"environment":      true,
-		"namespace":        true,
-		"project":          true,
-		"log_level":        true,
-		"dry_run":          true,
-		"self_destroy":     true,
-		"deployment_ring":  true,
-		"primary_region":   true,
-		"regional_regions": true,
-		"max_retries":      true,
-		"max_test_retries": true,
-		"account_id":       true,
-		"runner":           true,
-		"step_whitelist":   true,
+		"environment":         true,
+		"namespace":           true,
+		"project":             true,
+		"log_level":           true,
+		"dry_run":             true,
+		"self_destroy":        true,
+		"deployment_ring":     true,
+		"primary_region":      true,
+		"regional_regions":    true,
+		"max_retries":         true,
+		"max_test_retries":    true,
+		"retry_budget":        true,
+		"account_id":          true,
+		"runner":              true,
+		"step_whitelist":      true,
+		"track_whitelist":     true,
+		"action":              true,
+		"drift_fail_severity": true,
+		"plan_bundle_dir":     true,
+		"approval_source":     true,
+		"approval_regional":   true,
+		"approval_timeout":    true,
+		"approval_dir":        true,
+		"approval_addr":       true,
 	}
 
 	// Verify every mapstructure tag with a BindEnv key actually resolves
@@ -235,3 +268,132 @@ func TestNoNonConstantFormatStrings(t *testing.T) {
 		return nil
 	})
 }
+
+func TestRegisterRunner_ShouldRejectInvalidRunners(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() { RegisterRunner(Runner{Name: "terraform", NewStepper: func() Stepper { return stubStepper{} }}) }, "Duplicate names should panic")
+	require.Panics(t, func() { RegisterRunner(Runner{NewStepper: func() Stepper { return stubStepper{} }}) }, "Empty names should panic")
+	require.Panics(t, func() { RegisterRunner(Runner{Name: "nostepper"}) }, "Runners without a stepper should panic")
+
+	_, ok := GetRunner("nostepper")
+	require.False(t, ok)
+	require.Contains(t, RunnerNames(), "terraform")
+}
+
+func TestDetectRunner_ShouldReturnRunnerWithMatchingFiles(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/tf/main.tf", []byte{}, 0644))
+	require.NoError(t, afero.WriteFile(fs, "/other/main.json", []byte{}, 0644))
+
+	runner, ok := DetectRunner(fs, "/tf")
+	require.True(t, ok)
+	require.Equal(t, "terraform", runner.Name)
+
+	_, ok = DetectRunner(fs, "/other")
+	require.False(t, ok)
+}
+
+func TestInputValidation_ShouldRequireRegisteredRunner(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New()
+	validate.RegisterStructValidation(InputValidation, Config{})
+
+	cfg := Config{PrimaryRegion: "centralus", Action: DeployAction, DriftFailSeverity: DriftChanged.String()}
+
+	cfg.Runner = "terraform"
+	require.NoError(t, validate.Struct(cfg))
+
+	cfg.Runner = "unregistered"
+	require.Error(t, validate.Struct(cfg))
+}
+
+type stubConfigurablePlugin struct {
+	configureCount int
+}
+
+func (p *stubConfigurablePlugin) Initialize(logger *logrus.Entry) {}
+
+func (p *stubConfigurablePlugin) Configure(cfg Config, logger *logrus.Entry) error {
+	p.configureCount++
+	return fmt.Errorf("%s is not configured for %s", cfg.Runner, cfg.Environment)
+}
+
+func TestInitializeRunner_ShouldReturnConfigureErrorEveryTime(t *testing.T) {
+	t.Parallel()
+
+	plugin := &stubConfigurablePlugin{}
+	RegisterRunner(Runner{Name: "configurable", NewStepper: func() Stepper { return stubStepper{} }, Plugin: plugin})
+
+	cfg := Config{Runner: "configurable", Environment: "prod"}
+	logger := logrus.NewEntry(logrus.New())
+
+	require.EqualError(t, InitializeRunner("configurable", cfg, logger), "configurable is not configured for prod")
+	require.Error(t, InitializeRunner("configurable", cfg, logger))
+	require.Equal(t, 1, plugin.configureCount, "Plugins should be configured once")
+
+	require.ErrorIs(t, InitializeRunner("unregistered", cfg, logger), ErrRunnerNotRegistered)
+}
+
+func TestInputValidation_ShouldRejectNegativeRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New()
+	validate.RegisterStructValidation(InputValidation, Config{})
+
+	cfg := Config{PrimaryRegion: "centralus", Runner: "terraform", Action: DeployAction, DriftFailSeverity: DriftChanged.String(), RetryBudget: 10}
+	require.NoError(t, validate.Struct(cfg))
+
+	cfg.RetryBudget = -1
+	require.Error(t, validate.Struct(cfg))
+}
+
+func TestReadRunnerConfig_ShouldReadSectionAndEnvironmentVariables(t *testing.T) {
+	viper.Set("stubrunner.binary", "tofu")
+	viper.Set("stubrunner.retryable_errors", map[string]string{"dns": "Temporary failure"})
+	t.Setenv("RUNIAC_STUBRUNNER_LOCK_TIMEOUT", "5m")
+
+	runnerConfig := struct {
+		Binary          string            `mapstructure:"binary"`
+		LockTimeout     time.Duration     `mapstructure:"lock_timeout"`
+		UnlockOlderThan time.Duration     `mapstructure:"unlock_older_than"`
+		RetryableErrors map[string]string `mapstructure:"retryable_errors"`
+	}{UnlockOlderThan: time.Hour}
+
+	require.NoError(t, ReadRunnerConfig("stubrunner", &runnerConfig))
+
+	require.Equal(t, "tofu", runnerConfig.Binary)
+	require.Equal(t, 5*time.Minute, runnerConfig.LockTimeout, "Environment variables should set the section's settings")
+	require.Equal(t, time.Hour, runnerConfig.UnlockOlderThan, "Settings that are not set should keep their value")
+	require.Equal(t, map[string]string{"dns": "Temporary failure"}, runnerConfig.RetryableErrors)
+
+	require.Error(t, ReadRunnerConfig("stubrunner", runnerConfig), "Runner config should only be read into a pointer")
+}
+
+func TestReadStepConfig_ShouldReadTheStepsConfigFile(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/tracks/network/step1_vnet/runiac.yaml", []byte("runner: arm\narm:\n  scope: tenant\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/tracks/network/step2_dns/runiac.json", []byte(`{"runner": "terraform"}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/tracks/network/step3_invalid/runiac.yml", []byte("runner: [terraform"), 0644))
+
+	stepConfig, err := ReadStepConfig(fs, "/tracks/network/step1_vnet")
+	require.NoError(t, err)
+	require.Equal(t, "arm", stepConfig.GetString("runner"))
+	require.Equal(t, "tenant", stepConfig.GetString("arm.scope"))
+
+	stepConfig, err = ReadStepConfig(fs, "/tracks/network/step2_dns")
+	require.NoError(t, err)
+	require.Equal(t, "terraform", stepConfig.GetString("runner"))
+
+	stepConfig, err = ReadStepConfig(fs, "/tracks/network/step4_unconfigured")
+	require.NoError(t, err)
+	require.Empty(t, stepConfig.AllKeys(), "Steps without a config file should have an empty configuration")
+
+	_, err = ReadStepConfig(fs, "/tracks/network/step3_invalid")
+	require.Error(t, err)
+}
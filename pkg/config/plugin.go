@@ -1,6 +1,17 @@
 package config
 
-import "github.com/sirupsen/logrus"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
 
 // Interface RunnerPlugin describes capacilities and initializtion for runiac plugins.
 type RunnerPlugin interface {
@@ -8,3 +19,147 @@ type RunnerPlugin interface {
 	// Any user-facing output should be sent to the provided`logger` instance.
 	Initialize(logger *logrus.Entry)
 }
+
+// ConfigurableRunnerPlugin is implemented by plugins that verify the deployment configuration once initialized,
+// e.g. the version of the binary they run. An error stops the deployment before any step executes.
+type ConfigurableRunnerPlugin interface {
+	Configure(cfg Config, logger *logrus.Entry) error
+}
+
+// ErrRunnerNotRegistered is returned when initializing a runner that is not registered
+var ErrRunnerNotRegistered = errors.New("runner is not registered")
+
+// Runner describes a deployment tool that runiac can execute steps with
+type Runner struct {
+	Name       string                             // Value of the runner configuration that selects this runner
+	NewStepper func() Stepper                     // Creates the stepper that executes steps for this runner
+	Plugin     RunnerPlugin                       // Initialized once before any steps execute, optional
+	Detect     func(fs afero.Fs, dir string) bool // Reports whether a step directory contains files for this runner, optional
+}
+
+var (
+	runnersMutex sync.RWMutex
+	runners      = map[string]Runner{}
+	runnerInits  = map[string]*sync.Once{}
+	runnerErrs   = map[string]error{}
+)
+
+// RegisterRunner makes a runner available by name. It is intended to be called from the init function of a runner's package
+// and panics if the runner is incomplete or a runner with the same name is already registered.
+func RegisterRunner(runner Runner) {
+	runnersMutex.Lock()
+	defer runnersMutex.Unlock()
+
+	if runner.Name == "" {
+		panic("config: RegisterRunner runner name is empty")
+	}
+
+	if runner.NewStepper == nil {
+		panic(fmt.Sprintf("config: RegisterRunner runner %s has no stepper", runner.Name))
+	}
+
+	if _, dup := runners[runner.Name]; dup {
+		panic(fmt.Sprintf("config: RegisterRunner called twice for runner %s", runner.Name))
+	}
+
+	runners[runner.Name] = runner
+	runnerInits[runner.Name] = &sync.Once{}
+}
+
+// GetRunner returns the registered runner with the given name
+func GetRunner(name string) (Runner, bool) {
+	runnersMutex.RLock()
+	defer runnersMutex.RUnlock()
+
+	runner, ok := runners[name]
+	return runner, ok
+}
+
+// InitializeRunner initializes and configures the plugin of a registered runner the first time the runner is used.
+// The error configuring the plugin, if any, is returned every time.
+func InitializeRunner(name string, cfg Config, logger *logrus.Entry) error {
+	runnersMutex.RLock()
+	runner, ok := runners[name]
+	once := runnerInits[name]
+	runnersMutex.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRunnerNotRegistered, name)
+	}
+
+	if runner.Plugin == nil {
+		return nil
+	}
+
+	once.Do(func() {
+		runner.Plugin.Initialize(logger)
+
+		if configurable, ok := runner.Plugin.(ConfigurableRunnerPlugin); ok {
+			err := configurable.Configure(cfg, logger)
+
+			runnersMutex.Lock()
+			runnerErrs[name] = err
+			runnersMutex.Unlock()
+		}
+	})
+
+	runnersMutex.RLock()
+	defer runnersMutex.RUnlock()
+
+	return runnerErrs[name]
+}
+
+// ReadRunnerConfig unmarshals the section of the project's runiac.yml named after a runner, e.g. terraform, into the struct
+// rawVal points to. Settings that are not set keep their value. Each setting of the section can also be set by an environment
+// variable, e.g. RUNIAC_TERRAFORM_LOCK_TIMEOUT for lock_timeout in the terraform section.
+func ReadRunnerConfig(section string, rawVal interface{}) error {
+	rt := reflect.TypeOf(rawVal)
+	if rt.Kind() != reflect.Pointer || rt.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("runner config of section %s must be read into a pointer to a struct", section)
+	}
+
+	// nested keys bound to environment variables are only resolved one at a time
+	settings := viper.New()
+	for i := 0; i < rt.Elem().NumField(); i++ {
+		tag, ok := rt.Elem().Field(i).Tag.Lookup("mapstructure")
+		if !ok || tag == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s.%s", section, tag)
+		_ = viper.BindEnv(key, strings.ToUpper(fmt.Sprintf("runiac_%s_%s", section, tag)))
+
+		if viper.IsSet(key) {
+			settings.Set(tag, viper.Get(key))
+		}
+	}
+
+	return settings.Unmarshal(rawVal)
+}
+
+// RunnerNames returns the sorted names of the registered runners
+func RunnerNames() []string {
+	runnersMutex.RLock()
+	defer runnersMutex.RUnlock()
+
+	names := make([]string, 0, len(runners))
+	for name := range runners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DetectRunner returns the first registered runner, in name order, that detects its files within dir
+func DetectRunner(fs afero.Fs, dir string) (Runner, bool) {
+	for _, name := range RunnerNames() {
+		runner, _ := GetRunner(name)
+
+		if runner.Detect != nil && runner.Detect(fs, dir) {
+			return runner, true
+		}
+	}
+
+	return Runner{}, false
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
+	"github.com/spf13/viper"
 )
 
 type StepExecution struct {
@@ -32,9 +38,35 @@ type StepExecution struct {
 	TrackName                  string
 	DryRun                     bool
 	SelfDestroy                bool
+	Action                     string
+	PlanBundleDir              string
 	DefaultStepOutputVariables map[string]map[string]string // Previous step output variables are available in this map. K=StepName,V=map[VarName:VarVal]
 	OptionalStepParams         map[string]string
 	RequiredStepParams         map[string]interface{}
+	Context                    context.Context // Cancelled when the run is stopped, retries stop waiting once it is done
+}
+
+// ReadStepConfig reads the runiac.yml, runiac.yaml or runiac.json of a step directory. Runners unmarshal their own section
+// of it, e.g. terraform. Steps without one have an empty configuration.
+func ReadStepConfig(fs afero.Fs, stepDir string) (*viper.Viper, error) {
+	stepConfig := viper.New()
+	stepConfig.SetFs(fs)
+
+	for _, ext := range []string{"yml", "yaml", "json"} {
+		configFile := filepath.Join(stepDir, fmt.Sprintf("runiac.%s", ext))
+
+		if exists, _ := afero.Exists(fs, configFile); exists {
+			stepConfig.SetConfigFile(configFile)
+
+			if err := stepConfig.ReadInConfig(); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
+	return stepConfig, nil
 }
 
 // Step represents a delivery framework step, e.g. the executions needed to implement a track
@@ -71,6 +103,65 @@ type StepOutput struct {
 	StreamOutput     string
 	Err              error
 	OutputVariables  map[string]interface{}
+	Drift            *StepDrift           // Drift detected while executing the drift action, nil otherwise
+	PlannedChanges   map[string][]string  // Resource addresses by planned action, e.g. K=[create] V=[azurerm_resource_group.rg]
+	ErrorClass       *ErrorClassification // How the step's error was classified, nil when the runner does not classify errors
+}
+
+// ErrorClassification describes whether a step's error can go away when the step runs again
+type ErrorClassification struct {
+	Class    string // transient, permanent or unknown
+	Category string // The kind of error, e.g. throttling
+	Pattern  string // The regexp that matched the error
+}
+
+// StepDrift represents the changes made to a step's resources outside of runiac
+type StepDrift struct {
+	Severity  DriftSeverity
+	Resources []DriftedResource
+}
+
+// DriftedResource represents a single resource that no longer matches its deployed configuration
+type DriftedResource struct {
+	Address  string
+	Actions  []string
+	Severity DriftSeverity
+}
+
+// Add records a drifted resource, raising the step severity when needed
+func (d *StepDrift) Add(resource DriftedResource) {
+	d.Resources = append(d.Resources, resource)
+
+	if resource.Severity > d.Severity {
+		d.Severity = resource.Severity
+	}
+}
+
+// DriftSeverity represents how far a step's resources have drifted from their deployed configuration
+type DriftSeverity int
+
+const (
+	// DriftNone indicates the deployed resources match their configuration
+	DriftNone DriftSeverity = iota
+	// DriftChanged indicates at least one resource was modified outside of runiac
+	DriftChanged
+	// DriftDeleted indicates at least one resource was deleted outside of runiac
+	DriftDeleted
+)
+
+func (d DriftSeverity) String() string {
+	return [...]string{"none", "changed", "deleted"}[d]
+}
+
+// ParseDriftSeverity converts a drift severity name into a DriftSeverity
+func ParseDriftSeverity(s string) (DriftSeverity, error) {
+	for _, d := range []DriftSeverity{DriftNone, DriftChanged, DriftDeleted} {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+
+	return DriftNone, fmt.Errorf("invalid drift severity: %s", s)
 }
 
 // TFProviderType represents a Terraform provider type
@@ -98,6 +189,8 @@ type Stepper interface {
 	ExecuteStep(execution StepExecution) (resp StepOutput)
 	ExecuteStepTests(execution StepExecution) (resp StepTestOutput)
 	ExecuteStepDestroy(execution StepExecution) (output StepOutput)
+	// ExecuteStepOutputs reads the outputs of a previously deployed step without changing any resources.
+	ExecuteStepOutputs(execution StepExecution) (output StepOutput)
 }
 
 type DeployResult int
@@ -0,0 +1,344 @@
+// Package planbundle saves the plans created during a plan run so a later apply run can apply exactly those plans.
+package planbundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+)
+
+// ManifestVersion is the version of the manifest format written by this package
+const ManifestVersion = 1
+
+// ManifestFileName is the name of the manifest at the root of a plan bundle
+const ManifestFileName = "manifest.json"
+
+// entryFileName is the name of the file describing a single plan within its entry directory
+const entryFileName = "entry.json"
+
+// ErrEntryNotFound occurs when the plan bundle does not contain a plan for a step and region
+var ErrEntryNotFound = errors.New("plan bundle does not contain a plan for this step")
+
+// Manifest describes every plan saved in a plan bundle and the deployment they were planned for
+type Manifest struct {
+	Version        int       `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	Project        string    `json:"project"`
+	Environment    string    `json:"environment"`
+	Namespace      string    `json:"namespace"`
+	DeploymentRing string    `json:"deployment_ring"`
+	AccountID      string    `json:"account_id"`
+	AppVersion     string    `json:"app_version"`
+	Entries        []Entry   `json:"entries"`
+}
+
+// Entry describes the plan saved for a single step and region
+type Entry struct {
+	Track            string `json:"track"`
+	Step             string `json:"step"`
+	RegionDeployType string `json:"region_deploy_type"`
+	Region           string `json:"region"`
+	PlanFile         string `json:"plan_file"`          // Path of the binary plan, relative to the bundle root
+	PlanChecksum     string `json:"plan_checksum"`      // sha256 of the binary plan
+	PlanJSONFile     string `json:"plan_json_file"`     // Path of the JSON plan, relative to the bundle root
+	PlanJSONChecksum string `json:"plan_json_checksum"` // sha256 of the JSON plan
+	SourceChecksum   string `json:"source_checksum"`    // sha256 of the step's working tree when planned
+	InputsChecksum   string `json:"inputs_checksum"`    // sha256 of the step's inputs, including upstream outputs, when planned
+}
+
+// EntryDir returns the directory, relative to the bundle root, holding the plan for a step execution
+func EntryDir(exec config.StepExecution) string {
+	return filepath.Join(exec.TrackName, exec.StepName, fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region))
+}
+
+// NewManifest creates an empty manifest for the deployment described by cfg
+func NewManifest(cfg config.Config) Manifest {
+	return Manifest{
+		Version:        ManifestVersion,
+		CreatedAt:      time.Now().UTC(),
+		Project:        cfg.Project,
+		Environment:    cfg.Environment,
+		Namespace:      cfg.Namespace,
+		DeploymentRing: cfg.DeploymentRing,
+		AccountID:      cfg.AccountID,
+		AppVersion:     cfg.Version,
+		Entries:        []Entry{},
+	}
+}
+
+// Verify ensures the manifest was planned for the deployment described by cfg
+func (m Manifest) Verify(cfg config.Config) error {
+	if m.Version != ManifestVersion {
+		return fmt.Errorf("plan bundle manifest version %d is not supported, expected %d", m.Version, ManifestVersion)
+	}
+
+	expected := NewManifest(cfg)
+	mismatches := []string{}
+
+	for _, field := range []struct{ name, bundle, current string }{
+		{"project", m.Project, expected.Project},
+		{"environment", m.Environment, expected.Environment},
+		{"namespace", m.Namespace, expected.Namespace},
+		{"deployment ring", m.DeploymentRing, expected.DeploymentRing},
+		{"account id", m.AccountID, expected.AccountID},
+		{"app version", m.AppVersion, expected.AppVersion},
+	} {
+		if field.bundle != field.current {
+			mismatches = append(mismatches, fmt.Sprintf("%s (planned %q, current %q)", field.name, field.bundle, field.current))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("plan bundle was created for a different deployment: %s", strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
+
+// Find returns the entry for a step execution
+func (m Manifest) Find(exec config.StepExecution) (Entry, error) {
+	for _, e := range m.Entries {
+		if e.Track == exec.TrackName && e.Step == exec.StepName && e.RegionDeployType == exec.RegionDeployType.String() && e.Region == exec.Region {
+			return e, nil
+		}
+	}
+
+	return Entry{}, ErrEntryNotFound
+}
+
+// Prepare creates an empty plan bundle directory, refusing to mix plans with an existing bundle
+func Prepare(fs afero.Fs, bundleDir string) error {
+	exists, err := afero.DirExists(fs, bundleDir)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		empty, err := afero.IsEmpty(fs, bundleDir)
+		if err != nil {
+			return err
+		}
+
+		if !empty {
+			return fmt.Errorf("plan bundle directory %s is not empty", bundleDir)
+		}
+	}
+
+	return fs.MkdirAll(bundleDir, 0755)
+}
+
+// SavePlan copies a step's binary plan and JSON plan into the bundle and records an entry describing them
+func SavePlan(fs afero.Fs, bundleDir string, exec config.StepExecution, planFile string, planJSON []byte, sourceChecksum string, inputsChecksum string) (entry Entry, err error) {
+	entryDir := EntryDir(exec)
+
+	err = fs.MkdirAll(filepath.Join(bundleDir, entryDir), 0755)
+	if err != nil {
+		return
+	}
+
+	plan, err := afero.ReadFile(fs, planFile)
+	if err != nil {
+		return
+	}
+
+	entry = Entry{
+		Track:            exec.TrackName,
+		Step:             exec.StepName,
+		RegionDeployType: exec.RegionDeployType.String(),
+		Region:           exec.Region,
+		PlanFile:         filepath.Join(entryDir, "tfplan"),
+		PlanChecksum:     checksum(plan),
+		PlanJSONFile:     filepath.Join(entryDir, "tfplan.json"),
+		PlanJSONChecksum: checksum(planJSON),
+		SourceChecksum:   sourceChecksum,
+		InputsChecksum:   inputsChecksum,
+	}
+
+	err = afero.WriteFile(fs, filepath.Join(bundleDir, entry.PlanFile), plan, 0644)
+	if err != nil {
+		return
+	}
+
+	err = afero.WriteFile(fs, filepath.Join(bundleDir, entry.PlanJSONFile), planJSON, 0644)
+	if err != nil {
+		return
+	}
+
+	entryJSON, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = afero.WriteFile(fs, filepath.Join(bundleDir, entryDir, entryFileName), entryJSON, 0644)
+
+	return
+}
+
+// LoadPlan verifies the bundled plan for a step execution still matches its working tree and inputs,
+// then copies the binary plan to planFile so it can be applied
+func LoadPlan(fs afero.Fs, bundleDir string, exec config.StepExecution, planFile string, sourceChecksum string, inputsChecksum string) (entry Entry, err error) {
+	manifest, err := ReadManifest(fs, bundleDir)
+	if err != nil {
+		return
+	}
+
+	entry, err = manifest.Find(exec)
+	if err != nil {
+		return
+	}
+
+	if entry.SourceChecksum != sourceChecksum {
+		return entry, fmt.Errorf("working tree of %s changed since it was planned", EntryDir(exec))
+	}
+
+	if entry.InputsChecksum != inputsChecksum {
+		return entry, fmt.Errorf("inputs of %s, including upstream outputs, changed since it was planned", EntryDir(exec))
+	}
+
+	plan, err := afero.ReadFile(fs, filepath.Join(bundleDir, entry.PlanFile))
+	if err != nil {
+		return
+	}
+
+	if checksum(plan) != entry.PlanChecksum {
+		return entry, fmt.Errorf("plan %s does not match its manifest checksum", entry.PlanFile)
+	}
+
+	// the JSON plan is what reviewers and approvals read, so it must still describe the binary plan being applied
+	planJSON, err := afero.ReadFile(fs, filepath.Join(bundleDir, entry.PlanJSONFile))
+	if err != nil {
+		return
+	}
+
+	if checksum(planJSON) != entry.PlanJSONChecksum {
+		return entry, fmt.Errorf("plan %s does not match its manifest checksum", entry.PlanJSONFile)
+	}
+
+	err = afero.WriteFile(fs, planFile, plan, 0644)
+
+	return
+}
+
+// WriteManifest collects the entries saved in the bundle into its manifest
+func WriteManifest(fs afero.Fs, bundleDir string, manifest Manifest) (Manifest, error) {
+	err := afero.Walk(fs, bundleDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || info.Name() != entryFileName {
+			return nil
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		entry := Entry{}
+		err = json.Unmarshal(data, &entry)
+		if err != nil {
+			return fmt.Errorf("unable to read plan bundle entry %s: %w", path, err)
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+
+		return nil
+	})
+
+	if err != nil {
+		return manifest, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+
+	return manifest, afero.WriteFile(fs, filepath.Join(bundleDir, ManifestFileName), data, 0644)
+}
+
+// ReadManifest reads the manifest of a plan bundle
+func ReadManifest(fs afero.Fs, bundleDir string) (manifest Manifest, err error) {
+	data, err := afero.ReadFile(fs, filepath.Join(bundleDir, ManifestFileName))
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &manifest)
+
+	return
+}
+
+// ChecksumDir returns a checksum of the files within dir, skipping the directories and files matched by skip
+func ChecksumDir(fs afero.Fs, dir string, skip func(relPath string, info os.FileInfo) bool) (string, error) {
+	h := sha256.New()
+
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		if skip != nil && skip(rel, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), checksum(data))
+
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ChecksumInputs returns a checksum of a step's inputs that is independent of map ordering
+func ChecksumInputs(inputs map[string]string) string {
+	keys := make([]string, 0, len(inputs))
+	for k := range inputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\n", k, inputs[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,160 @@
+package planbundle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var stubBundleDir = "/bundle"
+var stubPlanFile = "/step/steptfplan"
+
+func stubExecution() config.StepExecution {
+	return config.StepExecution{
+		TrackName:        "track",
+		StepName:         "step",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Region:           "centralus",
+	}
+}
+
+func stubBundle(t *testing.T) afero.Fs {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, stubPlanFile, []byte("binary plan"), 0644))
+	require.NoError(t, Prepare(fs, stubBundleDir))
+
+	_, err := SavePlan(fs, stubBundleDir, stubExecution(), stubPlanFile, []byte(`{"resource_changes":[]}`), "source", "inputs")
+	require.NoError(t, err)
+
+	manifest, err := WriteManifest(fs, stubBundleDir, NewManifest(config.Config{Project: "runiac"}))
+	require.NoError(t, err)
+	require.Len(t, manifest.Entries, 1)
+
+	return fs
+}
+
+func TestLoadPlan_ShouldCopyPlanWhenUnchanged(t *testing.T) {
+	t.Parallel()
+
+	fs := stubBundle(t)
+
+	entry, err := LoadPlan(fs, stubBundleDir, stubExecution(), "/apply/steptfplan", "source", "inputs")
+
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("track", "step", "primary-centralus", "tfplan"), entry.PlanFile)
+
+	plan, err := afero.ReadFile(fs, "/apply/steptfplan")
+	require.NoError(t, err)
+	require.Equal(t, "binary plan", string(plan))
+}
+
+func TestLoadPlan_ShouldRefuseChanges(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sourceChecksum string
+		inputsChecksum string
+		tamper         string
+		expected       string
+	}{
+		"working tree changed": {sourceChecksum: "changed", inputsChecksum: "inputs", expected: "working tree"},
+		"inputs changed":       {sourceChecksum: "source", inputsChecksum: "changed", expected: "inputs"},
+		"plan tampered":        {sourceChecksum: "source", inputsChecksum: "inputs", tamper: "tfplan", expected: "checksum"},
+		"json plan tampered":   {sourceChecksum: "source", inputsChecksum: "inputs", tamper: "tfplan.json", expected: "tfplan.json does not match"},
+	}
+
+	for name, tc := range tests {
+		fs := stubBundle(t)
+
+		if tc.tamper != "" {
+			require.NoError(t, afero.WriteFile(fs, filepath.Join(stubBundleDir, "track", "step", "primary-centralus", tc.tamper), []byte("tampered"), 0644))
+		}
+
+		_, err := LoadPlan(fs, stubBundleDir, stubExecution(), "/apply/steptfplan", tc.sourceChecksum, tc.inputsChecksum)
+
+		require.Error(t, err, name)
+		require.Contains(t, err.Error(), tc.expected, name)
+
+		exists, _ := afero.Exists(fs, "/apply/steptfplan")
+		require.False(t, exists, "%s: plan should not be copied when refused", name)
+	}
+}
+
+func TestLoadPlan_ShouldErrorWhenStepWasNotPlanned(t *testing.T) {
+	t.Parallel()
+
+	fs := stubBundle(t)
+	exec := stubExecution()
+	exec.Region = "eastus"
+
+	_, err := LoadPlan(fs, stubBundleDir, exec, "/apply/steptfplan", "source", "inputs")
+
+	require.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestManifestVerify_ShouldRefuseDifferentDeployment(t *testing.T) {
+	t.Parallel()
+
+	manifest := NewManifest(config.Config{Project: "runiac", Environment: "nonprod", AccountID: "1"})
+
+	require.NoError(t, manifest.Verify(config.Config{Project: "runiac", Environment: "nonprod", AccountID: "1"}))
+
+	err := manifest.Verify(config.Config{Project: "runiac", Environment: "prod", AccountID: "1"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "environment")
+
+	manifest.AppVersion = "v1.0.0"
+	err = manifest.Verify(config.Config{Project: "runiac", Environment: "nonprod", AccountID: "1", Version: "v1.1.0"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "app version")
+}
+
+func TestPrepare_ShouldRefuseNonEmptyDirectory(t *testing.T) {
+	t.Parallel()
+
+	fs := stubBundle(t)
+
+	require.Error(t, Prepare(fs, stubBundleDir))
+	require.NoError(t, Prepare(fs, "/new-bundle"))
+}
+
+func TestChecksumDir_ShouldSkipAndDetectChanges(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/step/main.tf", []byte("resource"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/step/.terraform/plugins", []byte("plugin"), 0644))
+
+	skip := func(relPath string, info os.FileInfo) bool {
+		return strings.HasPrefix(relPath, ".terraform")
+	}
+
+	original, err := ChecksumDir(fs, "/step", skip)
+	require.NoError(t, err)
+
+	require.NoError(t, afero.WriteFile(fs, "/step/.terraform/plugins", []byte("updated plugin"), 0644))
+	skipped, err := ChecksumDir(fs, "/step", skip)
+	require.NoError(t, err)
+	require.Equal(t, original, skipped, "Skipped files should not change the checksum")
+
+	require.NoError(t, afero.WriteFile(fs, "/step/main.tf", []byte("changed resource"), 0644))
+	changed, err := ChecksumDir(fs, "/step", skip)
+	require.NoError(t, err)
+	require.NotEqual(t, original, changed)
+}
+
+func TestChecksumInputs_ShouldIgnoreOrder(t *testing.T) {
+	t.Parallel()
+
+	a := ChecksumInputs(map[string]string{"a": "1", "b": "2"})
+	b := ChecksumInputs(map[string]string{"b": "2", "a": "1"})
+	c := ChecksumInputs(map[string]string{"a": "1", "b": "3"})
+
+	require.Equal(t, a, b)
+	require.NotEqual(t, a, c)
+}
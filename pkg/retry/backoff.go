@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff returns how long to wait before a retry, numbered from 1, given the wait before the previous retry
+type Backoff func(retry int, previous time.Duration) time.Duration
+
+// randDuration returns a random duration in [0, n)
+var randDuration = func(n time.Duration) time.Duration {
+	return time.Duration(rand.Int64N(int64(n)))
+}
+
+// Fixed waits the same delay before every retry
+func Fixed(delay time.Duration) Backoff {
+	return func(retry int, previous time.Duration) time.Duration {
+		return delay
+	}
+}
+
+// Exponential doubles the delay before every retry, starting at base, up to maxDelay
+func Exponential(base time.Duration, maxDelay time.Duration) Backoff {
+	return func(retry int, previous time.Duration) time.Duration {
+		delay := base
+
+		for i := 1; i < retry && delay < maxDelay; i++ {
+			delay *= 2
+		}
+
+		return min(delay, maxDelay)
+	}
+}
+
+// DecorrelatedJitter waits a random delay between base and three times the previous delay, up to maxDelay. The
+// randomness spreads out the retries of actions that failed at the same time, e.g. when an API throttles every step.
+func DecorrelatedJitter(base time.Duration, maxDelay time.Duration) Backoff {
+	return func(retry int, previous time.Duration) time.Duration {
+		upper := 3 * max(previous, base)
+		if upper <= base {
+			return min(base, maxDelay)
+		}
+
+		return min(base+randDuration(upper-base), maxDelay)
+	}
+}
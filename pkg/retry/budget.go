@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// runBudget is shared by every retried action of the run, retries are unlimited while it is nil
+var runBudget atomic.Pointer[Budget]
+
+// Budget limits the number of retries across actions, so a systemic outage fails a run quickly instead of every
+// action retrying up to its maximum. A nil Budget is unlimited.
+type Budget struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+// NewBudget returns a budget of retries
+func NewBudget(retries int) *Budget {
+	return &Budget{remaining: retries}
+}
+
+// SetBudget sets the budget used by actions whose options do not set one, nil removes the limit
+func SetBudget(budget *Budget) {
+	runBudget.Store(budget)
+}
+
+// Remaining returns the number of retries left in the budget, -1 when it is unlimited
+func (b *Budget) Remaining() int {
+	if b == nil {
+		return -1
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.remaining
+}
+
+// take uses a retry of the budget, returning false when none are left
+func (b *Budget) take() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.remaining <= 0 {
+		return false
+	}
+
+	b.remaining--
+
+	return true
+}
@@ -0,0 +1,60 @@
+package retry
+
+import "errors"
+
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e retryableError) Unwrap() error {
+	return e.err
+}
+
+type nonRetryableError struct {
+	err error
+}
+
+func (e nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+// Retryable marks an error to be retried, even when it wraps a NonRetryable error
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return retryableError{err: err}
+}
+
+// NonRetryable marks an error to stop retrying immediately, e.g. when the action fails the same way every time
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return nonRetryableError{err: err}
+}
+
+// IsRetryable reports whether an error is retried, which is decided by the outermost Retryable or NonRetryable mark.
+// Errors without a mark are retried.
+func IsRetryable(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch err.(type) {
+		case retryableError:
+			return true
+		case nonRetryableError:
+			return false
+		}
+	}
+
+	return true
+}
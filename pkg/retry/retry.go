@@ -3,41 +3,109 @@ package retry
 // This code follows: https://github.com/gruntwork-io/terratest/blob/master/modules/retry/retry.go
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
+// ErrBudgetExhausted occurs when an action is not retried because the run's retry budget is used up
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Options configures how Do retries an action
+type Options struct {
+	MaxRetries int     // The maximum number of retries after the first attempt
+	Backoff    Backoff // How long to wait before each retry, retries immediately when nil
+	Budget     *Budget // Limits retries across actions, defaults to the run's budget set with SetBudget
+	Logger     *logrus.Entry
+}
+
 // DoWithRetry runs the specified action. If it returns a value, return that value. If it returns an error, sleep for
 // sleepBetweenRetries and try again, up to a maximum of maxRetries retries. If maxRetries is exceeded, return a
 // MaxRetriesExceeded error.
 func DoWithRetry(actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, logger *logrus.Entry, action func(attempt int) error) error {
-	for i := 0; i <= maxRetries; i++ {
-		logger.Info(actionDescription)
+	return Do(context.Background(), actionDescription, Options{MaxRetries: maxRetries, Backoff: Fixed(sleepBetweenRetries), Logger: logger}, action)
+}
+
+// Do runs the specified action, retrying errors after waiting according to the backoff. It stops early when the action
+// returns a NonRetryable error, the retry budget is exhausted or the context is done, and returns a MaxRetriesExceeded
+// error when the action fails after all retries.
+func Do(ctx context.Context, actionDescription string, opts Options, action func(attempt int) error) error {
+	budget := opts.Budget
+	if budget == nil {
+		budget = runBudget.Load()
+	}
+
+	var delay time.Duration
+
+	for i := 0; i <= opts.MaxRetries; i++ {
+		if i == 0 {
+			opts.Logger.Debug(actionDescription)
+		} else {
+			opts.Logger.Infof("%s, retry %v of %v", actionDescription, i, opts.MaxRetries)
+		}
 
 		err := action(i)
 		if err == nil {
 			return nil
 		}
 
+		if !IsRetryable(err) {
+			opts.Logger.WithError(err).Warningf("%s returned an error that is not retried: %s. Retry Count: %v.", actionDescription, err.Error(), i)
+			return err
+		}
+
 		// don't sleep after the final retry attempt
-		if i < maxRetries {
-			logger.WithError(err).Warningf("%s returned an error: %s. Sleeping for %s and will try again. Retry Count: %v.", actionDescription, err.Error(), sleepBetweenRetries, i)
-			time.Sleep(sleepBetweenRetries)
-		} else {
-			logger.WithError(err).Warningf("%s returned an error: %s. Retry Count: %v.", actionDescription, err.Error(), i)
+		if i == opts.MaxRetries {
+			opts.Logger.WithError(err).Warningf("%s returned an error: %s. Retry Count: %v.", actionDescription, err.Error(), i)
+			return MaxRetriesExceeded{Description: actionDescription, MaxRetries: opts.MaxRetries, Err: err}
+		}
+
+		if !budget.take() {
+			opts.Logger.WithError(err).Warningf("%s returned an error: %s. The retry budget is exhausted and it will not be retried. Retry Count: %v.", actionDescription, err.Error(), i)
+			return fmt.Errorf("'%s' was not retried: %w: %w", actionDescription, ErrBudgetExhausted, err)
+		}
+
+		if opts.Backoff != nil {
+			delay = opts.Backoff(i+1, delay)
+		}
+
+		opts.Logger.WithError(err).Warningf("%s returned an error: %s. Sleeping for %s and will try again. Retry Count: %v.", actionDescription, err.Error(), delay, i)
+
+		if sleepErr := Sleep(ctx, delay); sleepErr != nil {
+			return fmt.Errorf("'%s' was not retried: %w: %w", actionDescription, sleepErr, err)
 		}
 	}
 
-	return MaxRetriesExceeded{Description: actionDescription, MaxRetries: maxRetries}
+	return nil
+}
+
+// Sleep waits for the duration, returning the context's error when it is done first
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // MaxRetriesExceeded is an error that occurs when the maximum amount of retries is exceeded.
 type MaxRetriesExceeded struct {
 	Description string
 	MaxRetries  int
+	Err         error // The error of the final attempt
 }
 
 func (err MaxRetriesExceeded) Error() string {
 	return fmt.Sprintf("'%s' unsuccessful after %d retries", err.Description, err.MaxRetries)
 }
+
+func (err MaxRetriesExceeded) Unwrap() error {
+	return err.Err
+}
@@ -1,8 +1,10 @@
 package retry_test
 
 import (
+	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"github.com/optum/runiac/pkg/retry"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -37,3 +39,131 @@ func TestDoRetry_ShouldParseS3Correctly(t *testing.T) {
 		return errors.New("error")
 	})
 }
+
+func TestDo_ShouldStopOnNonRetryableError(t *testing.T) {
+	t.Parallel()
+
+	permanent := errors.New("permanent")
+	attempts := 0
+
+	err := retry.Do(context.Background(), "non-retryable", retry.Options{MaxRetries: 3, Logger: logger}, func(attempt int) error {
+		attempts++
+		return retry.NonRetryable(permanent)
+	})
+
+	require.Equal(t, 1, attempts, "should not retry a non-retryable error")
+	require.ErrorIs(t, err, permanent)
+	require.False(t, retry.IsRetryable(err))
+}
+
+func TestDo_ShouldWrapFinalErrorWhenRetriesAreExceeded(t *testing.T) {
+	t.Parallel()
+
+	transient := errors.New("transient")
+	attempts := 0
+
+	err := retry.Do(context.Background(), "always fails", retry.Options{MaxRetries: 2, Logger: logger}, func(attempt int) error {
+		attempts++
+		return transient
+	})
+
+	require.Equal(t, 3, attempts)
+	require.ErrorAs(t, err, &retry.MaxRetriesExceeded{})
+	require.ErrorIs(t, err, transient)
+}
+
+func TestDo_ShouldStopWhenBudgetIsExhausted(t *testing.T) {
+	t.Parallel()
+
+	budget := retry.NewBudget(3)
+	attempts := 0
+
+	for i := 0; i < 2; i++ {
+		_ = retry.Do(context.Background(), "shared budget", retry.Options{MaxRetries: 2, Budget: budget, Logger: logger}, func(attempt int) error {
+			attempts++
+			return errors.New("outage")
+		})
+	}
+
+	require.Equal(t, 5, attempts, "both actions should make their first attempt but share 3 retries")
+	require.Equal(t, 0, budget.Remaining())
+
+	err := retry.Do(context.Background(), "no budget", retry.Options{MaxRetries: 2, Budget: budget, Logger: logger}, func(attempt int) error {
+		return errors.New("outage")
+	})
+
+	require.ErrorIs(t, err, retry.ErrBudgetExhausted)
+}
+
+func TestDo_ShouldUseRunBudget(t *testing.T) {
+	retry.SetBudget(retry.NewBudget(1))
+	t.Cleanup(func() { retry.SetBudget(nil) })
+
+	attempts := 0
+
+	err := retry.Do(context.Background(), "run budget", retry.Options{MaxRetries: 3, Logger: logger}, func(attempt int) error {
+		attempts++
+		return errors.New("outage")
+	})
+
+	require.Equal(t, 2, attempts)
+	require.ErrorIs(t, err, retry.ErrBudgetExhausted)
+}
+
+func TestDo_ShouldStopSleepingWhenContextIsCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+
+	err := retry.Do(ctx, "canceled", retry.Options{MaxRetries: 3, Backoff: retry.Fixed(time.Hour), Logger: logger}, func(attempt int) error {
+		return errors.New("error")
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), time.Minute)
+}
+
+func TestExponential_ShouldDoubleUpToMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	backoff := retry.Exponential(time.Second, 5*time.Second)
+
+	require.Equal(t, time.Second, backoff(1, 0))
+	require.Equal(t, 2*time.Second, backoff(2, time.Second))
+	require.Equal(t, 4*time.Second, backoff(3, 2*time.Second))
+	require.Equal(t, 5*time.Second, backoff(4, 4*time.Second))
+	require.Equal(t, 5*time.Second, backoff(60, 5*time.Second))
+}
+
+func TestDecorrelatedJitter_ShouldStayWithinBounds(t *testing.T) {
+	t.Parallel()
+
+	backoff := retry.DecorrelatedJitter(time.Second, 10*time.Second)
+
+	var delay time.Duration
+	for i := 1; i <= 20; i++ {
+		next := backoff(i, delay)
+
+		require.GreaterOrEqual(t, next, time.Second)
+		require.LessOrEqual(t, next, 10*time.Second)
+		require.LessOrEqual(t, next, 3*max(delay, time.Second))
+
+		delay = next
+	}
+
+	require.Equal(t, time.Duration(0), retry.DecorrelatedJitter(0, time.Minute)(1, 0))
+}
+
+func TestIsRetryable_ShouldRespectOutermostMark(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("error")
+
+	require.True(t, retry.IsRetryable(err))
+	require.False(t, retry.IsRetryable(fmt.Errorf("wrapped: %w", retry.NonRetryable(err))))
+	require.True(t, retry.IsRetryable(retry.Retryable(retry.NonRetryable(err))))
+	require.Nil(t, retry.NonRetryable(nil))
+}
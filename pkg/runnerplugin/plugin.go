@@ -0,0 +1,262 @@
+package runnerplugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Plugin is a runner plugin executable. It implements config.Stepper and config.RunnerPlugin by calling the executable.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// Discover finds runner plugin executables within dirs, in order, and then on the PATH.
+// When the same runner is found more than once, the first executable found is used.
+func Discover(dirs ...string) []Plugin {
+	plugins := []Plugin{}
+	found := map[string]bool{}
+
+	for _, dir := range append(dirs, filepath.SplitList(os.Getenv("PATH"))...) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := strings.TrimPrefix(entry.Name(), ExecutablePrefix)
+			if name == entry.Name() || name == "" || entry.IsDir() || found[name] {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			path, err := filepath.Abs(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			found[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: path})
+		}
+	}
+
+	return plugins
+}
+
+// Register discovers runner plugins and registers a runner for each one.
+// Runners that are already registered, such as the built-in runners, take precedence over plugins with the same name.
+func Register(logger *logrus.Entry, dirs ...string) {
+	for _, plugin := range Discover(dirs...) {
+		if _, ok := config.GetRunner(plugin.Name); ok {
+			logger.Warnf("Ignoring runner plugin %s, the %s runner is already registered", plugin.Path, plugin.Name)
+			continue
+		}
+
+		logger.Debugf("Registering runner plugin %s", plugin.Path)
+
+		p := plugin
+		config.RegisterRunner(config.Runner{
+			Name:       p.Name,
+			NewStepper: func() config.Stepper { return p },
+			Plugin:     p,
+		})
+	}
+}
+
+// Call starts the plugin, sends it a single request and returns its result.
+// Log messages and stderr output are written to logger while the plugin runs.
+func (p Plugin) Call(logger *logrus.Entry, req Request) (result Message, err error) {
+	logger = logger.WithField("runnerPlugin", p.Name)
+
+	cmd := exec.Command(p.Path)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", ProtocolVersionEnv, ProtocolVersion))
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return
+	}
+
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.WithField("stream", "stderr").Info(scanner.Text())
+		}
+	}()
+
+	result, err = exchange(logger, stdin, stdout, req)
+
+	// drain stdout so the plugin is never blocked writing to it while we wait for it to exit
+	_, _ = io.Copy(io.Discard, stdout)
+	<-stderrDone
+
+	waitErr := cmd.Wait()
+	if err == nil && waitErr != nil {
+		err = fmt.Errorf("runner plugin %s exited with an error: %w", p.Name, waitErr)
+	}
+
+	return
+}
+
+// exchange performs the handshake, writes the request and reads messages until the result
+func exchange(logger *logrus.Entry, stdin io.WriteCloser, stdout io.Reader, req Request) (Message, error) {
+	decoder := json.NewDecoder(stdout)
+
+	handshake := Message{}
+	err := decoder.Decode(&handshake)
+	if err != nil {
+		_ = stdin.Close()
+		return Message{}, fmt.Errorf("unable to read runner plugin handshake: %w", err)
+	}
+
+	if handshake.Type != HandshakeMessage {
+		_ = stdin.Close()
+		return Message{}, fmt.Errorf("runner plugin sent %s before its handshake", handshake.Type)
+	}
+
+	if handshake.ProtocolVersion != ProtocolVersion {
+		_ = stdin.Close()
+		return Message{}, fmt.Errorf("runner plugin speaks protocol version %d, runiac speaks version %d", handshake.ProtocolVersion, ProtocolVersion)
+	}
+
+	err = json.NewEncoder(stdin).Encode(req)
+	_ = stdin.Close()
+	if err != nil {
+		return Message{}, fmt.Errorf("unable to send request to runner plugin: %w", err)
+	}
+
+	for {
+		msg := Message{}
+		err = decoder.Decode(&msg)
+		if errors.Is(err, io.EOF) {
+			return Message{}, errors.New("runner plugin exited without a result")
+		} else if err != nil {
+			return Message{}, fmt.Errorf("unable to read runner plugin message: %w", err)
+		}
+
+		switch msg.Type {
+		case LogMessage:
+			level, err := logrus.ParseLevel(msg.Level)
+			if err != nil {
+				level = logrus.InfoLevel
+			}
+
+			logger.WithFields(msg.Fields).Log(level, msg.Message)
+		case ResultMessage:
+			return msg, nil
+		default:
+			logger.Warnf("Ignoring unexpected runner plugin message: %s", msg.Type)
+		}
+	}
+}
+
+// Initialize asks the plugin to perform its one-time initialization
+func (p Plugin) Initialize(logger *logrus.Entry) {
+	result, err := p.Call(logger, Request{Method: MethodInitialize})
+	if err == nil {
+		err = stringError(result.Error)
+	}
+
+	if err != nil {
+		logger.WithError(err).Errorf("Unable to initialize runner plugin %s", p.Name)
+	}
+}
+
+// PreExecute calls the plugin's PreExecute
+func (p Plugin) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	result, err := p.Call(exec.Logger, Request{Method: MethodPreExecute, Execution: NewExecution(exec)})
+	if err != nil {
+		return exec, err
+	}
+
+	if result.Error != "" {
+		return exec, errors.New(result.Error)
+	}
+
+	if result.Execution == nil {
+		return exec, fmt.Errorf("runner plugin %s returned no execution from PreExecute", p.Name)
+	}
+
+	return result.Execution.StepExecution(exec.Logger, exec.Fs), nil
+}
+
+// ExecuteStep calls the plugin's ExecuteStep
+func (p Plugin) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	return p.callOutput(MethodExecuteStep, exec)
+}
+
+// ExecuteStepDestroy calls the plugin's ExecuteStepDestroy
+func (p Plugin) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	return p.callOutput(MethodExecuteStepDestroy, exec)
+}
+
+// ExecuteStepOutputs calls the plugin's ExecuteStepOutputs
+func (p Plugin) ExecuteStepOutputs(exec config.StepExecution) config.StepOutput {
+	return p.callOutput(MethodExecuteStepOutputs, exec)
+}
+
+// ExecuteStepTests calls the plugin's ExecuteStepTests
+func (p Plugin) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	result, err := p.Call(exec.Logger, Request{Method: MethodExecuteStepTests, Execution: NewExecution(exec)})
+	if err == nil && result.TestOutput == nil {
+		err = fmt.Errorf("runner plugin %s returned no test output", p.Name)
+	}
+
+	if err != nil {
+		exec.Logger.WithError(err).Error(err)
+		return config.StepTestOutput{StepName: exec.StepName, Err: err}
+	}
+
+	return result.TestOutput.StepTestOutput()
+}
+
+func (p Plugin) callOutput(method Method, exec config.StepExecution) config.StepOutput {
+	result, err := p.Call(exec.Logger, Request{Method: method, Execution: NewExecution(exec)})
+	if err == nil && result.Output == nil {
+		err = fmt.Errorf("runner plugin %s returned no output from %s", p.Name, method)
+	}
+
+	if err != nil {
+		exec.Logger.WithError(err).Error(err)
+		return config.StepOutput{
+			Status:           config.Fail,
+			RegionDeployType: exec.RegionDeployType,
+			Region:           exec.Region,
+			StepName:         exec.StepName,
+			Err:              err,
+		}
+	}
+
+	return result.Output.StepOutput()
+}
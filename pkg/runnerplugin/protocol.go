@@ -0,0 +1,275 @@
+// Package runnerplugin runs runners that are shipped as separate executables.
+//
+// A runner plugin is an executable named runiac-runner-<name>, found in .runiac/plugins or on the PATH, that registers
+// the runner <name>. runiac starts the plugin once for every call and exchanges JSON messages, one per line, over the
+// plugin's stdin and stdout:
+//
+//	plugin: {"type":"handshake","protocol_version":1,"name":"example"}
+//	runiac: {"method":"ExecuteStep","execution":{...}}
+//	plugin: {"type":"log","level":"info","message":"Deploying","fields":{...}}   (zero or more)
+//	plugin: {"type":"result","output":{...}}
+//
+// The methods mirror config.Stepper and config.RunnerPlugin. Anything the plugin writes to stderr is logged by runiac.
+// Plugins written in Go implement the plugin side by calling Serve from their main function.
+package runnerplugin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// ProtocolVersion is the version of the protocol spoken by this package. Plugins reporting a different version are refused.
+const ProtocolVersion = 1
+
+// ExecutablePrefix prefixes the name of every runner plugin executable
+const ExecutablePrefix = "runiac-runner-"
+
+// DefaultPluginDir is the project directory searched for runner plugins before the PATH
+const DefaultPluginDir = ".runiac/plugins"
+
+// ProtocolVersionEnv is set for the plugin process to the protocol version runiac speaks
+const ProtocolVersionEnv = "RUNIAC_PLUGIN_PROTOCOL_VERSION"
+
+// Method is a call runiac makes to a plugin
+type Method string
+
+const (
+	MethodInitialize         Method = "Initialize"
+	MethodPreExecute         Method = "PreExecute"
+	MethodExecuteStep        Method = "ExecuteStep"
+	MethodExecuteStepTests   Method = "ExecuteStepTests"
+	MethodExecuteStepDestroy Method = "ExecuteStepDestroy"
+	MethodExecuteStepOutputs Method = "ExecuteStepOutputs"
+)
+
+// MessageType identifies the messages a plugin writes to its stdout
+type MessageType string
+
+const (
+	HandshakeMessage MessageType = "handshake"
+	LogMessage       MessageType = "log"
+	ResultMessage    MessageType = "result"
+)
+
+// Request is the single call runiac writes to a plugin's stdin
+type Request struct {
+	Method    Method     `json:"method"`
+	Execution *Execution `json:"execution,omitempty"`
+}
+
+// Message is written by a plugin to its stdout. The fields that are set depend on the message type.
+type Message struct {
+	Type MessageType `json:"type"`
+
+	// handshake
+	ProtocolVersion int    `json:"protocol_version,omitempty"`
+	Name            string `json:"name,omitempty"`
+
+	// log
+	Level   string                 `json:"level,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+
+	// result
+	Execution  *Execution  `json:"execution,omitempty"`   // PreExecute
+	Output     *Output     `json:"output,omitempty"`      // ExecuteStep, ExecuteStepDestroy and ExecuteStepOutputs
+	TestOutput *TestOutput `json:"test_output,omitempty"` // ExecuteStepTests
+	Error      string      `json:"error,omitempty"`
+}
+
+// Execution is the serialized form of config.StepExecution. The step directory is shared through the filesystem.
+type Execution struct {
+	RegionDeployType           config.RegionDeployType        `json:"region_deploy_type"`
+	Region                     string                         `json:"region"`
+	UniqueExternalExecutionID  string                         `json:"unique_external_execution_id"`
+	RegionGroupRegions         []string                       `json:"region_group_regions"`
+	TargetAccountID            string                         `json:"target_account_id"`
+	RegionGroup                string                         `json:"region_group"`
+	PrimaryRegion              string                         `json:"primary_region"`
+	Dir                        string                         `json:"dir"`
+	Environment                string                         `json:"environment"`
+	AppVersion                 string                         `json:"app_version"`
+	AccountID                  string                         `json:"account_id"`
+	MaxRetries                 int                            `json:"max_retries"`
+	MaxTestRetries             int                            `json:"max_test_retries"`
+	CoreAccounts               map[string]config.Account      `json:"core_accounts"`
+	RegionGroups               map[string]map[string][]string `json:"region_groups"`
+	Namespace                  string                         `json:"namespace"`
+	CommonRegion               string                         `json:"common_region"`
+	StepName                   string                         `json:"step_name"`
+	StepID                     string                         `json:"step_id"`
+	DeploymentRing             string                         `json:"deployment_ring"`
+	Project                    string                         `json:"project"`
+	TrackName                  string                         `json:"track_name"`
+	DryRun                     bool                           `json:"dry_run"`
+	SelfDestroy                bool                           `json:"self_destroy"`
+	Action                     string                         `json:"action"`
+	PlanBundleDir              string                         `json:"plan_bundle_dir"`
+	DefaultStepOutputVariables map[string]map[string]string   `json:"default_step_output_variables"`
+	OptionalStepParams         map[string]string              `json:"optional_step_params"`
+	RequiredStepParams         map[string]interface{}         `json:"required_step_params"`
+}
+
+// Output is the serialized form of config.StepOutput
+type Output struct {
+	Status           config.DeployResult         `json:"status"`
+	RegionDeployType config.RegionDeployType     `json:"region_deploy_type"`
+	Region           string                      `json:"region"`
+	StepName         string                      `json:"step_name"`
+	StreamOutput     string                      `json:"stream_output"`
+	Error            string                      `json:"error,omitempty"`
+	OutputVariables  map[string]interface{}      `json:"output_variables"`
+	Drift            *config.StepDrift           `json:"drift,omitempty"`
+	PlannedChanges   map[string][]string         `json:"planned_changes,omitempty"`
+	ErrorClass       *config.ErrorClassification `json:"error_classification,omitempty"`
+}
+
+// TestOutput is the serialized form of config.StepTestOutput
+type TestOutput struct {
+	StepName     string `json:"step_name"`
+	StreamOutput string `json:"stream_output"`
+	Error        string `json:"error,omitempty"`
+}
+
+// NewExecution serializes a step execution
+func NewExecution(exec config.StepExecution) *Execution {
+	return &Execution{
+		RegionDeployType:           exec.RegionDeployType,
+		Region:                     exec.Region,
+		UniqueExternalExecutionID:  exec.UniqueExternalExecutionID,
+		RegionGroupRegions:         exec.RegionGroupRegions,
+		TargetAccountID:            exec.TargetAccountID,
+		RegionGroup:                exec.RegionGroup,
+		PrimaryRegion:              exec.PrimaryRegion,
+		Dir:                        exec.Dir,
+		Environment:                exec.Environment,
+		AppVersion:                 exec.AppVersion,
+		AccountID:                  exec.AccountID,
+		MaxRetries:                 exec.MaxRetries,
+		MaxTestRetries:             exec.MaxTestRetries,
+		CoreAccounts:               exec.CoreAccounts,
+		RegionGroups:               exec.RegionGroups,
+		Namespace:                  exec.Namespace,
+		CommonRegion:               exec.CommonRegion,
+		StepName:                   exec.StepName,
+		StepID:                     exec.StepID,
+		DeploymentRing:             exec.DeploymentRing,
+		Project:                    exec.Project,
+		TrackName:                  exec.TrackName,
+		DryRun:                     exec.DryRun,
+		SelfDestroy:                exec.SelfDestroy,
+		Action:                     exec.Action,
+		PlanBundleDir:              exec.PlanBundleDir,
+		DefaultStepOutputVariables: exec.DefaultStepOutputVariables,
+		OptionalStepParams:         exec.OptionalStepParams,
+		RequiredStepParams:         exec.RequiredStepParams,
+	}
+}
+
+// StepExecution deserializes the execution, using the logger and fs of the calling process
+func (e Execution) StepExecution(logger *logrus.Entry, fs afero.Fs) config.StepExecution {
+	return config.StepExecution{
+		RegionDeployType:           e.RegionDeployType,
+		Region:                     e.Region,
+		Logger:                     logger,
+		Fs:                         fs,
+		Context:                    context.Background(),
+		UniqueExternalExecutionID:  e.UniqueExternalExecutionID,
+		RegionGroupRegions:         e.RegionGroupRegions,
+		TargetAccountID:            e.TargetAccountID,
+		RegionGroup:                e.RegionGroup,
+		PrimaryRegion:              e.PrimaryRegion,
+		Dir:                        e.Dir,
+		Environment:                e.Environment,
+		AppVersion:                 e.AppVersion,
+		AccountID:                  e.AccountID,
+		MaxRetries:                 e.MaxRetries,
+		MaxTestRetries:             e.MaxTestRetries,
+		CoreAccounts:               e.CoreAccounts,
+		RegionGroups:               e.RegionGroups,
+		Namespace:                  e.Namespace,
+		CommonRegion:               e.CommonRegion,
+		StepName:                   e.StepName,
+		StepID:                     e.StepID,
+		DeploymentRing:             e.DeploymentRing,
+		Project:                    e.Project,
+		TrackName:                  e.TrackName,
+		DryRun:                     e.DryRun,
+		SelfDestroy:                e.SelfDestroy,
+		Action:                     e.Action,
+		PlanBundleDir:              e.PlanBundleDir,
+		DefaultStepOutputVariables: e.DefaultStepOutputVariables,
+		OptionalStepParams:         e.OptionalStepParams,
+		RequiredStepParams:         e.RequiredStepParams,
+	}
+}
+
+// NewOutput serializes a step output
+func NewOutput(output config.StepOutput) *Output {
+	return &Output{
+		Status:           output.Status,
+		RegionDeployType: output.RegionDeployType,
+		Region:           output.Region,
+		StepName:         output.StepName,
+		StreamOutput:     output.StreamOutput,
+		Error:            errorString(output.Err),
+		OutputVariables:  output.OutputVariables,
+		Drift:            output.Drift,
+		PlannedChanges:   output.PlannedChanges,
+		ErrorClass:       output.ErrorClass,
+	}
+}
+
+// StepOutput deserializes the output
+func (o Output) StepOutput() config.StepOutput {
+	return config.StepOutput{
+		Status:           o.Status,
+		RegionDeployType: o.RegionDeployType,
+		Region:           o.Region,
+		StepName:         o.StepName,
+		StreamOutput:     o.StreamOutput,
+		Err:              stringError(o.Error),
+		OutputVariables:  o.OutputVariables,
+		Drift:            o.Drift,
+		PlannedChanges:   o.PlannedChanges,
+		ErrorClass:       o.ErrorClass,
+	}
+}
+
+// NewTestOutput serializes a step test output
+func NewTestOutput(output config.StepTestOutput) *TestOutput {
+	return &TestOutput{
+		StepName:     output.StepName,
+		StreamOutput: output.StreamOutput,
+		Error:        errorString(output.Err),
+	}
+}
+
+// StepTestOutput deserializes the test output
+func (o TestOutput) StepTestOutput() config.StepTestOutput {
+	return config.StepTestOutput{
+		StepName:     o.StepName,
+		StreamOutput: o.StreamOutput,
+		Err:          stringError(o.Error),
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
+func stringError(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	return errors.New(s)
+}
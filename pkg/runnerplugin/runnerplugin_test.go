@@ -0,0 +1,124 @@
+package runnerplugin_test
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/runnerplugin"
+	"github.com/optum/runiac/pkg/runnerplugin/runnerplugintest"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var pluginDir string
+var examplePlugin runnerplugin.Plugin
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	var err error
+	pluginDir, err = os.MkdirTemp("", "runiac-plugins")
+	if err != nil {
+		panic(err)
+	}
+
+	// build the reference plugin once for every test
+	examplePlugin = runnerplugin.Plugin{Name: "example", Path: filepath.Join(pluginDir, runnerplugin.ExecutablePrefix+"example")}
+	build := exec.Command("go", "build", "-o", examplePlugin.Path, "../../cmd/runiac-runner-example")
+	build.Stderr = os.Stderr
+
+	err = build.Run()
+	if err != nil {
+		panic(err)
+	}
+
+	exitCode := m.Run()
+
+	_ = os.RemoveAll(pluginDir)
+	os.Exit(exitCode)
+}
+
+func stubExecution(t *testing.T, logger *logrus.Entry) config.StepExecution {
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewOsFs(),
+		Dir:              t.TempDir(),
+		StepName:         "step",
+		TrackName:        "track",
+		Region:           "centralus",
+		RegionDeployType: config.RegionalRegionDeployType,
+	}
+}
+
+func TestConformance_ReferencePlugin(t *testing.T) {
+	runnerplugintest.Conformance(t, examplePlugin.Path)
+}
+
+func TestExecuteStep_ShouldReturnOutputsAndStreamLogs(t *testing.T) {
+	logs, hook := test.NewNullLogger()
+	exec := stubExecution(t, logrus.NewEntry(logs))
+	require.NoError(t, os.WriteFile(filepath.Join(exec.Dir, "outputs.json"), []byte(`{"id":"abc"}`), 0644))
+
+	output := examplePlugin.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, config.RegionalRegionDeployType, output.RegionDeployType)
+	require.Equal(t, "abc", output.OutputVariables["id"])
+
+	require.NotEmpty(t, hook.AllEntries(), "Plugin logs should be streamed")
+	require.Equal(t, "Deploying step step", hook.LastEntry().Message)
+	require.Equal(t, "example", hook.LastEntry().Data["runnerPlugin"])
+}
+
+func TestExecuteStep_ShouldReturnPluginFailures(t *testing.T) {
+	logs, _ := test.NewNullLogger()
+	exec := stubExecution(t, logrus.NewEntry(logs))
+	require.NoError(t, os.WriteFile(filepath.Join(exec.Dir, "outputs.json"), []byte(`not json`), 0644))
+
+	output := examplePlugin.ExecuteStep(exec)
+
+	require.Equal(t, config.Fail, output.Status)
+	require.Error(t, output.Err)
+}
+
+func TestCall_ShouldRefuseOtherProtocolVersions(t *testing.T) {
+	logs, _ := test.NewNullLogger()
+	path := filepath.Join(t.TempDir(), runnerplugin.ExecutablePrefix+"future")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho '{\"type\":\"handshake\",\"protocol_version\":99}'\n"), 0755))
+
+	output := runnerplugin.Plugin{Name: "future", Path: path}.ExecuteStep(stubExecution(t, logrus.NewEntry(logs)))
+
+	require.Equal(t, config.Fail, output.Status)
+	require.Contains(t, output.Err.Error(), "protocol version 99")
+}
+
+func TestDiscover_ShouldPreferEarlierDirectories(t *testing.T) {
+	t.Setenv("PATH", "")
+
+	projectDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, runnerplugin.ExecutablePrefix+"example"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, runnerplugin.ExecutablePrefix+"notexecutable"), []byte{}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "unrelated"), []byte{}, 0755))
+
+	plugins := runnerplugin.Discover(projectDir, pluginDir)
+
+	require.Equal(t, []runnerplugin.Plugin{{Name: "example", Path: filepath.Join(projectDir, runnerplugin.ExecutablePrefix+"example")}}, plugins)
+}
+
+func TestRegister_ShouldNotReplaceRegisteredRunners(t *testing.T) {
+	logs, _ := test.NewNullLogger()
+
+	config.RegisterRunner(config.Runner{Name: "example", NewStepper: func() config.Stepper { return nil }})
+	runnerplugin.Register(logrus.NewEntry(logs), pluginDir)
+
+	runner, ok := config.GetRunner("example")
+	require.True(t, ok)
+	require.Nil(t, runner.Plugin, "The runner registered first should be kept")
+}
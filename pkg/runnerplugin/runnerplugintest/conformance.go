@@ -0,0 +1,106 @@
+// Package runnerplugintest provides a conformance suite for runner plugin executables.
+package runnerplugintest
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/runnerplugin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// Conformance verifies that the runner plugin executable at path speaks the runner plugin protocol.
+// Every method is called against an empty step directory, so only the protocol is verified, not the runner's behavior.
+func Conformance(t *testing.T, path string) {
+	logs, _ := test.NewNullLogger()
+	logs.SetLevel(logrus.TraceLevel)
+	logger := logrus.NewEntry(logs)
+
+	plugin := runnerplugin.Plugin{Name: "conformance", Path: path}
+
+	exec := runnerplugin.NewExecution(config.StepExecution{
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Region:           "centralus",
+		Dir:              t.TempDir(),
+		StepName:         "conformance",
+		StepID:           "conformance-step",
+		TrackName:        "conformance",
+		Action:           config.DeployAction,
+		DryRun:           true,
+		OptionalStepParams: map[string]string{
+			"runiac_step": "conformance",
+		},
+	})
+
+	call := func(t *testing.T, method runnerplugin.Method) runnerplugin.Message {
+		req := runnerplugin.Request{Method: method}
+		if method != runnerplugin.MethodInitialize {
+			req.Execution = exec
+		}
+
+		result, err := plugin.Call(logger, req)
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+
+		if result.Type != runnerplugin.ResultMessage {
+			t.Fatalf("%s returned a %s message instead of a result", method, result.Type)
+		}
+
+		return result
+	}
+
+	t.Run("Initialize", func(t *testing.T) {
+		result := call(t, runnerplugin.MethodInitialize)
+		if result.Error != "" {
+			t.Errorf("Initialize returned an error: %s", result.Error)
+		}
+	})
+
+	t.Run("PreExecute", func(t *testing.T) {
+		result := call(t, runnerplugin.MethodPreExecute)
+		if result.Error != "" {
+			return
+		}
+
+		if result.Execution == nil {
+			t.Fatal("PreExecute returned neither an execution nor an error")
+		}
+
+		if result.Execution.StepName != exec.StepName || result.Execution.TrackName != exec.TrackName {
+			t.Errorf("PreExecute changed the step it was called for: %s/%s", result.Execution.TrackName, result.Execution.StepName)
+		}
+	})
+
+	for _, method := range []runnerplugin.Method{runnerplugin.MethodExecuteStep, runnerplugin.MethodExecuteStepDestroy, runnerplugin.MethodExecuteStepOutputs} {
+		t.Run(string(method), func(t *testing.T) {
+			result := call(t, method)
+			if result.Output == nil {
+				t.Fatalf("%s returned no output", method)
+			}
+
+			if result.Output.Status < config.Fail || result.Output.Status > config.Na {
+				t.Errorf("%s returned an unknown status: %d", method, result.Output.Status)
+			}
+
+			if result.Output.Status == config.Fail && result.Output.Error == "" {
+				t.Errorf("%s failed without an error", method)
+			}
+		})
+	}
+
+	t.Run("ExecuteStepTests", func(t *testing.T) {
+		result := call(t, runnerplugin.MethodExecuteStepTests)
+		if result.TestOutput == nil {
+			t.Fatal("ExecuteStepTests returned no test output")
+		}
+	})
+
+	t.Run("UnsupportedMethod", func(t *testing.T) {
+		result := call(t, runnerplugin.Method("Unsupported"))
+		if result.Error == "" {
+			t.Error("An unsupported method should return an error")
+		}
+	})
+}
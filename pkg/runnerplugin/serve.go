@@ -0,0 +1,118 @@
+package runnerplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Serve implements the plugin side of the protocol. It is called from the main function of a runner plugin,
+// answers the single request runiac sends and returns once the result is written.
+func Serve(name string, stepper config.Stepper, plugin config.RunnerPlugin) error {
+	out := os.Stdout
+
+	// keep stray writes to stdout, e.g. from libraries, out of the protocol stream
+	os.Stdout = os.Stderr
+
+	return ServeIO(name, stepper, plugin, os.Stdin, out)
+}
+
+// ServeIO answers a single request read from in, writing the protocol messages to out
+func ServeIO(name string, stepper config.Stepper, plugin config.RunnerPlugin, in io.Reader, out io.Writer) error {
+	enc := &encoder{enc: json.NewEncoder(out)}
+
+	err := enc.Encode(Message{Type: HandshakeMessage, ProtocolVersion: ProtocolVersion, Name: name})
+	if err != nil {
+		return err
+	}
+
+	req := Request{}
+	err = json.NewDecoder(in).Decode(&req)
+	if err != nil {
+		return fmt.Errorf("unable to read request: %w", err)
+	}
+
+	logs := logrus.New()
+	logs.SetOutput(io.Discard)
+	logs.SetLevel(logrus.TraceLevel)
+	logs.AddHook(logHook{enc: enc})
+	logger := logrus.NewEntry(logs)
+
+	result := Message{Type: ResultMessage}
+
+	var exec config.StepExecution
+	if req.Execution != nil {
+		exec = req.Execution.StepExecution(logger, afero.NewOsFs())
+	}
+
+	switch {
+	case req.Method == MethodInitialize:
+		if plugin != nil {
+			plugin.Initialize(logger)
+		}
+	case req.Execution == nil:
+		result.Error = fmt.Sprintf("%s requires an execution", req.Method)
+	case req.Method == MethodPreExecute:
+		exec, err := stepper.PreExecute(exec)
+		result.Execution = NewExecution(exec)
+		result.Error = errorString(err)
+	case req.Method == MethodExecuteStep:
+		result.Output = NewOutput(stepper.ExecuteStep(exec))
+	case req.Method == MethodExecuteStepTests:
+		result.TestOutput = NewTestOutput(stepper.ExecuteStepTests(exec))
+	case req.Method == MethodExecuteStepDestroy:
+		result.Output = NewOutput(stepper.ExecuteStepDestroy(exec))
+	case req.Method == MethodExecuteStepOutputs:
+		result.Output = NewOutput(stepper.ExecuteStepOutputs(exec))
+	default:
+		result.Error = fmt.Sprintf("unsupported method: %s", req.Method)
+	}
+
+	return enc.Encode(result)
+}
+
+// encoder serializes messages written concurrently, e.g. logs from parallel goroutines
+type encoder struct {
+	mutex sync.Mutex
+	enc   *json.Encoder
+}
+
+func (e *encoder) Encode(msg Message) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.enc.Encode(msg)
+}
+
+// logHook streams log entries to runiac as log messages
+type logHook struct {
+	enc *encoder
+}
+
+func (h logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h logHook) Fire(entry *logrus.Entry) error {
+	fields := map[string]interface{}{}
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+
+		fields[k] = v
+	}
+
+	return h.enc.Encode(Message{
+		Type:    LogMessage,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		Fields:  fields,
+	})
+}
@@ -135,7 +135,8 @@ func logTextAndAppendToOutput(logger *logrus.Entry, mutex *sync.Mutex, text stri
 // in a way that works across platforms.
 func GetExitCodeForRunCommandError(err error) (int, error) {
 	// http://stackoverflow.com/a/10385867/483528
-	if exitErr, ok := err.(*exec.ExitError); ok {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
 		// The program has exited with an exit code != 0
 
 		// This works on both Unix and Windows. Although package
@@ -0,0 +1,77 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/spf13/afero"
+)
+
+// JUnitDir is where step tests write their JUnit reports
+var JUnitDir = filepath.Join("/", "output", "junit")
+
+// GoTestsExist reports whether a step has a compiled tests/tests.test or *_test.go sources in its tests directory
+func GoTestsExist(fs afero.Fs, stepDir string) bool {
+	if info, err := fs.Stat(filepath.Join(stepDir, "tests", "tests.test")); err == nil && !info.IsDir() {
+		return true
+	}
+
+	matches, err := afero.Glob(fs, filepath.Join(stepDir, "tests", "*_test.go"))
+	return err == nil && len(matches) > 0
+}
+
+// JUnitFile returns the JUnit report of a step's deployment to a region, e.g. <project>-<track>-<step>-primary-centralus.xml,
+// creating the report directory when it does not exist. The suffix tells the reports of a deployment apart.
+func JUnitFile(exec config.StepExecution, suffix string) string {
+	if _, err := os.Stat(JUnitDir); os.IsNotExist(err) {
+		err = os.MkdirAll(JUnitDir, os.ModePerm)
+
+		if err != nil {
+			exec.Logger.WithError(err).Warn("Failed to create output directory for test results")
+		}
+	}
+
+	return filepath.Join(JUnitDir, fmt.Sprintf("%s%s.xml", stepDeployID(exec), suffix))
+}
+
+// ExecuteGoTests runs a step's go tests with gotestsum, retrying up to MaxTestRetries times. A compiled tests/tests.test
+// takes precedence over the *_test.go sources within the same directory. Commands are run by run.
+func ExecuteGoTests(exec config.StepExecution, envVars map[string]string, run func(shell.Command) (string, error)) (output config.StepTestOutput) {
+	output.StepName = exec.StepName
+
+	testDir := filepath.Join(exec.Dir, "tests")
+	junitFile := JUnitFile(exec, "")
+
+	args := []string{"--format", "standard-verbose", "--junitfile", junitFile, "--", "-v", "-count=1", "."}
+	if info, err := exec.Fs.Stat(filepath.Join(testDir, "tests.test")); err == nil && !info.IsDir() {
+		args = []string{"--format", "standard-verbose", "--junitfile", junitFile, "--raw-command", "--", "test2json", "-p", stepDeployID(exec), "./tests.test", "-test.v"}
+	}
+
+	_ = retry.DoWithRetry(fmt.Sprintf("execute tests: %s", testDir), exec.MaxTestRetries, 20*time.Second, exec.Logger, func(retryCount int) error {
+		cmd := shell.Command{
+			Command:        "gotestsum",
+			Args:           args,
+			Logger:         exec.Logger.WithField("retryCount", retryCount),
+			SensitiveArgs:  false,
+			NonInteractive: true,
+			Env:            envVars,
+			WorkingDir:     testDir,
+		}
+
+		output.StreamOutput, output.Err = run(cmd)
+
+		return output.Err
+	})
+
+	return
+}
+
+// stepDeployID identifies a step's deployment to a region in test reports
+func stepDeployID(exec config.StepExecution) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s", exec.Project, exec.TrackName, exec.StepName, exec.RegionDeployType, exec.Region)
+}
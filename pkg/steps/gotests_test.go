@@ -0,0 +1,75 @@
+package steps_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/pkg/steps"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func stubGoTestsExecution(fs afero.Fs) config.StepExecution {
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               fs,
+		Dir:              "/steps/network",
+		Project:          "runiac",
+		TrackName:        "core",
+		StepName:         "network",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		MaxTestRetries:   1,
+	}
+}
+
+func TestGoTestsExist_ShouldDetectCompiledTestsAndSources(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/compiled/tests/tests.test", []byte{}, 0755))
+	require.NoError(t, afero.WriteFile(fs, "/steps/sources/tests/network_test.go", []byte{}, 0644))
+	require.NoError(t, afero.WriteFile(fs, "/steps/untested/tests/helpers.go", []byte{}, 0644))
+
+	require.True(t, steps.GoTestsExist(fs, "/steps/compiled"))
+	require.True(t, steps.GoTestsExist(fs, "/steps/sources"))
+	require.False(t, steps.GoTestsExist(fs, "/steps/untested"))
+}
+
+func TestExecuteGoTests_ShouldPreferCompiledTests(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/tests/tests.test", []byte{}, 0755))
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/tests/network_test.go", []byte{}, 0644))
+
+	commands := []shell.Command{}
+	output := steps.ExecuteGoTests(stubGoTestsExecution(fs), map[string]string{"runiac_region": "centralus"}, func(cmd shell.Command) (string, error) {
+		commands = append(commands, cmd)
+		return "PASS", nil
+	})
+
+	require.NoError(t, output.Err)
+	require.Equal(t, "PASS", output.StreamOutput)
+	require.Equal(t, "network", output.StepName)
+	require.Len(t, commands, 1)
+	require.Equal(t, "gotestsum", commands[0].Command)
+	require.Equal(t, "/steps/network/tests", commands[0].WorkingDir)
+	require.Equal(t, []string{"--format", "standard-verbose", "--junitfile", "/output/junit/runiac-core-network-primary-centralus.xml",
+		"--raw-command", "--", "test2json", "-p", "runiac-core-network-primary-centralus", "./tests.test", "-test.v"}, commands[0].Args)
+	require.Equal(t, "centralus", commands[0].Env["runiac_region"])
+}
+
+func TestExecuteGoTests_ShouldRetryFailedTests(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/tests/network_test.go", []byte{}, 0644))
+
+	runs := 0
+	output := steps.ExecuteGoTests(stubGoTestsExecution(fs), map[string]string{}, func(cmd shell.Command) (string, error) {
+		runs++
+		require.Equal(t, []string{"--format", "standard-verbose", "--junitfile", "/output/junit/runiac-core-network-primary-centralus.xml", "--", "-v", "-count=1", "."}, cmd.Args)
+		return "FAIL", errors.New("exit status 1")
+	})
+
+	require.Error(t, output.Err)
+	require.Equal(t, "FAIL", output.StreamOutput)
+	require.Equal(t, 2, runs, "Failed tests should be retried up to max test retries")
+}
@@ -1,6 +1,7 @@
 package steps
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/optum/runiac/pkg/cloudaccountdeployment"
@@ -14,6 +15,11 @@ import (
 )
 
 func NewExecution(s config.Step, logger *logrus.Entry, fs afero.Fs, regionDeployType config.RegionDeployType, region string, defaultStepOutputVariables map[string]map[string]string) config.StepExecution {
+	ctx := s.DeployConfig.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	return config.StepExecution{
 		RegionDeployType:           regionDeployType,
 		Region:                     region,
@@ -39,6 +45,9 @@ func NewExecution(s config.Step, logger *logrus.Entry, fs afero.Fs, regionDeploy
 		UniqueExternalExecutionID:  s.DeployConfig.UniqueExternalExecutionID,
 		RegionGroups:               s.DeployConfig.RegionGroups,
 		SelfDestroy:                s.DeployConfig.SelfDestroy,
+		Action:                     s.DeployConfig.Action,
+		PlanBundleDir:              s.DeployConfig.PlanBundleDir,
+		Context:                    ctx,
 		Logger: logger.WithFields(logrus.Fields{
 			"step":            s.Name,
 			"stepProgression": s.ProgressionLevel,
@@ -75,6 +84,10 @@ func ExecuteStepDestroy(stepper config.Stepper, exec config.StepExecution) confi
 	return stepper.ExecuteStepDestroy(exec)
 }
 
+func ExecuteStepOutputs(stepper config.Stepper, exec config.StepExecution) config.StepOutput {
+	return stepper.ExecuteStepOutputs(exec)
+}
+
 func ExecuteStepTests(stepper config.Stepper, exec config.StepExecution) config.StepTestOutput {
 	output := stepper.ExecuteStepTests(exec)
 	postStepTest(exec, output)
@@ -160,7 +173,16 @@ func InitExecution(s config.Step, logger *logrus.Entry, fs afero.Fs,
 
 func postStep(exec config.StepExecution, output config.StepOutput) {
 	if output.Err != nil {
-		cloudaccountdeployment.RecordStepFail(exec.Logger, "", exec.TrackName, exec.StepName, exec.RegionDeployType.String(), exec.Region, exec.UniqueExternalExecutionID, exec.Project, exec.RegionGroupRegions, output.Err)
+		logger := exec.Logger
+
+		if output.ErrorClass != nil {
+			logger = logger.WithFields(logrus.Fields{
+				"errorClass":    output.ErrorClass.Class,
+				"errorCategory": output.ErrorClass.Category,
+			})
+		}
+
+		cloudaccountdeployment.RecordStepFail(logger, "", exec.TrackName, exec.StepName, exec.RegionDeployType.String(), exec.Region, exec.UniqueExternalExecutionID, exec.Project, exec.RegionGroupRegions, output.Err)
 	} else if output.Status == config.Fail {
 		cloudaccountdeployment.RecordStepFail(exec.Logger, "", exec.TrackName, exec.StepName, exec.RegionDeployType.String(), exec.Region, exec.UniqueExternalExecutionID, exec.Project, exec.RegionGroupRegions, errors.New("step recorded failure with no error thrown"))
 	} else if output.Status == config.Unstable {
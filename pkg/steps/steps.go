@@ -4,22 +4,19 @@ package steps
 
 import (
 	"fmt"
-	pluginsarm "github.com/optum/runiac/plugins/arm"
-	pluginsterraform "github.com/optum/runiac/plugins/terraform"
 	"strings"
 
 	"github.com/optum/runiac/pkg/config"
 )
 
+// DetermineRunner returns the stepper of the registered runner configured for the step
 func DetermineRunner(s config.Step) config.Stepper {
-	switch s.DeployConfig.Runner {
-	case "arm":
-		return pluginsarm.ArmStepper{}
-	case "terraform":
-		return pluginsterraform.TerraformStepper{}
-	default:
+	runner, ok := config.GetRunner(s.DeployConfig.Runner)
+	if !ok {
 		return nil
 	}
+
+	return runner.NewStepper()
 }
 
 // Adds previous step output to stepParams which get added as environment variables
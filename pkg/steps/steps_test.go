@@ -54,3 +54,10 @@ func TestAddTrackOutputToParams(t *testing.T) {
 	require.Equal(t, "v2", mockParams["cool_step1-k2"], "stepParams should be set with the correct key and value")
 	require.Equal(t, "v3", mockParams["cool_step2-k3"], "stepParams should be set with the correct key and value")
 }
+
+func TestDetermineRunner_ShouldReturnRegisteredStepper(t *testing.T) {
+	stepper := steps.DetermineRunner(config.Step{DeployConfig: config.Config{Runner: plugins_terraform.RunnerName}})
+	require.IsType(t, plugins_terraform.TerraformStepper{}, stepper)
+
+	require.Nil(t, steps.DetermineRunner(config.Step{DeployConfig: config.Config{Runner: "unregistered"}}))
+}
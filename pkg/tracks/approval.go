@@ -0,0 +1,419 @@
+package tracks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/planbundle"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// ErrApprovalRejected occurs when an approval gate is rejected
+var ErrApprovalRejected = errors.New("approval rejected")
+
+// ApprovalGate describes a point where execution pauses until a human approves continuing
+type ApprovalGate struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Summary     string `json:"summary"`
+}
+
+// Approver receives approval signals for approval gates
+type Approver interface {
+	// WaitForApproval blocks until the gate is approved. It returns ErrApprovalRejected when the gate is rejected
+	// and the context's error when the context is done first.
+	WaitForApproval(ctx context.Context, gate ApprovalGate) error
+}
+
+// NewApprover creates the approver for the configured approval source, returning nil when approval gates are disabled
+func NewApprover(cfg config.Config, logger *logrus.Entry, fs afero.Fs) (Approver, error) {
+	switch cfg.ApprovalSource {
+	case "":
+		return nil, nil
+	case config.TTYApprovalSource:
+		return NewTTYApprover(os.Stdin, os.Stdout), nil
+	case config.FileApprovalSource:
+		return &FileApprover{Fs: fs, Dir: cfg.ApprovalDir, PollInterval: 5 * time.Second}, nil
+	case config.HTTPApprovalSource:
+		return NewHTTPApprover(cfg.ApprovalAddr, logger)
+	default:
+		return nil, fmt.Errorf("invalid approval source: %s", cfg.ApprovalSource)
+	}
+}
+
+// waitForApproval pauses at the gate until it is approved, rejected, the timeout elapses or the run is stopped
+func waitForApproval(ctx context.Context, approver Approver, logger *logrus.Entry, timeout time.Duration, gate ApprovalGate) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	gateLogger := logger.WithField("approvalGate", gate.ID)
+	gateLogger.Infof("Waiting for approval: %s\n%s", gate.Description, gate.Summary)
+
+	err := approver.WaitForApproval(ctx, gate)
+
+	switch {
+	case err == nil:
+		gateLogger.Info("Approved")
+	case errors.Is(err, context.DeadlineExceeded):
+		gateLogger.Warnf("Approval timed out after %v", timeout)
+	default:
+		gateLogger.WithError(err).Warn("Not approved")
+	}
+
+	return err
+}
+
+// TTYApprover prompts for approval on an interactive terminal
+type TTYApprover struct {
+	Out io.Writer
+
+	mutex sync.Mutex
+	lines chan string
+}
+
+// NewTTYApprover creates an approver that prompts on out and reads answers from in
+func NewTTYApprover(in io.Reader, out io.Writer) *TTYApprover {
+	approver := &TTYApprover{
+		Out:   out,
+		lines: make(chan string),
+	}
+
+	// a single reader is shared by all prompts, reads cannot be interrupted when a prompt times out
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			approver.lines <- scanner.Text()
+		}
+		close(approver.lines)
+	}()
+
+	return approver
+}
+
+// WaitForApproval prompts for the gate, one gate at a time
+func (approver *TTYApprover) WaitForApproval(ctx context.Context, gate ApprovalGate) error {
+	approver.mutex.Lock()
+	defer approver.mutex.Unlock()
+
+	_, _ = fmt.Fprintf(approver.Out, "\n%s\n%s\nApprove? [y/N]: ", gate.Description, gate.Summary)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case line, ok := <-approver.lines:
+		if !ok {
+			return errors.New("approval input closed")
+		}
+
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "y" || answer == "yes" {
+			return nil
+		}
+
+		return ErrApprovalRejected
+	}
+}
+
+// FileApprover waits for an approval file to be dropped in a watched directory.
+// While waiting, {gate}.pending describes the gate. Creating {gate}.approved or {gate}.rejected decides it.
+type FileApprover struct {
+	Fs           afero.Fs
+	Dir          string
+	PollInterval time.Duration
+}
+
+// WaitForApproval polls the directory until the gate is decided
+func (approver *FileApprover) WaitForApproval(ctx context.Context, gate ApprovalGate) error {
+	err := approver.Fs.MkdirAll(approver.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	pendingFile := filepath.Join(approver.Dir, fmt.Sprintf("%s.pending", gate.ID))
+	data, err := json.MarshalIndent(gate, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = afero.WriteFile(approver.Fs, pendingFile, data, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = approver.Fs.Remove(pendingFile) }()
+
+	ticker := time.NewTicker(approver.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if ok, _ := afero.Exists(approver.Fs, filepath.Join(approver.Dir, fmt.Sprintf("%s.rejected", gate.ID))); ok {
+			return ErrApprovalRejected
+		}
+
+		if ok, _ := afero.Exists(approver.Fs, filepath.Join(approver.Dir, fmt.Sprintf("%s.approved", gate.ID))); ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// HTTPApprover exposes pending approval gates on a local HTTP endpoint.
+// GET /approvals lists the pending gates, POST /approvals/{gate}/approve or /approvals/{gate}/reject decides one.
+type HTTPApprover struct {
+	mutex   sync.Mutex
+	pending map[string]pendingApproval
+}
+
+type pendingApproval struct {
+	gate     ApprovalGate
+	decision chan error
+}
+
+// NewHTTPApprover starts serving the approval endpoint on addr
+func NewHTTPApprover(addr string, logger *logrus.Entry) (*HTTPApprover, error) {
+	approver := &HTTPApprover{}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Serving approvals on %s", listener.Addr())
+
+	go func() {
+		err := http.Serve(listener, approver.Handler())
+		logger.WithError(err).Error("Approval endpoint stopped")
+	}()
+
+	return approver, nil
+}
+
+// Handler returns the HTTP handler for the approval endpoint
+func (approver *HTTPApprover) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /approvals", func(w http.ResponseWriter, r *http.Request) {
+		approver.mutex.Lock()
+		gates := []ApprovalGate{}
+		for _, p := range approver.pending {
+			gates = append(gates, p.gate)
+		}
+		approver.mutex.Unlock()
+
+		sort.Slice(gates, func(i, j int) bool { return gates[i].ID < gates[j].ID })
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(gates)
+	})
+
+	mux.HandleFunc("POST /approvals/{gate}/{decision}", func(w http.ResponseWriter, r *http.Request) {
+		var decision error
+
+		switch r.PathValue("decision") {
+		case "approve":
+			decision = nil
+		case "reject":
+			decision = ErrApprovalRejected
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		approver.mutex.Lock()
+		p, ok := approver.pending[r.PathValue("gate")]
+		if ok {
+			delete(approver.pending, r.PathValue("gate"))
+		}
+		approver.mutex.Unlock()
+
+		if !ok {
+			http.Error(w, "approval gate is not pending", http.StatusNotFound)
+			return
+		}
+
+		p.decision <- decision
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// WaitForApproval marks the gate as pending until it is decided through the endpoint
+func (approver *HTTPApprover) WaitForApproval(ctx context.Context, gate ApprovalGate) error {
+	p := pendingApproval{
+		gate:     gate,
+		decision: make(chan error, 1),
+	}
+
+	approver.mutex.Lock()
+	if approver.pending == nil {
+		approver.pending = map[string]pendingApproval{}
+	}
+	approver.pending[gate.ID] = p
+	approver.mutex.Unlock()
+
+	select {
+	case err := <-p.decision:
+		return err
+	case <-ctx.Done():
+		approver.mutex.Lock()
+		delete(approver.pending, gate.ID)
+		approver.mutex.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// errNotApproved wraps the error of an approval gate that skipped a step
+var errNotApproved = errors.New("changes were not approved")
+
+// executeApprovedStep plans a step into a plan bundle, waits for the plan to be approved and applies exactly that plan.
+// A step whose runner does not save plans is previewed with a dry run instead and deployed once the preview is approved.
+func executeApprovedStep(execution RegionExecution, logger *logrus.Entry, stepOutputVariables map[string]map[string]string, stepProgression int,
+	s config.Step, out chan<- config.Step) {
+	deployConfig := s.DeployConfig
+	bundleDir := filepath.Join(execution.PlanDir, s.TrackName, s.Name, fmt.Sprintf("%s-%s", execution.RegionDeployType, execution.Region))
+	slogger := logger.WithField("step", s.Name)
+
+	err := planbundle.Prepare(execution.Fs, bundleDir)
+	if err != nil {
+		slogger.WithError(err).Error("Unable to create plan bundle for approval")
+		s.Output = config.StepOutput{
+			Status:           config.Fail,
+			RegionDeployType: execution.RegionDeployType,
+			Region:           execution.Region,
+			StepName:         s.Name,
+			Err:              err,
+		}
+		out <- s
+		return
+	}
+
+	s.DeployConfig.Action = config.PlanAction
+	s.DeployConfig.DryRun = true
+	s.DeployConfig.PlanBundleDir = bundleDir
+
+	planned := runStep(execution, slogger, stepOutputVariables, stepProgression, s)
+	planned.DeployConfig = deployConfig
+
+	if planned.Output.Err != nil || planned.Output.Status != config.Success {
+		out <- planned
+		return
+	}
+
+	manifest, err := planbundle.WriteManifest(execution.Fs, bundleDir, planbundle.NewManifest(deployConfig))
+	if err != nil {
+		slogger.WithError(err).Error("Unable to write plan bundle manifest for approval")
+		planned.Output.Status = config.Fail
+		planned.Output.Err = err
+		out <- planned
+		return
+	}
+
+	saved := len(manifest.Entries) > 0
+
+	// a saved plan without changes applies nothing, so there is nothing to approve
+	if !saved || len(planned.Output.PlannedChanges) > 0 {
+		err = waitForApproval(deployConfig.Context, execution.Approver, slogger, deployConfig.ApprovalTimeout, ApprovalGate{
+			ID:          fmt.Sprintf("%s-%s-%s-%s", s.TrackName, s.Name, execution.RegionDeployType, execution.Region),
+			Description: fmt.Sprintf("Apply the planned changes of step %s/%s to %s", s.TrackName, s.Name, execution.Region),
+			Summary:     stepSummary(s.TrackName, execution.RegionDeployType, execution.Region, planned),
+		})
+
+		if err != nil {
+			planned.Output.Status = config.Skipped
+			planned.Output.Err = fmt.Errorf("%w: %w", errNotApproved, err)
+			out <- planned
+			return
+		}
+	}
+
+	if saved {
+		s.DeployConfig.Action = config.ApplyAction
+		s.DeployConfig.DryRun = false
+	} else {
+		slogger.Warn("The step's runner does not save plans, the approved preview will be planned again when deploying")
+		s.DeployConfig = deployConfig
+	}
+
+	applied := runStep(execution, slogger, stepOutputVariables, stepProgression, s)
+	applied.DeployConfig = deployConfig
+	out <- applied
+}
+
+// runStep executes a step and waits for its output
+func runStep(execution RegionExecution, logger *logrus.Entry, stepOutputVariables map[string]map[string]string, stepProgression int, s config.Step) config.Step {
+	stepChan := make(chan config.Step, 1)
+	ExecuteStep(execution.Region, execution.RegionDeployType, logger, execution.Fs, stepOutputVariables, stepProgression, s, stepChan, false)
+
+	return <-stepChan
+}
+
+// copyStepOutputVariables copies the step outputs of a region, so a step waiting for approval reads the outputs it started with
+// while the outputs of other steps are added
+func copyStepOutputVariables(stepOutputVariables map[string]map[string]string) map[string]map[string]string {
+	copied := make(map[string]map[string]string, len(stepOutputVariables))
+	for step, outputs := range stepOutputVariables {
+		copied[step] = make(map[string]string, len(outputs))
+		for k, v := range outputs {
+			copied[step][k] = v
+		}
+	}
+
+	return copied
+}
+
+// executionSummary describes the changes planned or applied by each step of a region execution
+func executionSummary(trackName string, exec RegionExecution) (lines []string) {
+	for _, s := range exec.Output.Steps {
+		if s.Output.Status == config.Na {
+			continue
+		}
+
+		lines = append(lines, stepSummary(trackName, exec.RegionDeployType, exec.Region, s))
+	}
+
+	sort.Strings(lines)
+
+	return
+}
+
+// stepSummary describes the changes planned or applied by a step
+func stepSummary(trackName string, regionDeployType config.RegionDeployType, region string, s config.Step) string {
+	changes := []string{}
+	for action, addresses := range s.Output.PlannedChanges {
+		changes = append(changes, fmt.Sprintf("%s %d", action, len(addresses)))
+	}
+	sort.Strings(changes)
+
+	if len(changes) == 0 {
+		changes = append(changes, "no changes")
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s: %s", trackName, s.Name, regionDeployType, region, strings.Join(changes, ", "))
+}
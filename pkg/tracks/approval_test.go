@@ -0,0 +1,342 @@
+package tracks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/planbundle"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+type stubApprover struct {
+	err   error
+	gates []tracks.ApprovalGate
+	mutex sync.Mutex
+}
+
+func (approver *stubApprover) WaitForApproval(ctx context.Context, gate tracks.ApprovalGate) error {
+	approver.mutex.Lock()
+	defer approver.mutex.Unlock()
+
+	approver.gates = append(approver.gates, gate)
+	return approver.err
+}
+
+func stubPlannedOutput(name string) tracks.Output {
+	return tracks.Output{
+		Name: name,
+		Executions: []tracks.RegionExecution{
+			{
+				Region:           "primaryregion",
+				RegionDeployType: config.PrimaryRegionDeployType,
+				Output: tracks.ExecutionOutput{
+					ExecutedCount: 1,
+					Steps: map[string]config.Step{
+						"step": {
+							Name: "step",
+							Output: config.StepOutput{
+								Status:         config.Success,
+								PlannedChanges: map[string][]string{"[create]": {"azurerm_resource_group.rg"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestTTYApprover_ShouldApproveOnlyWhenConfirmed(t *testing.T) {
+	out := &bytes.Buffer{}
+	approver := tracks.NewTTYApprover(strings.NewReader("yes\nn\n"), out)
+
+	require.NoError(t, approver.WaitForApproval(context.Background(), tracks.ApprovalGate{ID: "apply", Description: "Apply the plan"}))
+	require.ErrorIs(t, approver.WaitForApproval(context.Background(), tracks.ApprovalGate{ID: "apply"}), tracks.ErrApprovalRejected)
+	require.Contains(t, out.String(), "Apply the plan")
+	require.Error(t, approver.WaitForApproval(context.Background(), tracks.ApprovalGate{ID: "apply"}), "Closed input should not approve")
+}
+
+func TestFileApprover_ShouldWaitForDecisionFile(t *testing.T) {
+	approvalFs := afero.NewMemMapFs()
+	approver := &tracks.FileApprover{Fs: approvalFs, Dir: "/approvals", PollInterval: time.Millisecond}
+
+	go func() {
+		for {
+			if ok, _ := afero.Exists(approvalFs, "/approvals/apply.pending"); ok {
+				_ = afero.WriteFile(approvalFs, "/approvals/apply.approved", []byte{}, 0644)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	require.NoError(t, approver.WaitForApproval(context.Background(), tracks.ApprovalGate{ID: "apply"}))
+
+	pending, _ := afero.Exists(approvalFs, "/approvals/apply.pending")
+	require.False(t, pending, "The pending file should be removed once decided")
+
+	require.NoError(t, afero.WriteFile(approvalFs, "/approvals/track-regional.rejected", []byte{}, 0644))
+	require.ErrorIs(t, approver.WaitForApproval(context.Background(), tracks.ApprovalGate{ID: "track-regional"}), tracks.ErrApprovalRejected)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, approver.WaitForApproval(ctx, tracks.ApprovalGate{ID: "undecided"}), context.DeadlineExceeded)
+}
+
+func TestHTTPApprover_ShouldDecidePendingGates(t *testing.T) {
+	approver := &tracks.HTTPApprover{}
+	server := httptest.NewServer(approver.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/approvals/apply/approve", "", nil)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, "Gates that are not pending cannot be decided")
+
+	decided := make(chan error)
+	go func() {
+		decided <- approver.WaitForApproval(context.Background(), tracks.ApprovalGate{ID: "apply", Summary: "track/step: [create] 1"})
+	}()
+
+	gates := []tracks.ApprovalGate{}
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(server.URL + "/approvals")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		return json.NewDecoder(resp.Body).Decode(&gates) == nil && len(gates) == 1
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, "apply", gates[0].ID)
+
+	resp, err = http.Post(server.URL+"/approvals/apply/reject", "", nil)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.ErrorIs(t, <-decided, tracks.ErrApprovalRejected)
+}
+
+func TestExecuteTracks_ShouldGateStepsWhenApprovalIsEnabled(t *testing.T) {
+	executions := []tracks.Execution{}
+	deployCfgSpy := []config.Config{}
+	mutex := &sync.Mutex{}
+
+	tracks.DeployTrack = func(execution tracks.Execution, cfg config.Config, tr tracks.Track, out chan<- tracks.Output) {
+		mutex.Lock()
+		executions = append(executions, execution)
+		deployCfgSpy = append(deployCfgSpy, cfg)
+		mutex.Unlock()
+
+		out <- stubPlannedOutput(tr.Name)
+	}
+	defer func() { tracks.DeployTrack = tracks.ExecuteDeployTrack }()
+
+	approver := &stubApprover{}
+	tracker := tracks.DirectoryBasedTracker{Fs: fs, Log: logger, Approver: approver}
+
+	// act
+	tracker.ExecuteTracks(config.Config{TargetAll: true, Action: config.DeployAction})
+
+	// assert
+	require.Len(t, deployCfgSpy, stubTrackCount, "Tracks should only be executed once")
+	for i, cfg := range deployCfgSpy {
+		require.False(t, cfg.DryRun)
+		require.Same(t, approver, executions[i].Approver)
+		require.NotEmpty(t, executions[i].PlanDir, "Steps should save their plans for approval")
+	}
+
+	require.Empty(t, approver.gates, "Steps are approved as they are planned")
+}
+
+// stubPlanningStep executes a step, saving a plan with the planned changes when planning into a plan bundle
+func stubPlanningStep(t *testing.T, savePlans bool, plannedChanges map[string][]string, spy *[]config.Step) tracks.ExecuteStepFunc {
+	mutex := &sync.Mutex{}
+
+	return func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		mutex.Lock()
+		*spy = append(*spy, s)
+		mutex.Unlock()
+
+		s.Output = config.StepOutput{Status: config.Success, StepName: s.Name, Region: region, RegionDeployType: regionDeployType}
+
+		if s.DeployConfig.Action == config.PlanAction {
+			s.Output.PlannedChanges = plannedChanges
+
+			if savePlans {
+				exec := config.StepExecution{TrackName: s.TrackName, StepName: s.Name, RegionDeployType: regionDeployType, Region: region}
+				planFile := filepath.Join(s.DeployConfig.PlanBundleDir, "tfplan")
+				require.NoError(t, afero.WriteFile(fs, planFile, []byte("plan"), 0644))
+
+				_, err := planbundle.SavePlan(fs, s.DeployConfig.PlanBundleDir, exec, planFile, []byte("{}"), "source", "inputs")
+				require.NoError(t, err)
+			}
+		}
+
+		out <- s
+	}
+}
+
+func stubGatedExecution(approver tracks.Approver, orderedSteps map[int][]config.Step) tracks.RegionExecution {
+	return tracks.RegionExecution{
+		TrackName:                  "track",
+		Logger:                     logger,
+		Fs:                         afero.NewMemMapFs(),
+		TrackStepProgressionsCount: len(orderedSteps),
+		TrackOrderedSteps:          orderedSteps,
+		Region:                     "primaryregion",
+		RegionDeployType:           config.PrimaryRegionDeployType,
+		Approver:                   approver,
+		PlanDir:                    "/plans",
+	}
+}
+
+func executeRegion(execution tracks.RegionExecution) tracks.RegionExecution {
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- execution
+
+	return <-outChan
+}
+
+func TestExecuteDeployTrackRegion_ShouldApplyApprovedPlan(t *testing.T) {
+	executeStepSpy := []config.Step{}
+	tracks.ExecuteStep = stubPlanningStep(t, true, map[string][]string{"[create]": {"azurerm_resource_group.rg"}}, &executeStepSpy)
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	approver := &stubApprover{}
+	step := config.Step{Name: "step", TrackName: "track", DeployConfig: config.Config{Action: config.DeployAction}}
+
+	// act
+	regionOutput := executeRegion(stubGatedExecution(approver, map[int][]config.Step{1: {step}}))
+
+	// assert
+	require.Len(t, executeStepSpy, 2, "Step should be planned once, then applied")
+	require.Equal(t, config.PlanAction, executeStepSpy[0].DeployConfig.Action)
+	require.True(t, executeStepSpy[0].DeployConfig.DryRun)
+	require.Equal(t, config.ApplyAction, executeStepSpy[1].DeployConfig.Action)
+	require.False(t, executeStepSpy[1].DeployConfig.DryRun)
+	require.Equal(t, executeStepSpy[0].DeployConfig.PlanBundleDir, executeStepSpy[1].DeployConfig.PlanBundleDir, "The approved plan should be applied")
+
+	require.Len(t, approver.gates, 1)
+	require.Equal(t, "track-step-primary-primaryregion", approver.gates[0].ID)
+	require.Contains(t, approver.gates[0].Summary, "track/step/primary/primaryregion: [create] 1")
+
+	require.Equal(t, config.Success, regionOutput.Output.Steps["step"].Output.Status)
+	require.Equal(t, config.DeployAction, regionOutput.Output.Steps["step"].DeployConfig.Action)
+}
+
+func TestExecuteDeployTrackRegion_ShouldApplyPlanWithoutChangesWithoutApproval(t *testing.T) {
+	executeStepSpy := []config.Step{}
+	tracks.ExecuteStep = stubPlanningStep(t, true, map[string][]string{}, &executeStepSpy)
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	approver := &stubApprover{err: tracks.ErrApprovalRejected}
+	step := config.Step{Name: "step", TrackName: "track", DeployConfig: config.Config{Action: config.DeployAction}}
+
+	// act
+	regionOutput := executeRegion(stubGatedExecution(approver, map[int][]config.Step{1: {step}}))
+
+	// assert
+	require.Empty(t, approver.gates)
+	require.Len(t, executeStepSpy, 2)
+	require.Equal(t, config.ApplyAction, executeStepSpy[1].DeployConfig.Action)
+	require.Equal(t, config.Success, regionOutput.Output.Steps["step"].Output.Status)
+}
+
+func TestExecuteDeployTrackRegion_ShouldSkipLaterStepsWhenApprovalIsRejected(t *testing.T) {
+	executeStepSpy := []config.Step{}
+	tracks.ExecuteStep = stubPlanningStep(t, true, map[string][]string{"[create]": {"azurerm_resource_group.rg"}}, &executeStepSpy)
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	approver := &stubApprover{err: tracks.ErrApprovalRejected}
+	deployConfig := config.Config{Action: config.DeployAction}
+
+	// act
+	regionOutput := executeRegion(stubGatedExecution(approver, map[int][]config.Step{
+		1: {{Name: "step1", TrackName: "track", DeployConfig: deployConfig}},
+		2: {{Name: "step2", TrackName: "track", DeployConfig: deployConfig}},
+	}))
+
+	// assert
+	require.Len(t, executeStepSpy, 1, "Only the first step should be planned")
+	require.Len(t, approver.gates, 1)
+
+	require.Equal(t, config.Skipped, regionOutput.Output.Steps["step1"].Output.Status)
+	require.ErrorIs(t, regionOutput.Output.Steps["step1"].Output.Err, tracks.ErrApprovalRejected)
+	require.Equal(t, config.Skipped, regionOutput.Output.Steps["step2"].Output.Status)
+	require.Equal(t, 0, regionOutput.Output.FailureCount, "Steps that were not approved should not fail the region")
+	require.Equal(t, 2, regionOutput.Output.SkippedCount)
+	require.Contains(t, regionOutput.SkipReason, "not approved")
+}
+
+func TestExecuteDeployTrackRegion_ShouldDeployApprovedPreviewWhenPlansAreNotSaved(t *testing.T) {
+	executeStepSpy := []config.Step{}
+	tracks.ExecuteStep = stubPlanningStep(t, false, nil, &executeStepSpy)
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	approver := &stubApprover{}
+	step := config.Step{Name: "step", TrackName: "track", DeployConfig: config.Config{Action: config.DeployAction}}
+
+	// act
+	regionOutput := executeRegion(stubGatedExecution(approver, map[int][]config.Step{1: {step}}))
+
+	// assert
+	require.Len(t, approver.gates, 1, "Previews should be approved even without planned changes")
+	require.Len(t, executeStepSpy, 2)
+	require.Equal(t, config.DeployAction, executeStepSpy[1].DeployConfig.Action)
+	require.False(t, executeStepSpy[1].DeployConfig.DryRun)
+	require.Equal(t, config.Success, regionOutput.Output.Steps["step"].Output.Status)
+}
+
+func TestExecuteDeployTrackRegion_ShouldSkipStepsWithSkipReason(t *testing.T) {
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	executeStepSpy := map[string]config.Step{}
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		executeStepSpy[s.Name] = s
+		out <- s
+	}
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	regionalExecution := tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		Output:                     tracks.ExecutionOutput{},
+		TrackStepProgressionsCount: 1,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {
+				{Name: "step_r1", RegionalResourcesExist: true},
+			},
+		},
+		Region:           "regionalregion",
+		RegionDeployType: config.RegionalRegionDeployType,
+		SkipReason:       "regional deployment was not approved",
+	}
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- regionalExecution
+	regionOutput := <-outChan
+
+	require.Len(t, executeStepSpy, 0, "Should not execute steps that were not approved")
+	require.Equal(t, config.Skipped, regionOutput.Output.Steps["step_r1"].Output.Status)
+}
@@ -2,6 +2,7 @@ package tracks
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 	"github.com/otiai10/copy"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
+	"github.com/spf13/viper"
 )
 
 const (
@@ -31,24 +33,33 @@ type ExecuteTrackRegionFunc func(in <-chan RegionExecution, out chan<- RegionExe
 type ExecuteStepFunc func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
 	s config.Step, out chan<- config.Step, destroy bool)
 
+// ExecuteStepOutputsFunc reads the outputs of a previously deployed step without changing any resources
+type ExecuteStepOutputsFunc func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+	s config.Step, out chan<- config.Step)
+
 var DeployTrackRegion ExecuteTrackRegionFunc = ExecuteDeployTrackRegion
 var DestroyTrackRegion ExecuteTrackRegionFunc = ExecuteDestroyTrackRegion
+var GatherTrackRegionOutputs ExecuteTrackRegionFunc = ExecuteGatherTrackRegionOutputs
 
 var DeployTrack ExecuteTrackFunc = ExecuteDeployTrack
 var DestroyTrack ExecuteTrackFunc = ExecuteDestroyTrack
+var GatherTrack ExecuteTrackFunc = ExecuteGatherTrack
 
 var ExecuteStep ExecuteStepFunc = ExecuteStepImpl
+var ExecuteStepOutputs ExecuteStepOutputsFunc = ExecuteStepOutputsImpl
 
 // Tracker is an interface for working with tracks
 type Tracker interface {
 	GatherTracks(config config.Config) (tracks []Track)
 	ExecuteTracks(config config.Config) (output Stage)
+	DestroyTracks(config config.Config) (output Stage)
 }
 
 // DirectoryBasedTracker implements the Tracker interface
 type DirectoryBasedTracker struct {
-	Log *logrus.Entry
-	Fs  afero.Fs
+	Log      *logrus.Entry
+	Fs       afero.Fs
+	Approver Approver // Receives approvals at approval gates, gates are disabled when nil
 }
 
 // Track represents a delivery framework track (unit of functionality)
@@ -80,6 +91,8 @@ type Execution struct {
 	Output                              ExecutionOutput
 	DefaultExecutionStepOutputVariables map[string]map[string]map[string]string
 	PreTrackOutput                      *Output
+	Approver                            Approver // Receives approvals at approval gates, gates are disabled when nil
+	PlanDir                             string   // Where plans awaiting approval are saved, steps are not gated when empty
 }
 
 type RegionExecution struct {
@@ -95,6 +108,9 @@ type RegionExecution struct {
 	RegionDeployType           config.RegionDeployType
 	PrimaryOutput              ExecutionOutput // This value is only set when regiondeploytype == regional
 	DefaultStepOutputVariables map[string]map[string]string
+	SkipReason                 string   // When set, every step in the region is skipped for this reason
+	Approver                   Approver // Receives approvals of each step's plan when PlanDir is set
+	PlanDir                    string   // Where plans awaiting approval are saved, steps are not gated when empty
 }
 
 // TrackOutput represents the output from a track execution
@@ -173,6 +189,38 @@ func copyDefault(source, destination string) error {
 	return err
 }
 
+// determineRunnerName chooses the runner for a step. The runner set in the step's runiac.yml is used first,
+// followed by the configured runner when it detects the step's files, then any other runner detecting them.
+// Steps that no runner detects use the configured runner.
+func (tracker DirectoryBasedTracker) determineRunnerName(cfg config.Config, stepDir string, stepConfig *viper.Viper) (string, error) {
+	if name := stepConfig.GetString("runner"); name != "" {
+		if _, ok := config.GetRunner(name); !ok {
+			return "", fmt.Errorf("runner %s is not registered, registered runners: %v", name, config.RunnerNames())
+		}
+
+		return name, nil
+	}
+
+	// steps with only regional resources are detected from their regional directory
+	dirs := []string{stepDir, filepath.Join(stepDir, "regional")}
+
+	if runner, ok := config.GetRunner(cfg.Runner); ok && runner.Detect != nil {
+		for _, dir := range dirs {
+			if runner.Detect(tracker.Fs, dir) {
+				return runner.Name, nil
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		if runner, ok := config.DetectRunner(tracker.Fs, dir); ok {
+			return runner.Name, nil
+		}
+	}
+
+	return cfg.Runner, nil
+}
+
 func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, dir string) (Track, bool, error) {
 	t := Track{
 		Name:         name,
@@ -189,10 +237,9 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 	}
 
 	if t.IsDefaultTrack {
-		matches, _ := afero.Glob(tracker.Fs, "*.tf") // TODO(plugin): shift this check to a plugin to support more than terraform
-		if len(matches) > 0 {
+		if _, detected := config.DetectRunner(tracker.Fs, dir); detected {
 			_ = tracker.Fs.MkdirAll("./tracks/default/", 0755)
-			err := copyDefault("./", "./tracks/default/")
+			err := copyDefault(dir, "./tracks/default/")
 			if err != nil {
 				tracker.Log.WithError(err).Error("Failed to set up default track step")
 				return t, false, err
@@ -219,8 +266,14 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 	//	return t, false, nil
 	//}
 
+	// if tracks are being targeted, skip the non-targeted tracks
+	if len(cfg.TrackWhitelist) > 0 && !cfg.TargetAll && !containsTrack(cfg.TrackWhitelist, name) {
+		tracker.Log.Warning(fmt.Sprintf("Tracks: Skipping %s. Not present in track whitelist.", name))
+		return t, false, nil
+	}
+
 	// if steps are not being targeted and track are, skip the non-targeted tracks
-	if len(cfg.StepWhitelist) == 0 && !cfg.TargetAll {
+	if len(cfg.StepWhitelist) == 0 && len(cfg.TrackWhitelist) == 0 && !cfg.TargetAll {
 		tracker.Log.Warning(fmt.Sprintf("Tracks: Skipping %s", name))
 		return t, false, nil
 	} else {
@@ -244,7 +297,7 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 				}
 
 				// if step is not targeted, skip.
-				if !contains(cfg.StepWhitelist, stepID) && !cfg.TargetAll {
+				if len(cfg.StepWhitelist) > 0 && !contains(cfg.StepWhitelist, stepID) && !cfg.TargetAll {
 					tracker.Log.Warningf("Step %s disabled. Not present in whitelist.", stepID)
 					continue
 				}
@@ -269,15 +322,37 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 					ID:               stepID,
 				}
 
-				step.TestsExist = fileExists(tracker.Fs, filepath.Join(step.Dir, "tests/tests.test"))
+				step.TestsExist = testsExist(tracker.Fs, step.Dir)
 				step.RegionalResourcesExist = exists(tracker.Fs, filepath.Join(step.Dir, "regional"))
+
+				stepConfig, err := config.ReadStepConfig(tracker.Fs, step.Dir)
+				if err != nil {
+					tracker.Log.WithError(err).Errorf("Tracks: Skipping %s. Unable to read the configuration of step %s.", name, stepID)
+					return t, false, err
+				}
+
+				runnerName, err := tracker.determineRunnerName(cfg, step.Dir, stepConfig)
+				if err != nil {
+					tracker.Log.WithError(err).Errorf("Tracks: Skipping %s. Unable to determine the runner for step %s.", name, stepID)
+					return t, false, err
+				}
+
+				err = config.InitializeRunner(runnerName, cfg, tracker.Log)
+				if errors.Is(err, config.ErrRunnerNotRegistered) {
+					tracker.Log.WithError(err).Warnf("Step %s has no registered runner", stepID)
+				} else if err != nil {
+					tracker.Log.WithError(err).Errorf("Tracks: Skipping %s. Unable to initialize the %s runner for step %s.", name, runnerName, stepID)
+					return t, false, err
+				}
+
+				step.DeployConfig.Runner = runnerName
 				step.Runner = steps.DetermineRunner(step)
 
 				if step.RegionalResourcesExist {
-					step.RegionalTestsExist = fileExists(tracker.Fs, filepath.Join(step.Dir, "regional", "tests/tests.test"))
+					step.RegionalTestsExist = testsExist(tracker.Fs, filepath.Join(step.Dir, "regional"))
 				}
 
-				tracker.Log.Infof("Adding Step %s. Tests Exist: %v. Regional Resources Exist: %v. Regional Tests Exist: %v.", stepID, step.TestsExist, step.RegionalResourcesExist, step.RegionalTestsExist)
+				tracker.Log.Infof("Adding Step %s. Runner: %s. Tests Exist: %v. Regional Resources Exist: %v. Regional Tests Exist: %v.", stepID, runnerName, step.TestsExist, step.RegionalResourcesExist, step.RegionalTestsExist)
 
 				// let track know it needs to execute regionally as well
 				if !t.RegionalDeployment && step.RegionalResourcesExist {
@@ -313,6 +388,21 @@ func fileExists(fs afero.Fs, filename string) bool {
 	return !info.IsDir()
 }
 
+// testsExist checks if a step directory has a compiled tests/tests.test, go tests within tests or native *.tftest.hcl tests
+func testsExist(fs afero.Fs, dir string) bool {
+	if fileExists(fs, filepath.Join(dir, "tests", "tests.test")) {
+		return true
+	}
+
+	for _, pattern := range []string{"tests/*_test.go", "*.tftest.hcl", "tests/*.tftest.hcl"} {
+		if matches, err := afero.Glob(fs, filepath.Join(dir, pattern)); err == nil && len(matches) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 // isEmpty checks if a file or dir exists and is not empty
 func exists(fs afero.Fs, filename string) bool {
 	info, err := afero.IsEmpty(fs, filename)
@@ -322,7 +412,21 @@ func exists(fs afero.Fs, filename string) bool {
 // ExecuteTracks executes all tracks in parallel.
 // If a _pretrack exists, this is executed before
 // all other tracks.
+// When approval gates are enabled, each step's plan is saved and only applied once it is approved.
 func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output Stage) {
+	planDir := ""
+	if tracker.Approver != nil && !cfg.DryRun && cfg.Action == config.DeployAction {
+		var err error
+		planDir, err = afero.TempDir(tracker.Fs, "", "runiac-approval-plans")
+
+		if err != nil {
+			tracker.Log.WithError(err).Error("Unable to create directory for plans awaiting approval, no tracks will be executed")
+			return
+		}
+
+		defer func() { _ = tracker.Fs.RemoveAll(planDir) }()
+	}
+
 	output.Tracks = map[string]Track{}
 	var tracks = tracker.GatherTracks(cfg) // **All** tracks
 	var parallelTracks []Track             // Tracks that should be executed in parallel
@@ -351,6 +455,8 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 			Fs:                                  tracker.Fs,
 			Output:                              ExecutionOutput{},
 			DefaultExecutionStepOutputVariables: map[string]map[string]map[string]string{},
+			Approver:                            tracker.Approver,
+			PlanDir:                             planDir,
 		}
 		go DeployTrack(preTrackExecution, cfg, preTrack, preTrackChan)
 		// Wait for the track to contain an item,
@@ -391,6 +497,8 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 			Fs:                                  tracker.Fs,
 			Output:                              ExecutionOutput{},
 			DefaultExecutionStepOutputVariables: map[string]map[string]map[string]string{},
+			Approver:                            tracker.Approver,
+			PlanDir:                             planDir,
 		}
 		// If there is a pretrack, add its outputs
 		// to the execution so they are available.
@@ -412,80 +520,125 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 	}
 
 	// If SelfDestroy or Destroy is set (e.g. during PRs), destroy any resources created by the tracks
-	if cfg.SelfDestroy && !cfg.DryRun {
-		tracker.Log.Info("Executing destroy...")
-		trackDestroyChan := make(chan Output)
+	if cfg.SelfDestroy && !cfg.DryRun && cfg.Action != config.DriftAction && cfg.Action != config.UnlockAction {
+		var preTrackOutput *Output
+		if preTrackExists {
+			preTrackOutput = &preTrack.Output
+		}
 
-		for _, t := range parallelTracks {
-			executionStepOutputVariables := map[string]map[string]map[string]string{}
+		tracker.destroyTracks(cfg, output, parallelTracks, preTrackOutput)
+	}
 
-			for _, exec := range output.Tracks[t.Name].Output.Executions {
-				executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
-			}
+	return
+}
 
-			if tracker.Log.Level == logrus.DebugLevel {
-				jsonBytes, _ := json.Marshal(executionStepOutputVariables)
+// DestroyTracks destroys all targeted tracks without deploying them first.
+// Step outputs are gathered from state, and the _pretrack, if it exists, is destroyed
+// after all other tracks.
+func (tracker DirectoryBasedTracker) DestroyTracks(cfg config.Config) (output Stage) {
+	output.Tracks = map[string]Track{}
+	var tracks = tracker.GatherTracks(cfg) // **All** tracks
+	var parallelTracks []Track             // Tracks that should be destroyed in parallel
+	var preTrackOutput *Output
 
-				tracker.Log.Debugf("OUTPUT VARS: %s", string(jsonBytes))
-			}
+	for _, t := range tracks {
+		output.Tracks[t.Name] = t
+		if t.IsPreTrack {
+			// gather pretrack outputs first, all other tracks depend on them
+			tracker.Log.Debug("Pre-track gathering outputs")
 
-			execution := Execution{
+			preTrackChan := make(chan Output)
+			go GatherTrack(Execution{
 				Logger:                              tracker.Log,
 				Fs:                                  tracker.Fs,
 				Output:                              ExecutionOutput{},
-				DefaultExecutionStepOutputVariables: executionStepOutputVariables,
-			}
+				DefaultExecutionStepOutputVariables: map[string]map[string]map[string]string{},
+			}, cfg, t, preTrackChan)
+
+			gathered := <-preTrackChan
+			preTrackOutput = &gathered
+		} else {
+			parallelTracks = append(parallelTracks, t)
+		}
+	}
+
+	tracker.destroyTracks(cfg, output, parallelTracks, preTrackOutput)
+
+	return
+}
+
+// destroyTracks destroys tracks in parallel, followed by the _pretrack when preTrackOutput is set.
+// Destroy outputs are recorded on the matching tracks in output.
+func (tracker DirectoryBasedTracker) destroyTracks(cfg config.Config, output Stage, parallelTracks []Track, preTrackOutput *Output) {
+	tracker.Log.Info("Executing destroy...")
+	trackDestroyChan := make(chan Output)
+
+	for _, t := range parallelTracks {
+		executionStepOutputVariables := map[string]map[string]map[string]string{}
+
+		for _, exec := range output.Tracks[t.Name].Output.Executions {
+			executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
+		}
+
+		if tracker.Log.Level == logrus.DebugLevel {
+			jsonBytes, _ := json.Marshal(executionStepOutputVariables)
+
+			tracker.Log.Debugf("OUTPUT VARS: %s", string(jsonBytes))
+		}
+
+		execution := Execution{
+			Logger:                              tracker.Log,
+			Fs:                                  tracker.Fs,
+			Output:                              ExecutionOutput{},
+			DefaultExecutionStepOutputVariables: executionStepOutputVariables,
 			// If there is a pretrack, add its outputs
 			// to the execution so they are available.
-			if preTrackExists {
-				execution.PreTrackOutput = &preTrack.Output
-			}
-			go DestroyTrack(execution, cfg, t, trackDestroyChan)
+			PreTrackOutput: preTrackOutput,
 		}
+		go DestroyTrack(execution, cfg, t, trackDestroyChan)
+	}
 
-		// wait for all executions to finish (this loop matches above range)
-		for range parallelTracks {
-			// waiting to append <-trackDestroyChan Track N times will inherently wait for all above executions to finish
-			tDestroyOutout := <-trackDestroyChan
+	// wait for all executions to finish (this loop matches above range)
+	for range parallelTracks {
+		// waiting to append <-trackDestroyChan Track N times will inherently wait for all above executions to finish
+		tDestroyOutout := <-trackDestroyChan
 
-			if t, ok := output.Tracks[tDestroyOutout.Name]; ok {
-				// TODO: is it better to have a pointer for map value?
-				t.DestroyOutput = tDestroyOutout
-				output.Tracks[tDestroyOutout.Name] = t
-			}
+		if t, ok := output.Tracks[tDestroyOutout.Name]; ok {
+			// TODO: is it better to have a pointer for map value?
+			t.DestroyOutput = tDestroyOutout
+			output.Tracks[tDestroyOutout.Name] = t
 		}
+	}
 
-		// Destroy _pretrack if it exists
-		if preTrackExists {
-			tracker.Log.Debug("Pre-track destroying")
-			executionStepOutputVariables := map[string]map[string]map[string]string{}
+	// Destroy _pretrack if it exists
+	if preTrackOutput != nil {
+		preTrack := output.Tracks[PRE_TRACK_NAME]
 
-			for _, exec := range output.Tracks[preTrack.Name].Output.Executions {
-				executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
-			}
+		tracker.Log.Debug("Pre-track destroying")
+		executionStepOutputVariables := map[string]map[string]map[string]string{}
 
-			destroyPreTrackChan := make(chan Output)
-			preTrackDestroyExecution := Execution{
-				Logger:                              tracker.Log,
-				Fs:                                  tracker.Fs,
-				Output:                              ExecutionOutput{},
-				DefaultExecutionStepOutputVariables: executionStepOutputVariables,
-				PreTrackOutput:                      &preTrack.Output,
-			}
-			go DestroyTrack(preTrackDestroyExecution, cfg, preTrack, destroyPreTrackChan)
-			// Wait for the track to contain an item,
-			// indicating the track has been destroyed.
-			preTrackDestroyOutput := <-destroyPreTrackChan
-			preTrack.DestroyOutput = preTrackDestroyOutput
-			tracker.Log.Debug("Pre-track destroy finished")
-			if t, ok := output.Tracks[preTrackDestroyOutput.Name]; ok {
-				t.DestroyOutput = preTrackDestroyOutput
-				output.Tracks[preTrackDestroyOutput.Name] = t
-			}
+		for _, exec := range preTrackOutput.Executions {
+			executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
 		}
-	}
 
-	return
+		destroyPreTrackChan := make(chan Output)
+		preTrackDestroyExecution := Execution{
+			Logger:                              tracker.Log,
+			Fs:                                  tracker.Fs,
+			Output:                              ExecutionOutput{},
+			DefaultExecutionStepOutputVariables: executionStepOutputVariables,
+			PreTrackOutput:                      preTrackOutput,
+		}
+		go DestroyTrack(preTrackDestroyExecution, cfg, preTrack, destroyPreTrackChan)
+		// Wait for the track to contain an item,
+		// indicating the track has been destroyed.
+		preTrackDestroyOutput := <-destroyPreTrackChan
+		tracker.Log.Debug("Pre-track destroy finished")
+		if t, ok := output.Tracks[preTrackDestroyOutput.Name]; ok {
+			t.DestroyOutput = preTrackDestroyOutput
+			output.Tracks[preTrackDestroyOutput.Name] = t
+		}
+	}
 }
 
 // Adds step outputs variables to the track output variables map
@@ -534,9 +687,14 @@ func AppendPreTrackOutputsToDefaultStepOutputVariables(defaultStepOutputVariable
 
 // ExecuteDeployTrack is for executing a single track across regions
 func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out chan<- Output) {
+	action := config.DeployAction
+	if cfg.Action == config.DriftAction || cfg.Action == config.UnlockAction {
+		action = cfg.Action
+	}
+
 	logger := execution.Logger.WithFields(logrus.Fields{
 		"track":  t.Name,
-		"action": "deploy",
+		"action": action,
 	})
 
 	output := Output{
@@ -562,6 +720,8 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 		Region:                     region,
 		RegionDeployType:           config.PrimaryRegionDeployType,
 		DefaultStepOutputVariables: map[string]map[string]string{},
+		Approver:                   execution.Approver,
+		PlanDir:                    execution.PlanDir,
 	}
 
 	if val, ok := execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", primaryRegionExecution.RegionDeployType, primaryRegionExecution.Region)]; ok {
@@ -601,6 +761,20 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 
 	logger.Infof("Primary region successfully completed, executing regional deployments in %v.", targetRegions)
 
+	// pause before the regional wave, a rejection or timeout skips every regional step, as does a primary step that was not approved
+	skipReason := primaryTrackExecution.SkipReason
+	if execution.Approver != nil && cfg.ApprovalRegional && !cfg.DryRun && primaryTrackExecution.Output.FailureCount == 0 && skipReason == "" {
+		err := waitForApproval(cfg.Context, execution.Approver, logger, cfg.ApprovalTimeout, ApprovalGate{
+			ID:          fmt.Sprintf("%s-regional", t.Name),
+			Description: fmt.Sprintf("Deploy track %s to regions %v", t.Name, targetRegions),
+			Summary:     strings.Join(executionSummary(t.Name, primaryTrackExecution), "\n"),
+		})
+
+		if err != nil {
+			skipReason = fmt.Sprintf("regional deployment was not approved: %s", err)
+		}
+	}
+
 	for i := 0; i < targetRegionsCount; i++ {
 		go DeployTrackRegion(regionInChan, regionOutChan)
 	}
@@ -628,6 +802,9 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 			RegionDeployType:           config.RegionalRegionDeployType,
 			DefaultStepOutputVariables: outputVars,
 			PrimaryOutput:              primaryTrackExecution.Output,
+			SkipReason:                 skipReason,
+			Approver:                   execution.Approver,
+			PlanDir:                    execution.PlanDir,
 		}
 
 		// Add step outputs for regional steps
@@ -659,7 +836,9 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 	out <- output
 }
 
-// ExecuteDestroyTrack is a helper function for destroying a track
+// ExecuteDestroyTrack is a helper function for destroying a track.
+// When no previous step output variables are provided for a region (e.g. destroy is running without a preceding deploy),
+// they are first gathered from each step's state in progression order so downstream steps receive the same inputs as during deploy.
 func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out chan<- Output) {
 	trackLogger := execution.Logger.WithFields(logrus.Fields{
 		"track":  t.Name,
@@ -671,7 +850,37 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 		Executions: []RegionExecution{},
 	}
 
-	// TODO(high): need to gather previous step variables before attempting to destroy!
+	region := cfg.PrimaryRegion // TODO(cfg:region): allow this to be overridden
+
+	primaryExecution := RegionExecution{
+		TrackName:                  t.Name,
+		TrackDir:                   t.Dir,
+		TrackStepProgressionsCount: t.StepProgressionsCount,
+		TrackOrderedSteps:          t.OrderedSteps,
+		Logger:                     trackLogger,
+		Fs:                         execution.Fs,
+		Output:                     ExecutionOutput{},
+		Region:                     region,
+		RegionDeployType:           config.PrimaryRegionDeployType,
+	}
+
+	primaryOutputVariables, primaryOutputsExist := execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.PrimaryRegionDeployType, region)]
+	primaryExecution.DefaultStepOutputVariables = primaryOutputVariables
+
+	if !primaryOutputsExist {
+		primaryExecution.DefaultStepOutputVariables = map[string]map[string]string{}
+	}
+
+	// Add step outputs for primary steps
+	// from the pretrack
+	if execution.PreTrackOutput != nil {
+		primaryExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(primaryExecution.DefaultStepOutputVariables, execution.PreTrackOutput, primaryExecution.RegionDeployType, primaryExecution.Region)
+	}
+
+	if !primaryOutputsExist {
+		trackLogger.Info("No previous step outputs available for primary region, gathering outputs before destroying")
+		primaryExecution.DefaultStepOutputVariables = gatherRegionOutputs(primaryExecution)[0].Output.StepOutputVariables
+	}
 
 	// start with regional if existing
 	if t.RegionalDeployment {
@@ -681,9 +890,8 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 		targetRegions := cfg.RegionalRegions
 		targetRegionsCount := len(cfg.RegionalRegions)
 
-		for i := 0; i < targetRegionsCount; i++ {
-			go DestroyTrackRegion(regionInChan, regionOutChan)
-		}
+		regionExecutions := []RegionExecution{}
+		gatherExecutions := []RegionExecution{}
 
 		for _, reg := range targetRegions {
 			regionExecution := RegionExecution{
@@ -696,7 +904,17 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 				Output:                     ExecutionOutput{},
 				Region:                     reg,
 				RegionDeployType:           config.RegionalRegionDeployType,
-				DefaultStepOutputVariables: execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.RegionalRegionDeployType, reg)],
+			}
+
+			regionalOutputVariables, regionalOutputsExist := execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.RegionalRegionDeployType, reg)]
+			regionExecution.DefaultStepOutputVariables = regionalOutputVariables
+
+			if !regionalOutputsExist {
+				// regional steps receive primary step outputs during deploy, copy to avoid regions overwriting each other
+				regionExecution.DefaultStepOutputVariables = map[string]map[string]string{}
+				for k, v := range primaryExecution.DefaultStepOutputVariables {
+					regionExecution.DefaultStepOutputVariables[k] = v
+				}
 			}
 
 			// Add step outputs for regional steps
@@ -705,6 +923,28 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 				regionExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(regionExecution.DefaultStepOutputVariables, execution.PreTrackOutput, regionExecution.RegionDeployType, regionExecution.Region)
 			}
 
+			if regionalOutputsExist {
+				regionExecutions = append(regionExecutions, regionExecution)
+			} else {
+				gatherExecutions = append(gatherExecutions, regionExecution)
+			}
+		}
+
+		if len(gatherExecutions) > 0 {
+			trackLogger.Infof("No previous step outputs available for %v regional region(s), gathering outputs before destroying", len(gatherExecutions))
+
+			for _, gathered := range gatherRegionOutputs(gatherExecutions...) {
+				gathered.DefaultStepOutputVariables = gathered.Output.StepOutputVariables
+				gathered.Output = ExecutionOutput{}
+				regionExecutions = append(regionExecutions, gathered)
+			}
+		}
+
+		for i := 0; i < targetRegionsCount; i++ {
+			go DestroyTrackRegion(regionInChan, regionOutChan)
+		}
+
+		for _, regionExecution := range regionExecutions {
 			regionInChan <- regionExecution
 		}
 
@@ -718,36 +958,108 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 	primaryOutChan := make(chan RegionExecution, 1)
 	primaryInChan := make(chan RegionExecution, 1)
 
-	region := cfg.PrimaryRegion // TODO(cfg:region): allow this to be overridden
+	go DestroyTrackRegion(primaryInChan, primaryOutChan)
+	primaryInChan <- primaryExecution
+
+	primaryTrackOutput := <-primaryOutChan
+	output.Executions = append(output.Executions, primaryTrackOutput)
+
+	out <- output
+}
+
+// ExecuteGatherTrack gathers the step outputs of a previously deployed track across regions without changing any resources
+func ExecuteGatherTrack(execution Execution, cfg config.Config, t Track, out chan<- Output) {
+	logger := execution.Logger.WithFields(logrus.Fields{
+		"track":  t.Name,
+		"action": "output",
+	})
+
+	output := Output{
+		Name:       t.Name,
+		Executions: []RegionExecution{},
+	}
 
 	primaryExecution := RegionExecution{
 		TrackName:                  t.Name,
 		TrackDir:                   t.Dir,
 		TrackStepProgressionsCount: t.StepProgressionsCount,
 		TrackOrderedSteps:          t.OrderedSteps,
-		Logger:                     trackLogger,
+		Logger:                     logger,
 		Fs:                         execution.Fs,
 		Output:                     ExecutionOutput{},
-		Region:                     region,
+		Region:                     cfg.PrimaryRegion, // TODO(cfg:region): allow this to be overridden
 		RegionDeployType:           config.PrimaryRegionDeployType,
-		DefaultStepOutputVariables: execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.PrimaryRegionDeployType, region)],
+		DefaultStepOutputVariables: map[string]map[string]string{},
 	}
 
-	// Add step outputs for primary steps
-	// from the pretrack
 	if execution.PreTrackOutput != nil {
 		primaryExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(primaryExecution.DefaultStepOutputVariables, execution.PreTrackOutput, primaryExecution.RegionDeployType, primaryExecution.Region)
 	}
 
-	go DestroyTrackRegion(primaryInChan, primaryOutChan)
-	primaryInChan <- primaryExecution
+	primaryTrackExecution := gatherRegionOutputs(primaryExecution)[0]
+	output.Executions = append(output.Executions, primaryTrackExecution)
+	output.PrimaryStepOutputVariables = primaryTrackExecution.Output.StepOutputVariables
 
-	primaryTrackOutput := <-primaryOutChan
-	output.Executions = append(output.Executions, primaryTrackOutput)
+	if !t.RegionalDeployment {
+		out <- output
+		return
+	}
+
+	regionalExecutions := []RegionExecution{}
+	for _, reg := range cfg.RegionalRegions {
+		// copy to avoid regions overwriting each other while gathering
+		outputVars := map[string]map[string]string{}
+		for k, v := range primaryTrackExecution.Output.StepOutputVariables {
+			outputVars[k] = v
+		}
+
+		regionalExecution := RegionExecution{
+			TrackName:                  t.Name,
+			TrackDir:                   t.Dir,
+			TrackStepProgressionsCount: t.StepProgressionsCount,
+			TrackOrderedSteps:          t.OrderedSteps,
+			Logger:                     logger,
+			Fs:                         execution.Fs,
+			Output:                     ExecutionOutput{},
+			Region:                     reg,
+			RegionDeployType:           config.RegionalRegionDeployType,
+			DefaultStepOutputVariables: outputVars,
+		}
+
+		if execution.PreTrackOutput != nil {
+			regionalExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(regionalExecution.DefaultStepOutputVariables, execution.PreTrackOutput, regionalExecution.RegionDeployType, regionalExecution.Region)
+		}
+
+		regionalExecutions = append(regionalExecutions, regionalExecution)
+	}
+
+	output.Executions = append(output.Executions, gatherRegionOutputs(regionalExecutions...)...)
 
 	out <- output
 }
 
+// gatherRegionOutputs concurrently gathers step outputs for each of the region executions
+func gatherRegionOutputs(executions ...RegionExecution) []RegionExecution {
+	count := len(executions)
+	inChan := make(chan RegionExecution, count)
+	outChan := make(chan RegionExecution, count)
+
+	for i := 0; i < count; i++ {
+		go GatherTrackRegionOutputs(inChan, outChan)
+	}
+
+	for _, execution := range executions {
+		inChan <- execution
+	}
+
+	gathered := []RegionExecution{}
+	for i := 0; i < count; i++ {
+		gathered = append(gathered, <-outChan)
+	}
+
+	return gathered
+}
+
 func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecution) {
 	execution := <-in
 	logger := execution.Logger.WithFields(logrus.Fields{
@@ -794,6 +1106,28 @@ func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecut
 
 					slogger.Warn("Skipping step due to earlier step failures in this region")
 
+					s.Output.Status = config.Skipped
+					sChan <- s
+				}(s, logger)
+			} else if s.DeployConfig.Context != nil && s.DeployConfig.Context.Err() != nil {
+				go func(s config.Step, logger *logrus.Entry) {
+					slogger := logger.WithFields(logrus.Fields{
+						"step": s.Name,
+					})
+
+					slogger.Warn("Skipping step, the run was stopped")
+
+					s.Output.Status = config.Skipped
+					sChan <- s
+				}(s, logger)
+			} else if execution.SkipReason != "" {
+				go func(s config.Step, logger *logrus.Entry) {
+					slogger := logger.WithFields(logrus.Fields{
+						"step": s.Name,
+					})
+
+					slogger.Warnf("Skipping step, %s", execution.SkipReason)
+
 					s.Output.Status = config.Skipped
 					sChan <- s
 				}(s, logger)
@@ -808,6 +1142,8 @@ func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecut
 					s.Output.Status = config.Skipped
 					sChan <- s
 				}(s, logger)
+			} else if execution.PlanDir != "" {
+				go executeApprovedStep(execution, logger, copyStepOutputVariables(execution.Output.StepOutputVariables), progressionLevel, s, sChan)
 			} else {
 				go ExecuteStep(execution.Region, execution.RegionDeployType, logger, execution.Fs, execution.Output.StepOutputVariables, progressionLevel, s, sChan, false)
 			}
@@ -824,7 +1160,12 @@ func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecut
 			execution.Output.Steps[s.Name] = s
 			execution.Output.StepOutputVariables = AppendTrackOutput(execution.Output.StepOutputVariables, s.Output)
 
-			if s.Output.Err != nil || s.Output.Status == config.Fail {
+			if s.Output.Status == config.Skipped && errors.Is(s.Output.Err, errNotApproved) {
+				// later steps depend on the step that was not approved, they are skipped without failing the region
+				if execution.SkipReason == "" {
+					execution.SkipReason = s.Output.Err.Error()
+				}
+			} else if s.Output.Err != nil || s.Output.Status == config.Fail {
 				execution.Output.FailureCount++
 				execution.Output.FailedSteps = append(execution.Output.FailedSteps, s)
 			}
@@ -915,6 +1256,106 @@ func ExecuteDestroyTrackRegion(in <-chan RegionExecution, out chan<- RegionExecu
 	return
 }
 
+// ExecuteGatherTrackRegionOutputs reads step outputs from state within a single region and RegionDeployType.
+// Steps are read in progression order, so each step receives the outputs of earlier progressions just like during deploy.
+func ExecuteGatherTrackRegionOutputs(in <-chan RegionExecution, out chan<- RegionExecution) {
+	execution := <-in
+
+	logger := execution.Logger.WithFields(logrus.Fields{
+		"region":           execution.Region,
+		"regionDeployType": execution.RegionDeployType.String(),
+	})
+
+	execution.Output = ExecutionOutput{
+		Name:                execution.TrackName,
+		Dir:                 execution.TrackDir,
+		Steps:               map[string]config.Step{},
+		StepOutputVariables: execution.DefaultStepOutputVariables,
+	}
+
+	if execution.Output.StepOutputVariables == nil {
+		execution.Output.StepOutputVariables = map[string]map[string]string{}
+	}
+
+	for progressionLevel := 1; progressionLevel <= execution.TrackStepProgressionsCount; progressionLevel++ {
+		sChan := make(chan config.Step)
+		N := 0
+
+		for _, s := range execution.TrackOrderedSteps[progressionLevel] {
+			// regional resources do not exist
+			if execution.RegionDeployType == config.RegionalRegionDeployType && !s.RegionalResourcesExist {
+				continue
+			}
+
+			N++
+			go ExecuteStepOutputs(execution.Region, execution.RegionDeployType, logger, execution.Fs, execution.Output.StepOutputVariables, progressionLevel, s, sChan)
+		}
+
+		// wait for the whole progression before adding outputs, the map is shared with in-flight steps
+		gathered := []config.Step{}
+		for i := 0; i < N; i++ {
+			gathered = append(gathered, <-sChan)
+		}
+
+		for _, s := range gathered {
+			execution.Output.Steps[s.Name] = s
+
+			if s.Output.Err != nil || s.Output.Status == config.Fail {
+				logger.WithField("step", s.Name).WithError(s.Output.Err).Warn("Unable to gather step outputs, downstream steps will not receive them")
+				execution.Output.FailureCount++
+				execution.Output.FailedSteps = append(execution.Output.FailedSteps, s)
+				continue
+			}
+
+			execution.Output.ExecutedCount++
+			execution.Output.StepOutputVariables = AppendTrackOutput(execution.Output.StepOutputVariables, s.Output)
+		}
+	}
+
+	out <- execution
+}
+
+func ExecuteStepOutputsImpl(region string, regionDeployType config.RegionDeployType,
+	logger *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+	s config.Step, out chan<- config.Step) {
+
+	exec, err := steps.InitExecution(s, logger, fs, regionDeployType, region, defaultStepOutputVariables)
+
+	// if error initializing, short circuit
+	if err != nil {
+		s.Output = config.StepOutput{
+			Status:           config.Fail,
+			RegionDeployType: regionDeployType,
+			Region:           region,
+			StepName:         s.Name,
+			Err:              err,
+		}
+		out <- s
+		return
+	}
+
+	exec2, err := s.Runner.PreExecute(exec)
+
+	// outputs gathered from a directory that was not prepared would be wrong, fail the step instead
+	if err != nil {
+		logger.WithField("step", s.Name).WithError(err).Error("Unable to prepare step for gathering outputs")
+		s.Output = config.StepOutput{
+			Status:           config.Fail,
+			RegionDeployType: regionDeployType,
+			Region:           region,
+			StepName:         s.Name,
+			Err:              err,
+		}
+		out <- s
+		return
+	}
+
+	s.Output = steps.ExecuteStepOutputs(s.Runner, exec2)
+
+	out <- s
+	return
+}
+
 func ExecuteStepImpl(region string, regionDeployType config.RegionDeployType,
 	logger *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
 	s config.Step, out chan<- config.Step, destroy bool) {
@@ -969,6 +1410,10 @@ func executeStepTest(incomingLogger *logrus.Entry, fs afero.Fs, region string, r
 		logger.Warn("Skipping Tests Due to Deployment Error")
 	} else if s.DeployConfig.DryRun {
 		logger.Info("Skipping Tests for Dry Run")
+	} else if s.DeployConfig.Action == config.DriftAction {
+		logger.Info("Skipping Tests for Drift Detection")
+	} else if s.DeployConfig.Action == config.UnlockAction {
+		logger.Info("Skipping Tests for Unlock")
 	} else if s.Output.Status == config.Skipped {
 		logger.Warn("Skipping Tests because step was also skipped")
 	} else {
@@ -998,6 +1443,15 @@ func executeStepTest(incomingLogger *logrus.Entry, fs afero.Fs, region string, r
 	return
 }
 
+func containsTrack(s []string, e string) bool {
+	for _, a := range s {
+		if strings.EqualFold(a, e) {
+			return true
+		}
+	}
+	return false
+}
+
 func contains(s []string, e string) bool {
 	for _, a := range s {
 		if strings.ToLower(a) == strings.ToLower(e) || strings.ToLower(fmt.Sprintf("default/%s", a)) == strings.ToLower(e) {
@@ -1,6 +1,7 @@
 package tracks_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/golang/mock/gomock"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -28,6 +30,23 @@ var stubPreTrackName string
 var stubTrackNameA string
 var stubTrackNameB string
 
+type stubPlugin struct {
+	initializeCount int
+}
+
+func (p *stubPlugin) Initialize(logger *logrus.Entry) {
+	p.initializeCount++
+}
+
+var stubRunnerPlugin = &stubPlugin{}
+
+func stubDetect(file string) func(afero.Fs, string) bool {
+	return func(fs afero.Fs, dir string) bool {
+		exists, _ := afero.Exists(fs, filepath.Join(dir, file))
+		return exists
+	}
+}
+
 func TestMain(m *testing.M) {
 	// arrange
 
@@ -40,13 +59,27 @@ func TestMain(m *testing.M) {
 	tracks.DeployTrack = tracks.ExecuteDeployTrack
 	tracks.DeployTrackRegion = tracks.ExecuteDeployTrackRegion
 	tracks.DestroyTrackRegion = tracks.ExecuteDestroyTrackRegion
+	tracks.GatherTrackRegionOutputs = tracks.ExecuteGatherTrackRegionOutputs
 	tracks.ExecuteStep = tracks.ExecuteStepImpl
+	tracks.ExecuteStepOutputs = tracks.ExecuteStepOutputsImpl
 
 	sut = tracks.DirectoryBasedTracker{
 		Fs:  fs,
 		Log: logger,
 	}
 
+	config.RegisterRunner(config.Runner{
+		Name:       "stubterraform",
+		NewStepper: func() config.Stepper { return nil },
+		Plugin:     stubRunnerPlugin,
+		Detect:     stubDetect("main.tf"),
+	})
+	config.RegisterRunner(config.Runner{
+		Name:       "stubarm",
+		NewStepper: func() config.Stepper { return nil },
+		Detect:     stubDetect("main.json"),
+	})
+
 	stubStepWithTests = config.Step{
 		Name:                   "a11",
 		TestsExist:             true,
@@ -922,3 +955,385 @@ func TestExecuteDeployTrackRegion_ShouldNaWhenRegionalResourcesDoNotExist(t *tes
 	require.NotNil(t, primaryTrackExecution)
 	require.Equal(t, config.Na, primaryTrackExecution.Output.Steps["step_p1"].Output.Status)
 }
+
+func TestExecuteDestroyTrack_ShouldGatherOutputsWhenNotProvided(t *testing.T) {
+	stubPrimaryRegion := "primaryregion"
+	stubRegionalRegion := "regionalregion"
+
+	gatherSpy := map[string]map[string]map[string]string{}
+	destroySpy := map[string]map[string]map[string]string{}
+	mutex := &sync.Mutex{}
+
+	tracks.GatherTrackRegionOutputs = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+		execution := <-in
+		key := fmt.Sprintf("%s-%s", execution.RegionDeployType, execution.Region)
+
+		mutex.Lock()
+		gatherSpy[key] = execution.DefaultStepOutputVariables
+		mutex.Unlock()
+
+		execution.Output.StepOutputVariables = map[string]map[string]string{
+			"step1": {"from": key},
+		}
+		for k, v := range execution.DefaultStepOutputVariables {
+			execution.Output.StepOutputVariables[k+"-incoming"] = v
+		}
+		out <- execution
+	}
+	defer func() { tracks.GatherTrackRegionOutputs = tracks.ExecuteGatherTrackRegionOutputs }()
+
+	tracks.DestroyTrackRegion = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+		execution := <-in
+
+		mutex.Lock()
+		destroySpy[fmt.Sprintf("%s-%s", execution.RegionDeployType, execution.Region)] = execution.DefaultStepOutputVariables
+		mutex.Unlock()
+
+		out <- execution
+	}
+	defer func() { tracks.DestroyTrackRegion = tracks.ExecuteDestroyTrackRegion }()
+
+	trackChan := make(chan tracks.Output, 1)
+
+	// act
+	tracks.ExecuteDestroyTrack(tracks.Execution{
+		Logger: logger,
+		Fs:     fs,
+		Output: tracks.ExecutionOutput{},
+	}, config.Config{
+		PrimaryRegion:   stubPrimaryRegion,
+		RegionalRegions: []string{stubRegionalRegion},
+	}, tracks.Track{
+		RegionalDeployment: true,
+	}, trackChan)
+
+	mockOutput := <-trackChan
+
+	primaryKey := fmt.Sprintf("%s-%s", config.PrimaryRegionDeployType, stubPrimaryRegion)
+	regionalKey := fmt.Sprintf("%s-%s", config.RegionalRegionDeployType, stubRegionalRegion)
+
+	// assert
+	require.Len(t, mockOutput.Executions, 2)
+	require.Len(t, gatherSpy, 2, "Should gather outputs for primary and regional regions")
+	require.Equal(t, primaryKey, destroySpy[primaryKey]["step1"]["from"], "Primary destroy should receive gathered primary outputs")
+	require.Equal(t, regionalKey, destroySpy[regionalKey]["step1"]["from"], "Regional destroy should receive gathered regional outputs")
+	require.Equal(t, primaryKey, gatherSpy[regionalKey]["step1"]["from"], "Regional gather should start from gathered primary outputs")
+}
+
+func TestExecuteDestroyTrack_ShouldNotGatherOutputsWhenProvided(t *testing.T) {
+	stubPrimaryRegion := "primaryregion"
+	stubPrimaryStepOutputVars := map[string]map[string]string{
+		"step1": {"primary": "primary"},
+	}
+
+	gatherCount := 0
+	var destroySpy tracks.RegionExecution
+
+	tracks.GatherTrackRegionOutputs = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+		execution := <-in
+		gatherCount++
+		out <- execution
+	}
+	defer func() { tracks.GatherTrackRegionOutputs = tracks.ExecuteGatherTrackRegionOutputs }()
+
+	tracks.DestroyTrackRegion = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+		destroySpy = <-in
+		out <- destroySpy
+	}
+	defer func() { tracks.DestroyTrackRegion = tracks.ExecuteDestroyTrackRegion }()
+
+	trackChan := make(chan tracks.Output, 1)
+
+	// act
+	tracks.ExecuteDestroyTrack(tracks.Execution{
+		Logger: logger,
+		Fs:     fs,
+		Output: tracks.ExecutionOutput{},
+		DefaultExecutionStepOutputVariables: map[string]map[string]map[string]string{
+			fmt.Sprintf("%s-%s", config.PrimaryRegionDeployType, stubPrimaryRegion): stubPrimaryStepOutputVars,
+		},
+	}, config.Config{
+		PrimaryRegion: stubPrimaryRegion,
+	}, tracks.Track{}, trackChan)
+
+	<-trackChan
+
+	// assert
+	require.Equal(t, 0, gatherCount, "Should not gather outputs when provided by a preceding deploy")
+	require.Equal(t, stubPrimaryStepOutputVars, destroySpy.DefaultStepOutputVariables)
+}
+
+func TestExecuteGatherTrackRegionOutputs_ShouldPassOutputsToLaterProgressions(t *testing.T) {
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	spy := map[string]map[string]map[string]string{}
+	mutex := &sync.Mutex{}
+
+	tracks.ExecuteStepOutputs = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step) {
+		mutex.Lock()
+		spy[s.Name] = map[string]map[string]string{}
+		for k, v := range defaultStepOutputVariables {
+			spy[s.Name][k] = v
+		}
+		mutex.Unlock()
+
+		s.Output = config.StepOutput{
+			Status:           config.Success,
+			StepName:         s.Name,
+			RegionDeployType: regionDeployType,
+			Region:           region,
+			OutputVariables:  map[string]interface{}{"id": s.Name},
+		}
+		out <- s
+	}
+	defer func() { tracks.ExecuteStepOutputs = tracks.ExecuteStepOutputsImpl }()
+
+	go tracks.ExecuteGatherTrackRegionOutputs(inChan, outChan)
+	inChan <- tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		TrackStepProgressionsCount: 2,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "step_p1", ProgressionLevel: 1}},
+			2: {{Name: "step_p2", ProgressionLevel: 2}},
+		},
+		RegionDeployType: config.PrimaryRegionDeployType,
+	}
+
+	mockOutput := <-outChan
+
+	// assert
+	require.Empty(t, spy["step_p1"], "First progression should not receive any outputs")
+	require.Equal(t, "step_p1", spy["step_p2"]["step_p1"]["id"], "Second progression should receive outputs from the first")
+	require.Equal(t, "step_p2", mockOutput.Output.StepOutputVariables["step_p2"]["id"])
+	require.Equal(t, 2, mockOutput.Output.ExecutedCount)
+}
+
+func TestGetTracksWithTrackWhitelist_ShouldReturnOnlyWhitelistedTracks(t *testing.T) {
+	// act
+	mockTracks := sut.GatherTracks(config.Config{
+		TrackWhitelist: []string{stubTrackNameA},
+	})
+
+	// assert
+	require.Len(t, mockTracks, 1, "Only the whitelisted track should have been gathered")
+	require.Equal(t, stubTrackNameA, mockTracks[0].Name)
+	require.Equal(t, stubTracks[stubTrackNameA].StepsCount, mockTracks[0].StepsCount, "All steps in the whitelisted track should be included")
+}
+
+func TestDestroyTracks_ShouldGatherPreTrackOutputsAndDestroyPreTrackLast(t *testing.T) {
+	stubPreTrackOutput := tracks.Output{
+		Name: tracks.PRE_TRACK_NAME,
+		Executions: []tracks.RegionExecution{
+			{
+				Region:           "primaryregion",
+				RegionDeployType: config.PrimaryRegionDeployType,
+				Output: tracks.ExecutionOutput{
+					StepOutputVariables: map[string]map[string]string{
+						"pretrack_step": {"id": "pretrack"},
+					},
+				},
+			},
+		},
+	}
+
+	gatherSpy := []string{}
+	destroySpy := []string{}
+	destroyPreTrackOutputSpy := map[string]*tracks.Output{}
+	mutex := &sync.Mutex{}
+
+	tracks.GatherTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		gatherSpy = append(gatherSpy, t.Name)
+		out <- stubPreTrackOutput
+	}
+	defer func() { tracks.GatherTrack = tracks.ExecuteGatherTrack }()
+
+	tracks.DestroyTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		mutex.Lock()
+		destroySpy = append(destroySpy, t.Name)
+		destroyPreTrackOutputSpy[t.Name] = execution.PreTrackOutput
+		mutex.Unlock()
+
+		out <- tracks.Output{Name: t.Name}
+	}
+	defer func() { tracks.DestroyTrack = tracks.ExecuteDestroyTrack }()
+
+	deployCount := 0
+	tracks.DeployTrack = func(execution tracks.Execution, cfg config.Config, tr tracks.Track, out chan<- tracks.Output) {
+		deployCount++
+		out <- tracks.Output{Name: tr.Name}
+	}
+	defer func() { tracks.DeployTrack = tracks.ExecuteDeployTrack }()
+
+	// act
+	mockExecution := sut.DestroyTracks(config.Config{
+		TargetAll: true,
+		Action:    config.DestroyAction,
+	})
+
+	// assert
+	require.Equal(t, 0, deployCount, "Tracks should not be deployed when destroying")
+	require.Equal(t, []string{tracks.PRE_TRACK_NAME}, gatherSpy, "Only the pretrack outputs should be gathered up front")
+	require.Len(t, destroySpy, stubTrackCount, "All tracks should be destroyed")
+	require.Equal(t, tracks.PRE_TRACK_NAME, destroySpy[len(destroySpy)-1], "The pretrack should be destroyed last")
+
+	for name, preTrackOutput := range destroyPreTrackOutputSpy {
+		require.NotNil(t, preTrackOutput, "Track %s should receive the gathered pretrack outputs", name)
+		require.Equal(t, stubPreTrackOutput.Executions, preTrackOutput.Executions)
+	}
+
+	for _, tr := range mockExecution.Tracks {
+		require.Equal(t, tr.Name, tr.DestroyOutput.Name, "Destroy output should be recorded for track %s", tr.Name)
+	}
+}
+
+func TestGetTracks_ShouldDetermineRunnerPerStep(t *testing.T) {
+	runnerFs := afero.NewMemMapFs()
+	_ = afero.WriteFile(runnerFs, "tracks/mixed/step1_tf/main.tf", []byte{}, 0644)
+	_ = afero.WriteFile(runnerFs, "tracks/mixed/step1_arm/main.json", []byte{}, 0644)
+	_ = afero.WriteFile(runnerFs, "tracks/mixed/step2_configured/main.tf", []byte{}, 0644)
+	_ = afero.WriteFile(runnerFs, "tracks/mixed/step2_configured/runiac.yml", []byte("runner: stubarm\n"), 0644)
+	_ = afero.WriteFile(runnerFs, "tracks/mixed/step2_regionalonly/regional/main.json", []byte{}, 0644)
+	_ = runnerFs.MkdirAll("tracks/mixed/step3_empty", 0755)
+	_ = afero.WriteFile(runnerFs, "tracks/unknown/step1_x/runiac.yml", []byte("runner: missing\n"), 0644)
+
+	tracker := tracks.DirectoryBasedTracker{Fs: runnerFs, Log: logger}
+
+	// act
+	gathered := tracker.GatherTracks(config.Config{TargetAll: true, Runner: "stubterraform"})
+
+	// assert
+	require.Len(t, gathered, 1, "Tracks with an unregistered runner should be skipped")
+	require.Equal(t, "mixed", gathered[0].Name)
+
+	runners := map[string]string{}
+	for _, progression := range gathered[0].OrderedSteps {
+		for _, s := range progression {
+			runners[s.Name] = s.DeployConfig.Runner
+		}
+	}
+
+	require.Equal(t, map[string]string{
+		"tf":           "stubterraform",
+		"arm":          "stubarm",
+		"configured":   "stubarm",
+		"regionalonly": "stubarm",
+		"empty":        "stubterraform",
+	}, runners)
+	require.Equal(t, 1, stubRunnerPlugin.initializeCount, "Runner plugins should be initialized once")
+}
+
+func TestGetTracks_ShouldDetectNativeAndGoTests(t *testing.T) {
+	testFs := afero.NewMemMapFs()
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_compiled/tests/tests.test", []byte{}, 0644)
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_gosource/tests/bucket_test.go", []byte{}, 0644)
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_native/bucket.tftest.hcl", []byte{}, 0644)
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_nativetests/tests/bucket.tftest.hcl", []byte{}, 0644)
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_regional/regional/main.tf", []byte{}, 0644)
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_regional/regional/tests/bucket.tftest.hcl", []byte{}, 0644)
+	_ = afero.WriteFile(testFs, "tracks/tested/step1_untested/tests/helpers.go", []byte{}, 0644)
+
+	tracker := tracks.DirectoryBasedTracker{Fs: testFs, Log: logger}
+
+	// act
+	gathered := tracker.GatherTracks(config.Config{TargetAll: true, Runner: "stubterraform"})
+
+	// assert
+	require.Len(t, gathered, 1)
+
+	testsExist := map[string]bool{}
+	for _, s := range gathered[0].OrderedSteps[1] {
+		testsExist[s.Name] = s.TestsExist || s.RegionalTestsExist
+	}
+
+	require.Equal(t, map[string]bool{
+		"compiled":    true,
+		"gosource":    true,
+		"native":      true,
+		"nativetests": true,
+		"regional":    true,
+		"untested":    false,
+	}, testsExist)
+}
+
+func TestExecuteDeployTrackRegion_ShouldSkipStepsWhenTheRunIsStopped(t *testing.T) {
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	executeStepSpy := map[string]config.Step{}
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		executeStepSpy[s.Name] = s
+		out <- s
+	}
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	execution := tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		Output:                     tracks.ExecutionOutput{},
+		TrackStepProgressionsCount: 1,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {
+				{Name: "step_p1", DeployConfig: config.Config{Context: ctx}},
+			},
+		},
+		Region:           "primaryregion",
+		RegionDeployType: config.PrimaryRegionDeployType,
+	}
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- execution
+	regionOutput := <-outChan
+
+	require.Len(t, executeStepSpy, 0, "Should not start steps once the run is stopped")
+	require.Equal(t, config.Skipped, regionOutput.Output.Steps["step_p1"].Output.Status)
+	require.Equal(t, 1, regionOutput.Output.SkippedCount)
+}
+
+// stubPreExecuteErrorStepper fails to prepare every step and records the steps it executed
+type stubPreExecuteErrorStepper struct {
+	executed *[]string
+}
+
+func (s stubPreExecuteErrorStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, fmt.Errorf("unable to prepare %s", exec.StepName)
+}
+
+func (s stubPreExecuteErrorStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	*s.executed = append(*s.executed, exec.StepName)
+	return config.StepOutput{Status: config.Success}
+}
+
+func (s stubPreExecuteErrorStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return config.StepTestOutput{}
+}
+
+func (s stubPreExecuteErrorStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	return s.ExecuteStep(exec)
+}
+
+func (s stubPreExecuteErrorStepper) ExecuteStepOutputs(exec config.StepExecution) config.StepOutput {
+	return s.ExecuteStep(exec)
+}
+
+func TestExecuteStepOutputsImpl_ShouldFailWhenStepCannotBePrepared(t *testing.T) {
+	executed := []string{}
+	out := make(chan config.Step, 1)
+
+	step := config.Step{Name: "network", TrackName: "core", Dir: "/core/network", Runner: stubPreExecuteErrorStepper{executed: &executed}}
+
+	tracks.ExecuteStepOutputsImpl("primaryregion", config.PrimaryRegionDeployType, logger, afero.NewMemMapFs(), map[string]map[string]string{}, 1, step, out)
+	gathered := <-out
+
+	require.Empty(t, executed, "Outputs should not be gathered from a step that was not prepared")
+	require.Equal(t, config.Fail, gathered.Output.Status)
+	require.EqualError(t, gathered.Output.Err, "unable to prepare network")
+	require.Equal(t, "network", gathered.Output.StepName)
+}
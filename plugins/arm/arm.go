@@ -1,5 +1,7 @@
 package plugins_arm
 
+import "encoding/json"
+
 // Struct representing the final deployment metadata plan stored by ARM
 type deployment struct {
 	Name       string               `json:"name"`
@@ -8,10 +10,51 @@ type deployment struct {
 
 // Struct that contains a list of resources created as part of a template
 type deploymentProperties struct {
-	OutputResources []outputResource `json:"outputResources"`
+	OutputResources []outputResource            `json:"outputResources"`
+	Outputs         map[string]deploymentOutput `json:"outputs"`
+}
+
+// Struct that contains each output of a deployment
+type deploymentOutput struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Struct representing the parameters declared by a template
+type templateDefinition struct {
+	Parameters map[string]templateParameter `json:"parameters"`
+}
+
+// Struct that contains each parameter declared by a template
+type templateParameter struct {
+	Type string `json:"type"`
+}
+
+// Struct representing a deployment parameters file
+type parametersFile struct {
+	Schema         string                     `json:"$schema"`
+	ContentVersion string                     `json:"contentVersion"`
+	Parameters     map[string]json.RawMessage `json:"parameters"`
+}
+
+// Struct that contains each parameter value of a parameters file
+type parameterValue struct {
+	Value interface{} `json:"value"`
 }
 
 // Struct that contains each resource created by a template
 type outputResource struct {
 	ID string `json:"id"`
 }
+
+// Struct representing the result of a what-if operation
+type whatIfResult struct {
+	Status  string         `json:"status"`
+	Changes []whatIfChange `json:"changes"`
+}
+
+// Struct that contains each resource change predicted by a what-if operation
+type whatIfChange struct {
+	ResourceID string `json:"resourceId"`
+	ChangeType string `json:"changeType"`
+}
@@ -0,0 +1,207 @@
+package plugins_arm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+)
+
+const parametersSchema = "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#"
+
+var invalidParameterChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// GetTemplateParams returns the runiac parameters and upstream outputs that can be passed to a template.
+// Keys are valid parameter names, e.g. the vnet_id output of the network step becomes network_vnet_id.
+func GetTemplateParams(exec config.StepExecution) map[string]string {
+	params := map[string]string{}
+	for k, v := range exec.OptionalStepParams {
+		params[invalidParameterChars.ReplaceAllString(k, "_")] = v
+	}
+
+	coreAccountIDs := map[string]string{}
+	for k, v := range exec.CoreAccounts {
+		coreAccountIDs[k] = v.ID
+	}
+
+	if len(coreAccountIDs) > 0 {
+		ids, _ := json.Marshal(coreAccountIDs)
+		params["runiac_core_account_ids_map"] = string(ids)
+	}
+
+	params["runiac_account_id"] = exec.AccountID
+	params["runiac_region"] = exec.Region
+	params["runiac_app_version"] = exec.AppVersion
+	params["runiac_namespace"] = exec.Namespace
+	params["runiac_environment"] = exec.Environment
+
+	return params
+}
+
+// WriteParametersFile writes a parameters file for the parameters the template declares that runiac has values for,
+// overridden by the step's parameter files, see GetParameterFiles. Both paths are relative to the step directory.
+// When there are no parameters, no file is written and "" is returned.
+func WriteParametersFile(exec config.StepExecution, templateFile string, parametersFilePath string) (string, error) {
+	data, err := afero.ReadFile(exec.Fs, filepath.Join(exec.Dir, templateFile))
+	if err != nil {
+		return "", err
+	}
+
+	template := templateDefinition{}
+	err = json.Unmarshal(data, &template)
+	if err != nil {
+		return "", fmt.Errorf("unable to read parameters of template %s: %w", templateFile, err)
+	}
+
+	params := map[string]string{}
+	for k, v := range GetTemplateParams(exec) {
+		params[strings.ToLower(k)] = v
+	}
+
+	file := parametersFile{
+		Schema:         parametersSchema,
+		ContentVersion: "1.0.0.0",
+		Parameters:     map[string]json.RawMessage{},
+	}
+
+	// parameter names are case insensitive, and passing a parameter the template does not declare fails the deployment
+	for name, declaration := range template.Parameters {
+		value, ok := params[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+
+		typedValue, err := toParameterType(declaration.Type, value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s is not a valid %s: %w", name, declaration.Type, err)
+		}
+
+		file.Parameters[name], err = json.Marshal(parameterValue{Value: typedValue})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, parameterFile := range GetParameterFiles(exec) {
+		err = mergeParameterFile(exec.Fs, filepath.Join(exec.Dir, parameterFile), file.Parameters)
+		if err != nil {
+			return "", fmt.Errorf("unable to read parameter file %s: %w", parameterFile, err)
+		}
+
+		exec.Logger.Infof("Using parameters of %s", parameterFile)
+	}
+
+	if len(file.Parameters) == 0 {
+		return "", nil
+	}
+
+	data, err = json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return parametersFilePath, afero.WriteFile(exec.Fs, filepath.Join(exec.Dir, parametersFilePath), data, 0644)
+}
+
+// GetParameterFiles returns the parameter files within the step directory, in the order they are applied, mirroring the
+// terraform override convention: parameters.json, environment_<environment>_parameters.json and ring_<ring>_parameters.json
+func GetParameterFiles(exec config.StepExecution) []string {
+	candidates := []string{
+		"parameters.json",
+		fmt.Sprintf("environment_%s_parameters.json", strings.ToLower(exec.Environment)),
+		fmt.Sprintf("ring_%s_parameters.json", strings.ToLower(exec.DeploymentRing)),
+	}
+
+	files := []string{}
+	for _, file := range candidates {
+		if exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, file)); exists {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// mergeParameterFile sets the parameters of a parameters file, keeping references, e.g. to key vault secrets, as they are
+func mergeParameterFile(fs afero.Fs, path string, parameters map[string]json.RawMessage) error {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return err
+	}
+
+	file := parametersFile{}
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range file.Parameters {
+		// parameter names are case insensitive, the last file to set a parameter wins
+		for existing := range parameters {
+			if strings.EqualFold(existing, name) {
+				delete(parameters, existing)
+			}
+		}
+
+		parameters[name] = value
+	}
+
+	return nil
+}
+
+// toParameterType converts a runiac parameter, which is always a string, to the type the template declares
+func toParameterType(parameterType string, value string) (interface{}, error) {
+	switch strings.ToLower(parameterType) {
+	case "int":
+		return strconv.Atoi(value)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "object", "secureobject", "array":
+		var v interface{}
+		err := json.Unmarshal([]byte(value), &v)
+		return v, err
+	default:
+		return value, nil
+	}
+}
+
+// GetDeploymentOutputs reads the outputs of the step's last deployment, preserving their types
+func GetDeploymentOutputs(exec config.StepExecution) (map[string]interface{}, error) {
+	options, err := getCommonOptions(exec)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := getScope(exec)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := azureCLI.DeploymentShow(options, scope, createDeploymentName(exec))
+	if err != nil {
+		return nil, err
+	}
+
+	return readDeploymentOutputs(resp)
+}
+
+// readDeploymentOutputs reads properties.outputs of a deployment, as returned by create and show
+func readDeploymentOutputs(resp string) (map[string]interface{}, error) {
+	metadata := deployment{}
+	err := json.Unmarshal([]byte(resp), &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read deployment outputs: %w", err)
+	}
+
+	outputs := map[string]interface{}{}
+	for name, output := range metadata.Properties.Outputs {
+		outputs[name] = output.Value
+	}
+
+	return outputs, nil
+}
@@ -1,22 +1,53 @@
 package arm
 
 type AzureRM interface {
+	DeploymentCreate(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error)
+	DeploymentDelete(options *Options, scope Scope, deploymentName string) (out string, err error)
+	DeploymentShow(options *Options, scope Scope, deploymentName string) (out string, err error)
+	DeploymentWhatIf(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error)
+	DeploymentWhatIfJSON(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error)
+	GroupResourceList(options *Options, resourceGroup string, accountID string) (out string, err error)
 	ResourceDelete(options *Options, ids []string) (out string, err error)
-	SubCreate(options *Options, deploymentName string, accountID string, location string, file string) (out string, err error)
+	SubCreate(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error)
 	SubDelete(options *Options, deploymentName string, accountID string) (out string, err error)
 	SubShow(options *Options, deploymentName string, accountID string) (out string, err error)
-	SubWhatIf(options *Options, deploymentName string, accountID string, location string, file string) (out string, err error)
+	SubWhatIf(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error)
+	SubWhatIfJSON(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error)
 	Version(options *Options) (out string, err error)
 }
 
 type AzureCLI struct{}
 
+func (a AzureCLI) DeploymentCreate(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error) {
+	return DeploymentCreate(options, scope, deploymentName, location, file, parametersFile)
+}
+
+func (a AzureCLI) DeploymentDelete(options *Options, scope Scope, deploymentName string) (out string, err error) {
+	return DeploymentDelete(options, scope, deploymentName)
+}
+
+func (a AzureCLI) DeploymentShow(options *Options, scope Scope, deploymentName string) (out string, err error) {
+	return DeploymentShow(options, scope, deploymentName)
+}
+
+func (a AzureCLI) DeploymentWhatIf(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error) {
+	return DeploymentWhatIf(options, scope, deploymentName, location, file, parametersFile)
+}
+
+func (a AzureCLI) DeploymentWhatIfJSON(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error) {
+	return DeploymentWhatIfJSON(options, scope, deploymentName, location, file, parametersFile)
+}
+
+func (a AzureCLI) GroupResourceList(options *Options, resourceGroup string, accountID string) (out string, err error) {
+	return GroupResourceList(options, resourceGroup, accountID)
+}
+
 func (a AzureCLI) ResourceDelete(options *Options, ids []string) (out string, err error) {
 	return ResourceDelete(options, ids)
 }
 
-func (a AzureCLI) SubCreate(options *Options, deploymentName string, accountID string, location string, file string) (out string, err error) {
-	return SubCreate(options, deploymentName, accountID, location, file)
+func (a AzureCLI) SubCreate(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error) {
+	return SubCreate(options, deploymentName, accountID, location, file, parametersFile)
 }
 
 func (a AzureCLI) SubShow(options *Options, deploymentName string, accountID string) (out string, err error) {
@@ -27,8 +58,12 @@ func (a AzureCLI) SubDelete(options *Options, deploymentName string, accountID s
 	return SubDelete(options, deploymentName, accountID)
 }
 
-func (a AzureCLI) SubWhatIf(options *Options, deploymentName string, accountID string, location string, file string) (out string, err error) {
-	return SubWhatIf(options, deploymentName, accountID, location, file)
+func (a AzureCLI) SubWhatIf(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error) {
+	return SubWhatIf(options, deploymentName, accountID, location, file, parametersFile)
+}
+
+func (a AzureCLI) SubWhatIfJSON(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error) {
+	return SubWhatIfJSON(options, deploymentName, accountID, location, file, parametersFile)
 }
 
 func (a AzureCLI) Version(options *Options) (out string, err error) {
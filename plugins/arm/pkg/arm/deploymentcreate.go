@@ -0,0 +1,20 @@
+package arm
+
+import "fmt"
+
+func DeploymentCreate(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error) {
+	args := scope.templateArgs("create", deploymentName, location, file, parametersFile)
+
+	args = append(args, "--output", "json")
+
+	return RunAzureCLICommand(true, options, args...)
+}
+
+// appendParametersFile passes the parameters file to a deployment command, when there is one
+func appendParametersFile(args []string, parametersFile string) []string {
+	if parametersFile == "" {
+		return args
+	}
+
+	return append(args, "--parameters", fmt.Sprintf("@%s", parametersFile))
+}
@@ -0,0 +1,7 @@
+package arm
+
+func DeploymentDelete(options *Options, scope Scope, deploymentName string) (out string, err error) {
+	args := scope.deploymentArgs("delete", deploymentName)
+
+	return RunAzureCLICommand(true, options, args...)
+}
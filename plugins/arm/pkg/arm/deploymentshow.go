@@ -0,0 +1,9 @@
+package arm
+
+func DeploymentShow(options *Options, scope Scope, deploymentName string) (out string, err error) {
+	args := scope.deploymentArgs("show", deploymentName)
+
+	args = append(args, "--output", "json")
+
+	return RunAzureCLICommand(true, options, args...)
+}
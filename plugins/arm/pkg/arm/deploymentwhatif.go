@@ -0,0 +1,16 @@
+package arm
+
+func DeploymentWhatIf(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error) {
+	args := scope.templateArgs("what-if", deploymentName, location, file, parametersFile)
+
+	return RunAzureCLICommand(true, options, args...)
+}
+
+// DeploymentWhatIfJSON runs a what-if and returns the machine readable list of changes
+func DeploymentWhatIfJSON(options *Options, scope Scope, deploymentName string, location string, file string, parametersFile string) (out string, err error) {
+	args := scope.templateArgs("what-if", deploymentName, location, file, parametersFile)
+
+	args = append(args, "--result-format", "FullResourcePayloads", "--no-pretty-print")
+
+	return RunAzureCLICommand(false, options, args...)
+}
@@ -0,0 +1,19 @@
+package arm
+
+// GroupResourceList returns a JSON list of the IDs of every resource within the resource group
+func GroupResourceList(options *Options, resourceGroup string, accountID string) (out string, err error) {
+	args := []string{
+		"resource",
+		"list",
+		"--resource-group",
+		resourceGroup,
+		"--subscription",
+		accountID,
+		"--query",
+		"[].id",
+		"--output",
+		"json",
+	}
+
+	return RunAzureCLICommand(false, options, args...)
+}
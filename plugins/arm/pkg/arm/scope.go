@@ -0,0 +1,53 @@
+package arm
+
+// Deployment scopes, named after the az deployment subcommands
+const (
+	ResourceGroupScope   = "group"
+	SubscriptionScope    = "sub"
+	ManagementGroupScope = "mg"
+	TenantScope          = "tenant"
+)
+
+// Scope identifies where a deployment is created
+type Scope struct {
+	Type              string
+	SubscriptionID    string
+	ResourceGroup     string
+	ManagementGroupID string
+}
+
+// deploymentArgs returns the arguments of an az deployment command targeting the scope
+func (s Scope) deploymentArgs(command string, deploymentName string) []string {
+	args := []string{
+		"deployment",
+		s.Type,
+		command,
+		"--name",
+		deploymentName,
+	}
+
+	switch s.Type {
+	case ResourceGroupScope:
+		args = append(args, "--resource-group", s.ResourceGroup, "--subscription", s.SubscriptionID)
+	case ManagementGroupScope:
+		args = append(args, "--management-group-id", s.ManagementGroupID)
+	case SubscriptionScope:
+		args = append(args, "--subscription", s.SubscriptionID)
+	}
+
+	return args
+}
+
+// templateArgs returns the arguments of an az deployment command deploying a template to the scope.
+// Resource group deployments are created in the location of the group.
+func (s Scope) templateArgs(command string, deploymentName string, location string, file string, parametersFile string) []string {
+	args := s.deploymentArgs(command, deploymentName)
+
+	if s.Type != ResourceGroupScope {
+		args = append(args, "--location", location)
+	}
+
+	args = append(args, "--template-file", file)
+
+	return appendParametersFile(args, parametersFile)
+}
@@ -0,0 +1,37 @@
+package arm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateArgs_ShouldTargetScope(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		scope    Scope
+		expected []string
+	}{
+		"resource group": {
+			scope:    Scope{Type: ResourceGroupScope, SubscriptionID: "sub", ResourceGroup: "rg"},
+			expected: []string{"deployment", "group", "create", "--name", "runiac", "--resource-group", "rg", "--subscription", "sub", "--template-file", "main.json", "--parameters", "@parameters.json"},
+		},
+		"subscription": {
+			scope:    Scope{Type: SubscriptionScope, SubscriptionID: "sub"},
+			expected: []string{"deployment", "sub", "create", "--name", "runiac", "--subscription", "sub", "--location", "centralus", "--template-file", "main.json", "--parameters", "@parameters.json"},
+		},
+		"management group": {
+			scope:    Scope{Type: ManagementGroupScope, SubscriptionID: "sub", ManagementGroupID: "mg"},
+			expected: []string{"deployment", "mg", "create", "--name", "runiac", "--management-group-id", "mg", "--location", "centralus", "--template-file", "main.json", "--parameters", "@parameters.json"},
+		},
+		"tenant": {
+			scope:    Scope{Type: TenantScope, SubscriptionID: "sub"},
+			expected: []string{"deployment", "tenant", "create", "--name", "runiac", "--location", "centralus", "--template-file", "main.json", "--parameters", "@parameters.json"},
+		},
+	}
+
+	for name, test := range tests {
+		require.Equal(t, test.expected, test.scope.templateArgs("create", "runiac", "centralus", "main.json", "parameters.json"), name)
+	}
+}
@@ -1,15 +1,5 @@
 package arm
 
 func SubDelete(options *Options, deploymentName string, accountID string) (out string, err error) {
-	args := []string{
-		"deployment",
-		"sub",
-		"delete",
-		"--name",
-		deploymentName,
-		"--subscription",
-		accountID,
-	}
-
-	return RunAzureCLICommand(true, options, args...)
+	return DeploymentDelete(options, Scope{Type: SubscriptionScope, SubscriptionID: accountID}, deploymentName)
 }
@@ -1,15 +1,5 @@
 package arm
 
 func SubShow(options *Options, deploymentName string, accountID string) (out string, err error) {
-	args := []string{
-		"deployment",
-		"sub",
-		"show",
-		"--name",
-		deploymentName,
-		"--subscription",
-		accountID,
-	}
-
-	return RunAzureCLICommand(true, options, args...)
+	return DeploymentShow(options, Scope{Type: SubscriptionScope, SubscriptionID: accountID}, deploymentName)
 }
@@ -1,19 +1,10 @@
 package arm
 
-func SubWhatIf(options *Options, deploymentName string, accountID string, location string, file string) (out string, err error) {
-	args := []string{
-		"deployment",
-		"sub",
-		"what-if",
-		"--name",
-		deploymentName,
-		"--location",
-		location,
-		"--template-file",
-		file,
-		"--subscription",
-		accountID,
-	}
+func SubWhatIf(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error) {
+	return DeploymentWhatIf(options, Scope{Type: SubscriptionScope, SubscriptionID: accountID}, deploymentName, location, file, parametersFile)
+}
 
-	return RunAzureCLICommand(true, options, args...)
+// SubWhatIfJSON runs a what-if and returns the machine readable list of changes
+func SubWhatIfJSON(options *Options, deploymentName string, accountID string, location string, file string, parametersFile string) (out string, err error) {
+	return DeploymentWhatIfJSON(options, Scope{Type: SubscriptionScope, SubscriptionID: accountID}, deploymentName, location, file, parametersFile)
 }
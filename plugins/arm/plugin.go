@@ -1,10 +1,32 @@
 package plugins_arm
 
 import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
 	"github.com/optum/runiac/plugins/arm/pkg/arm"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
+// RunnerName is the runner configuration value that selects the arm runner
+const RunnerName = "arm"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return ArmStepper{} },
+		Plugin:     ArmPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains an ARM template
+func Detect(fs afero.Fs, dir string) bool {
+	exists, err := afero.Exists(fs, filepath.Join(dir, "main.json"))
+	return err == nil && exists
+}
+
 type ArmPlugin struct{}
 
 func (info ArmPlugin) Initialize(logger *logrus.Entry) {
@@ -0,0 +1,124 @@
+package plugins_arm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/arm/pkg/arm"
+	"github.com/spf13/afero"
+)
+
+// Deployment scopes of the arm section of a step's runiac.yml
+const (
+	ResourceGroupScope   = "resource_group"
+	SubscriptionScope    = "subscription"
+	ManagementGroupScope = "management_group"
+	TenantScope          = "tenant"
+)
+
+var scopeTypes = map[string]string{
+	ResourceGroupScope:   arm.ResourceGroupScope,
+	SubscriptionScope:    arm.SubscriptionScope,
+	ManagementGroupScope: arm.ManagementGroupScope,
+	TenantScope:          arm.TenantScope,
+}
+
+// schemaScopes maps the schema a template declares to the scope it is deployed to
+var schemaScopes = map[string]string{
+	"deploymenttemplate.json":                ResourceGroupScope,
+	"subscriptiondeploymenttemplate.json":    SubscriptionScope,
+	"managementgroupdeploymenttemplate.json": ManagementGroupScope,
+	"tenantdeploymenttemplate.json":          TenantScope,
+}
+
+// StepConfig is the arm section of a step's runiac.yml
+type StepConfig struct {
+	Scope           string `mapstructure:"scope"`
+	ResourceGroup   string `mapstructure:"resource_group"`
+	ManagementGroup string `mapstructure:"management_group"`
+}
+
+// ReadStepConfig reads the arm section of the step's runiac.yml. Steps without a scope are deployed to the scope their
+// template's schema declares, or to the subscription.
+func ReadStepConfig(exec config.StepExecution) (StepConfig, error) {
+	stepConfig := StepConfig{}
+
+	v, err := config.ReadStepConfig(exec.Fs, exec.Dir)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	err = v.UnmarshalKey("arm", &stepConfig)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	if stepConfig.Scope == "" {
+		stepConfig.Scope = detectScope(exec)
+	}
+
+	switch stepConfig.Scope {
+	case ResourceGroupScope:
+		if stepConfig.ResourceGroup == "" {
+			return stepConfig, fmt.Errorf("arm.resource_group is required to deploy to the %s scope", stepConfig.Scope)
+		}
+	case ManagementGroupScope:
+		if stepConfig.ManagementGroup == "" {
+			return stepConfig, fmt.Errorf("arm.management_group is required to deploy to the %s scope", stepConfig.Scope)
+		}
+	case SubscriptionScope, TenantScope:
+	default:
+		return stepConfig, fmt.Errorf("arm.scope %s is not one of %s, %s, %s or %s", stepConfig.Scope, ResourceGroupScope, SubscriptionScope, ManagementGroupScope, TenantScope)
+	}
+
+	return stepConfig, nil
+}
+
+// detectScope returns the scope declared by the schema of the step's template, defaulting to the subscription
+func detectScope(exec config.StepExecution) string {
+	// the template generated for the execution, e.g. compiled from bicep, followed by the step's template
+	for _, templateFile := range []string{filepath.Join(TempDir(exec), mainTemplateFile), mainTemplateFile} {
+		data, err := afero.ReadFile(exec.Fs, filepath.Join(exec.Dir, templateFile))
+		if err != nil {
+			continue
+		}
+
+		template := struct {
+			Schema string `json:"$schema"`
+		}{}
+
+		if err := json.Unmarshal(data, &template); err != nil {
+			continue
+		}
+
+		schema := strings.ToLower(strings.TrimSuffix(template.Schema, "#"))
+		if scope, ok := schemaScopes[schema[strings.LastIndex(schema, "/")+1:]]; ok {
+			return scope
+		}
+	}
+
+	return SubscriptionScope
+}
+
+// deploymentScope returns the scope the step is deployed to
+func (stepConfig StepConfig) deploymentScope(exec config.StepExecution) arm.Scope {
+	return arm.Scope{
+		Type:              scopeTypes[stepConfig.Scope],
+		SubscriptionID:    exec.AccountID,
+		ResourceGroup:     stepConfig.ResourceGroup,
+		ManagementGroupID: stepConfig.ManagementGroup,
+	}
+}
+
+// getScope reads the scope the step is deployed to
+func getScope(exec config.StepExecution) (arm.Scope, error) {
+	stepConfig, err := ReadStepConfig(exec)
+	if err != nil {
+		return arm.Scope{}, err
+	}
+
+	return stepConfig.deploymentScope(exec), nil
+}
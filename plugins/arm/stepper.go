@@ -2,22 +2,31 @@ package plugins_arm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/pkg/steps"
 	"github.com/optum/runiac/plugins/arm/pkg/arm"
-	"github.com/spf13/afero"
 )
 
 type ArmStepper struct{}
 
-var azureCLI arm.AzureCLI = arm.AzureCLI{}
+var azureCLI arm.AzureRM = arm.AzureCLI{}
+
+var runTests = shell.RunShellCommandAndGetAndStreamOutput
+
+var errNotDeployed = errors.New("step is not deployed")
 
 func (stepper ArmStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
 	return exec, nil
 }
 
-// ExecuteStepDestroy destroys a step
+// ExecuteStepDestroy destroys the resources of the step's last deployment, or every resource within the resource
+// group of resource group scoped steps, in dependency order, followed by the deployment itself
 func (stepper ArmStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
 	output.RegionDeployType = exec.RegionDeployType
 	output.Region = exec.Region
@@ -32,37 +41,62 @@ func (stepper ArmStepper) ExecuteStepDestroy(exec config.StepExecution) (output
 		return
 	}
 
-	// find the metadata associated with the last deployment
-	resp, err := azureCLI.SubShow(options, deploymentName, exec.AccountID)
-	if err != nil {
-		options.Logger.WithError(output.Err).Error("Failed to plan template deployment")
+	var scope arm.Scope
+	scope, output.Err = getScope(exec)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Unable to read arm step configuration")
 		return
 	}
 
-	// unmarshal the output into a struct
-	metadata := deployment{}
-	err = json.Unmarshal([]byte(resp), &metadata)
-	if err != nil {
-		options.Logger.WithError(output.Err).Error("Failed to read last template deployment")
+	var ids []string
+	if scope.Type == arm.ResourceGroupScope {
+		ids, output.Err = getGroupResourceIDs(options, scope)
+	} else {
+		ids, output.Err = getDeploymentResourceIDs(options, scope, deploymentName)
+	}
+
+	if errors.Is(output.Err, errNotDeployed) {
+		options.Logger.Warn("Step is not deployed, nothing to destroy")
+		output.Err = nil
+		output.Status = config.Na
+		return
+	} else if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Failed to read resources to destroy")
 		return
 	}
 
-	// gather the list of resource ids affected by that deployment
-	ids := []string{}
-	for _, resource := range metadata.Properties.OutputResources {
-		ids = append(ids, resource.ID)
+	if exec.DryRun {
+		order, _ := getDeletionOrder(ids, getTemplateDependencies(readDeployedTemplate(exec)))
+		options.Logger.Infof("---------- Skipping delete of %d resources, this is a dry run ---------- ", len(ids))
+		output.PlannedChanges = map[string][]string{}
+		if len(order) > 0 {
+			output.PlannedChanges["[delete]"] = order
+		}
+		output.Status = config.Success
+		return
 	}
 
-	// delete all created resources
-	_, err = azureCLI.ResourceDelete(options, ids)
-	if err != nil {
+	results := deleteResources(exec, options, ids, readDeployedTemplate(exec))
+
+	lines := []string{}
+	for _, r := range results {
+		lines = append(lines, fmt.Sprintf("%s: %s", r.ID, r.Result))
+	}
+	output.StreamOutput = strings.Join(lines, "\n")
+
+	// keep the deployment metadata until every resource is deleted, so destroy can be run again
+	output.Err = getDeleteResultsError(results)
+	if output.Err != nil {
 		options.Logger.WithError(output.Err).Error("Failed to delete resources")
 		return
 	}
 
 	// delete deployment metadata
-	_, err = azureCLI.SubDelete(options, deploymentName, exec.AccountID)
-	if err != nil {
+	var resp string
+	resp, output.Err = azureCLI.DeploymentDelete(options, scope, deploymentName)
+	if isNotFound(resp, output.Err) {
+		output.Err = nil
+	} else if output.Err != nil {
 		options.Logger.WithError(output.Err).Error("Failed to delete deployment metadata")
 		return
 	}
@@ -71,12 +105,93 @@ func (stepper ArmStepper) ExecuteStepDestroy(exec config.StepExecution) (output
 	return
 }
 
+// getDeploymentResourceIDs returns the IDs of the resources created by the last deployment
+func getDeploymentResourceIDs(options *arm.Options, scope arm.Scope, deploymentName string) ([]string, error) {
+	resp, err := azureCLI.DeploymentShow(options, scope, deploymentName)
+	if isNotFound(resp, err) {
+		return nil, errNotDeployed
+	} else if err != nil {
+		return nil, err
+	}
+
+	metadata := deployment{}
+	err = json.Unmarshal([]byte(resp), &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read last template deployment: %w", err)
+	}
+
+	ids := []string{}
+	for _, resource := range metadata.Properties.OutputResources {
+		ids = append(ids, resource.ID)
+	}
+
+	return ids, nil
+}
+
+// getGroupResourceIDs returns the IDs of every resource within the resource group, including those of earlier deployments
+func getGroupResourceIDs(options *arm.Options, scope arm.Scope) ([]string, error) {
+	resp, err := azureCLI.GroupResourceList(options, scope.ResourceGroup, scope.SubscriptionID)
+	if isNotFound(resp, err) {
+		return nil, errNotDeployed
+	} else if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	err = json.Unmarshal([]byte(resp), &ids)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read resources of resource group %s: %w", scope.ResourceGroup, err)
+	}
+
+	return ids, nil
+}
+
 // ExecuteStep deploys a step
-func (stepper ArmStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+func (stepper ArmStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	templateFile, err := parseMainTemplate(exec)
+	if err != nil {
+		exec.Logger.WithError(err).Error("Unable to parse template for step execution")
+
+		return config.StepOutput{
+			RegionDeployType: exec.RegionDeployType,
+			Region:           exec.Region,
+			StepName:         exec.StepName,
+			Status:           config.Fail,
+			Err:              err,
+		}
+	}
+
+	parametersFile, err := WriteParametersFile(exec, templateFile, filepath.Join(TempDir(exec), "parameters.json"))
+	if err != nil {
+		exec.Logger.WithError(err).Error("Unable to write parameters for step execution")
+
+		return config.StepOutput{
+			RegionDeployType: exec.RegionDeployType,
+			Region:           exec.Region,
+			StepName:         exec.StepName,
+			Status:           config.Fail,
+			Err:              err,
+		}
+	}
+
+	return DeployTemplate(exec, templateFile, parametersFile)
+}
+
+// DeployTemplate deploys a template, relative to the step directory, with an optional parameters file,
+// and returns the deployment's outputs. It is shared by the runners that produce ARM templates.
+func DeployTemplate(exec config.StepExecution, templateFile string, parametersFile string) (output config.StepOutput) {
 	output.RegionDeployType = exec.RegionDeployType
 	output.Region = exec.Region
 	output.StepName = exec.StepName
 	output.Status = config.Fail
+
+	// deployments do not hold locks that outlive an interrupted execution
+	if exec.Action == config.UnlockAction {
+		exec.Logger.Warn("State locks are not managed by the arm runner")
+		output.Status = config.Na
+		return
+	}
+
 	var options *arm.Options
 	deploymentName := createDeploymentName(exec)
 
@@ -86,131 +201,209 @@ func (stepper ArmStepper) ExecuteStep(exec config.StepExecution) (output config.
 		return
 	}
 
-	mainTemplateFile, err := parseMainTemplate(exec)
-	if err != nil {
-		options.Logger.WithError(output.Err).Error("Unable to parse template for step execution")
+	var scope arm.Scope
+	scope, output.Err = getScope(exec)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Unable to read arm step configuration")
 		return
 	}
 
-	_, err = azureCLI.SubWhatIf(options, deploymentName, exec.AccountID, exec.Region, mainTemplateFile)
-	if err != nil {
+	// what-if results cannot be applied, so ARM steps have nothing to save to or apply from a plan bundle
+	if exec.Action == config.ApplyAction {
+		output.Err = errors.New("plan bundles are not supported by the arm runner")
+		options.Logger.WithError(output.Err).Error("Unable to apply plan bundle")
+		return
+	} else if exec.Action == config.PlanAction {
+		options.Logger.Warn("Plan bundles are not supported by the arm runner, the what-if will not be saved")
+	}
+
+	if exec.Action == config.DriftAction {
+		return executeDrift(exec, options, scope, output, deploymentName, templateFile, parametersFile)
+	}
+
+	var resp string
+	resp, output.Err = azureCLI.DeploymentWhatIfJSON(options, scope, deploymentName, exec.Region, templateFile, parametersFile)
+	if output.Err != nil {
 		options.Logger.WithError(output.Err).Error("Failed to plan template deployment")
 		return
 	}
 
+	var summary ChangeSummary
+	summary, output.Err = ReadChangeSummary(resp)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Failed to read template deployment what-if")
+		return
+	}
+
+	options.Logger.Infof("What-if: %s", summary)
+	output.PlannedChanges = summary.PlannedChanges()
+	for action, ids := range output.PlannedChanges {
+		options.Logger.Infof("%s: %s", action, strings.Join(ids, ", "))
+	}
+
 	if exec.DryRun {
 		options.Logger.Info("---------- Skipping create, this is a dry run ---------- ")
 	} else {
-		_, err = azureCLI.SubCreate(options, deploymentName, exec.AccountID, exec.Region, mainTemplateFile)
-		if err != nil {
+		resp, output.Err = azureCLI.DeploymentCreate(options, scope, deploymentName, exec.Region, templateFile, parametersFile)
+		if output.Err != nil {
 			options.Logger.WithError(output.Err).Error("Failed to deploy template")
 			return
 		}
+
+		output.OutputVariables, output.Err = readDeploymentOutputs(resp)
+		if output.Err != nil {
+			// the create response can be interleaved with warnings, the stored deployment is authoritative
+			options.Logger.WithError(output.Err).Warn("Unable to read outputs from template deployment, reading the stored deployment")
+			output.OutputVariables, output.Err = GetDeploymentOutputs(exec)
+		}
+
+		if output.Err != nil {
+			options.Logger.WithError(output.Err).Error("Unable to read deployment outputs")
+			return
+		}
 	}
 
 	output.Status = config.Success
 	return
 }
 
-// ExecuteStepTests executes the tests for a step
-func (stepper ArmStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
-	// TODO
-	return
+// executeDrift runs a what-if against the last deployment and reports the resources that drifted without deploying
+func executeDrift(exec config.StepExecution, options *arm.Options, scope arm.Scope, output config.StepOutput, deploymentName string, templateFile string, parametersFile string) config.StepOutput {
+	resp, err := azureCLI.DeploymentWhatIfJSON(options, scope, deploymentName, exec.Region, templateFile, parametersFile)
+	if err != nil {
+		output.Err = err
+		options.Logger.WithError(err).Error("Failed to plan template deployment")
+		return output
+	}
+
+	result := whatIfResult{}
+	err = json.Unmarshal([]byte(resp), &result)
+	if err != nil {
+		output.Err = err
+		options.Logger.WithError(err).Error("Failed to read template deployment what-if")
+		return output
+	}
+
+	output.Drift = getStepDrift(result)
+
+	for _, r := range output.Drift.Resources {
+		options.Logger.Warn(fmt.Sprintf("Drift detected %s: %s", r.Address, r.Actions))
+	}
+
+	output.Status = config.Success
+	return output
 }
 
-func getCommonOptions(exec config.StepExecution) (options *arm.Options, err error) {
-	options = &arm.Options{
-		AzureCLIBinary: "az",
-		AzureCLIDir:    exec.Dir,
-		EnvVars:        map[string]string{},
-		Logger:         exec.Logger,
+// getStepDrift summarizes the drifted resources of a what-if result
+func getStepDrift(result whatIfResult) *config.StepDrift {
+	drift := &config.StepDrift{}
+
+	for _, c := range result.Changes {
+		var severity config.DriftSeverity
+
+		switch c.ChangeType {
+		case "Modify", "Delete":
+			severity = config.DriftChanged
+		case "Create":
+			// a resource from the last deployment no longer exists
+			severity = config.DriftDeleted
+		default:
+			// NoChange, Ignore, Deploy and Unsupported are not drift
+			continue
+		}
+
+		drift.Add(config.DriftedResource{
+			Address:  c.ResourceID,
+			Actions:  []string{c.ChangeType},
+			Severity: severity,
+		})
 	}
 
-	return
+	return drift
 }
 
-func createDeploymentName(exec config.StepExecution) string {
-	return fmt.Sprintf("runiac-%s-%s-%s-%s", exec.Project, exec.TrackName, exec.StepName, exec.Region)
+// ExecuteStepOutputs reads the outputs of the step's last deployment
+func (stepper ArmStepper) ExecuteStepOutputs(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail
+
+	output.OutputVariables, output.Err = GetDeploymentOutputs(exec)
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Unable to read deployment outputs")
+		return
+	}
+
+	output.Status = config.Success
+	return
 }
 
-func parseMainTemplate(exec config.StepExecution) (string, error) {
-	fs := afero.NewOsFs()
+// ExecuteStepTests executes the tests for a step with the step's parameters and deployment outputs as environment variables
+func (stepper ArmStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	output.StepName = exec.StepName
 
-	err := fs.Mkdir(fmt.Sprintf("%s/.temp", exec.Dir), 0755)
-	if err != nil {
-		exec.Logger.WithError(err).Error(err)
-		return "", err
+	if !steps.GoTestsExist(exec.Fs, exec.Dir) {
+		exec.Logger.Debug("Step has no tests")
+		return
 	}
 
-	data, err := afero.ReadFile(fs, fmt.Sprintf("%s/main.json", exec.Dir))
+	envVars, err := GetTestEnvVars(exec)
 	if err != nil {
-		exec.Logger.WithError(err).Error(err)
-		return "", err
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to read deployment outputs for step tests")
+		return
 	}
 
-	template := make(map[string]interface{})
-	err = json.Unmarshal(data, &template)
-	if err != nil {
-		exec.Logger.Error("Unable to unmarshall template file")
-		exec.Logger.WithError(err).Error(err)
-		return "", err
+	output = steps.ExecuteGoTests(exec, envVars, runTests)
+
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Step tests failed")
 	}
 
-	resources := template["resources"].([]interface{})
-	for _, item := range resources {
-		resource := item.(map[string]interface{})
+	return
+}
 
-		resourceType := resource["type"].(string)
-		if resourceType != "Microsoft.Resources/deployments" {
-			continue
-		}
+// GetTestEnvVars returns the template parameters, e.g. runiac_region, and the outputs of the step's last deployment,
+// prefixed with output_, as environment variables. Outputs that are not strings are JSON encoded.
+func GetTestEnvVars(exec config.StepExecution) (map[string]string, error) {
+	envVars := GetTemplateParams(exec)
 
-		properties := resource["properties"].(map[string]interface{})
-		if properties == nil {
-			continue
-		}
+	outputs, err := GetDeploymentOutputs(exec)
+	if err != nil {
+		return nil, err
+	}
 
-		if properties["_templateLink"] == nil {
+	for name, value := range outputs {
+		key := fmt.Sprintf("output_%s", invalidParameterChars.ReplaceAllString(name, "_"))
+
+		if s, ok := value.(string); ok {
+			envVars[key] = s
 			continue
 		}
 
-		templateLink := properties["_templateLink"].(map[string]interface{})
-		if templateLink != nil {
-			localUri := templateLink["localUri"].(string)
-
-			linkedTemplateData, err := afero.ReadFile(fs, fmt.Sprintf("%s/%s", exec.Dir, localUri))
-			if err != nil {
-				exec.Logger.WithError(err).Error(err)
-				return "", err
-			}
-
-			linkedTemplate := make(map[string]interface{})
-			err = json.Unmarshal(linkedTemplateData, &linkedTemplate)
-			if err != nil {
-				exec.Logger.WithError(err).Error(err)
-				return "", err
-			}
-
-			// replace the _templateLink property with the contents of the template itself
-			delete(properties, "_templateLink")
-			properties["template"] = linkedTemplate
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode output %s: %w", name, err)
 		}
-	}
 
-	result, err := json.Marshal(template)
-	if err != nil {
-		exec.Logger.Error("Failed to marshal interpolated template")
-		exec.Logger.WithError(err).Error(err)
-		return "", err
+		envVars[key] = string(data)
 	}
 
-	filePath := ".temp/main.json"
-	path := fmt.Sprintf("%s/%s", exec.Dir, filePath)
-	err = afero.WriteFile(fs, path, result, 0644)
-	if err != nil {
-		exec.Logger.WithError(err).Error(err)
-		return "", err
+	return envVars, nil
+}
+
+func getCommonOptions(exec config.StepExecution) (options *arm.Options, err error) {
+	options = &arm.Options{
+		AzureCLIBinary: "az",
+		AzureCLIDir:    exec.Dir,
+		EnvVars:        map[string]string{},
+		Logger:         exec.Logger,
 	}
 
-	return filePath, nil
+	return
+}
+
+func createDeploymentName(exec config.StepExecution) string {
+	return fmt.Sprintf("runiac-%s-%s-%s-%s", exec.Project, exec.TrackName, exec.StepName, exec.Region)
 }
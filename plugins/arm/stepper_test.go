@@ -0,0 +1,399 @@
+package plugins_arm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/plugins/arm/pkg/arm"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = logrus.NewEntry(logrus.New())
+
+// stubAzureCLI returns a canned deployment for DeploymentShow and DeploymentCreate, recording the calls it receives
+type stubAzureCLI struct {
+	arm.AzureCLI
+	deployment     string
+	created        string
+	groupResources string
+	whatIf         string
+	deleteFailures map[string][]string
+	calls          *[]string
+	parametersFile *string
+}
+
+func (s stubAzureCLI) record(call string) {
+	if s.calls != nil {
+		*s.calls = append(*s.calls, call)
+	}
+}
+
+func (s stubAzureCLI) DeploymentShow(options *arm.Options, scope arm.Scope, deploymentName string) (string, error) {
+	s.record(fmt.Sprintf("show %s", scope.Type))
+	if s.deployment == "" {
+		return "ERROR: (DeploymentNotFound) Deployment 'runiac' could not be found.", errors.New("exit status 3")
+	}
+	return s.deployment, nil
+}
+
+func (s stubAzureCLI) DeploymentWhatIfJSON(options *arm.Options, scope arm.Scope, deploymentName string, location string, file string, parametersFile string) (string, error) {
+	s.record(fmt.Sprintf("what-if %s", scope.Type))
+	if s.whatIf == "" {
+		return `{"status": "Succeeded", "changes": []}`, nil
+	}
+	return s.whatIf, nil
+}
+
+func (s stubAzureCLI) DeploymentCreate(options *arm.Options, scope arm.Scope, deploymentName string, location string, file string, parametersFile string) (string, error) {
+	s.record(fmt.Sprintf("create %s", scope.Type))
+	if s.parametersFile != nil {
+		*s.parametersFile = parametersFile
+	}
+	return s.created, nil
+}
+
+func (s stubAzureCLI) DeploymentDelete(options *arm.Options, scope arm.Scope, deploymentName string) (string, error) {
+	s.record(fmt.Sprintf("delete %s %s", scope.Type, deploymentName))
+	return "", nil
+}
+
+func (s stubAzureCLI) GroupResourceList(options *arm.Options, resourceGroup string, accountID string) (string, error) {
+	s.record(fmt.Sprintf("list %s", resourceGroup))
+	return s.groupResources, nil
+}
+
+func (s stubAzureCLI) ResourceDelete(options *arm.Options, ids []string) (string, error) {
+	s.record(fmt.Sprintf("delete %s", strings.Join(ids, " ")))
+
+	// each attempt to delete a resource fails with the next of its failures, until there are none left
+	id := strings.Join(ids, " ")
+	if failures := s.deleteFailures[id]; len(failures) > 0 {
+		s.deleteFailures[id] = failures[1:]
+		return failures[0], errors.New("exit status 1")
+	}
+
+	return "", nil
+}
+
+func stubExecution(fs afero.Fs) config.StepExecution {
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               fs,
+		Context:          context.Background(),
+		Dir:              "/steps/network",
+		Project:          "runiac",
+		TrackName:        "core",
+		StepName:         "network",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		OptionalStepParams: map[string]string{
+			"dns-zone_id": "zone",
+		},
+	}
+}
+
+func TestExecuteStepTests_ShouldPassParamsAndOutputsAsEnvVars(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/tests/tests.test", []byte{}, 0755))
+
+	commands := []shell.Command{}
+	runTests = func(cmd shell.Command) (string, error) {
+		commands = append(commands, cmd)
+		return "PASS", nil
+	}
+	azureCLI = stubAzureCLI{deployment: `{"properties": {"outputs": {
+		"vnetId": {"type": "String", "value": "vnet"},
+		"subnetCount": {"type": "Int", "value": 2},
+		"tags": {"type": "Object", "value": {"team": "runiac"}}
+	}}}`}
+	defer func() {
+		runTests = shell.RunShellCommandAndGetAndStreamOutput
+		azureCLI = arm.AzureCLI{}
+	}()
+
+	output := ArmStepper{}.ExecuteStepTests(stubExecution(fs))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, "PASS", output.StreamOutput)
+	require.Len(t, commands, 1)
+	require.Equal(t, "gotestsum", commands[0].Command)
+	require.Equal(t, filepath.Join("/steps/network", "tests"), commands[0].WorkingDir)
+	require.Contains(t, commands[0].Args, "/output/junit/runiac-core-network-primary-centralus.xml")
+	require.Contains(t, commands[0].Args, "./tests.test")
+
+	env := commands[0].Env
+	require.Equal(t, "centralus", env["runiac_region"])
+	require.Equal(t, "zone", env["dns_zone_id"])
+	require.Equal(t, "vnet", env["output_vnetId"])
+	require.Equal(t, "2", env["output_subnetCount"])
+	require.Equal(t, `{"team":"runiac"}`, env["output_tags"])
+}
+
+func TestExecuteStepTests_ShouldReturnTestFailure(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/tests/network_test.go", []byte{}, 0644))
+
+	runs := 0
+	runTests = func(cmd shell.Command) (string, error) {
+		runs++
+		return "FAIL", errors.New("exit status 1")
+	}
+	azureCLI = stubAzureCLI{deployment: `{"properties": {}}`}
+	defer func() {
+		runTests = shell.RunShellCommandAndGetAndStreamOutput
+		azureCLI = arm.AzureCLI{}
+	}()
+
+	output := ArmStepper{}.ExecuteStepTests(stubExecution(fs))
+
+	require.Error(t, output.Err)
+	require.Equal(t, "FAIL", output.StreamOutput)
+	require.Equal(t, 1, runs)
+}
+
+func TestExecuteStepTests_ShouldDoNothingWithoutTests(t *testing.T) {
+	runTests = func(cmd shell.Command) (string, error) {
+		t.Fatal("tests should not run")
+		return "", nil
+	}
+	defer func() { runTests = shell.RunShellCommandAndGetAndStreamOutput }()
+
+	output := ArmStepper{}.ExecuteStepTests(stubExecution(afero.NewMemMapFs()))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, "network", output.StepName)
+}
+
+func TestExecuteStep_ShouldPassParamsAndReturnTypedOutputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.json"), []byte(`{
+		"parameters": {
+			"runiac_region": {"type": "string"},
+			"dns_zone_id": {"type": "string"}
+		},
+		"resources": []
+	}`), 0644))
+
+	createdParametersFile := ""
+	azureCLI = stubAzureCLI{
+		parametersFile: &createdParametersFile,
+		created: `{"properties": {"outputs": {
+			"vnetId": {"type": "String", "value": "vnet"},
+			"subnetCount": {"type": "Int", "value": 2},
+			"private": {"type": "Bool", "value": true}
+		}}}`,
+	}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	exec := stubExecution(afero.NewOsFs())
+	exec.Dir = dir
+
+	output := ArmStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, map[string]interface{}{"vnetId": "vnet", "subnetCount": float64(2), "private": true}, output.OutputVariables)
+	require.Equal(t, ".temp/primary-centralus/parameters.json", createdParametersFile)
+
+	data, err := os.ReadFile(filepath.Join(dir, createdParametersFile))
+	require.NoError(t, err)
+
+	params := struct {
+		Parameters map[string]parameterValue `json:"parameters"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &params))
+	require.Equal(t, map[string]parameterValue{
+		"runiac_region": {Value: "centralus"},
+		"dns_zone_id":   {Value: "zone"},
+	}, params.Parameters)
+}
+
+func TestExecuteStep_ShouldFallBackToStoredDeploymentOutputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.json"), []byte(`{"resources": []}`), 0644))
+
+	parametersFile := ""
+	azureCLI = stubAzureCLI{
+		parametersFile: &parametersFile,
+		created:        "WARNING: the response is not json",
+		deployment:     `{"properties": {"outputs": {"vnetId": {"type": "String", "value": "vnet"}}}}`,
+	}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	exec := stubExecution(afero.NewOsFs())
+	exec.Dir = dir
+
+	output := ArmStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, map[string]interface{}{"vnetId": "vnet"}, output.OutputVariables)
+	require.Equal(t, "", parametersFile, "templates without matching parameters should not get a parameters file")
+}
+
+func TestReadStepConfig_ShouldDetectScopeFromTemplateSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#":                ResourceGroupScope,
+		"https://schema.management.azure.com/schemas/2018-05-01/subscriptionDeploymentTemplate.json#":    SubscriptionScope,
+		"https://schema.management.azure.com/schemas/2019-08-01/managementGroupDeploymentTemplate.json#": ManagementGroupScope,
+		"https://schema.management.azure.com/schemas/2019-08-01/tenantDeploymentTemplate.json#":          TenantScope,
+		"": SubscriptionScope,
+	}
+
+	for schema, expected := range tests {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/steps/network/main.json", []byte(fmt.Sprintf(`{"$schema": %q}`, schema)), 0644))
+		require.NoError(t, afero.WriteFile(fs, "/steps/network/runiac.yml", []byte("arm:\n  resource_group: rg-network\n  management_group: mg-runiac\n"), 0644))
+
+		stepConfig, err := ReadStepConfig(stubExecution(fs))
+
+		require.NoError(t, err)
+		require.Equal(t, expected, stepConfig.Scope, schema)
+	}
+}
+
+func TestReadStepConfig_ShouldRequireResourceGroup(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/runiac.yml", []byte("arm:\n  scope: resource_group\n"), 0644))
+
+	_, err := ReadStepConfig(stubExecution(fs))
+
+	require.EqualError(t, err, "arm.resource_group is required to deploy to the resource_group scope")
+}
+
+func TestReadStepConfig_ShouldRejectUnknownScope(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/runiac.yml", []byte("arm:\n  scope: region\n"), 0644))
+
+	_, err := ReadStepConfig(stubExecution(fs))
+
+	require.Error(t, err)
+}
+
+func TestExecuteStep_ShouldDeployToConfiguredScope(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.json"), []byte(`{"resources": []}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "runiac.yml"), []byte("arm:\n  scope: management_group\n  management_group: mg-runiac\n"), 0644))
+
+	calls := []string{}
+	azureCLI = stubAzureCLI{calls: &calls, created: `{"properties": {}}`}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	exec := stubExecution(afero.NewOsFs())
+	exec.Dir = dir
+
+	output := ArmStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, []string{"what-if mg", "create mg"}, calls)
+}
+
+func TestExecuteStepDestroy_ShouldDeleteResourceGroupContents(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/runiac.yml", []byte("arm:\n  scope: resource_group\n  resource_group: rg-network\n"), 0644))
+
+	calls := []string{}
+	azureCLI = stubAzureCLI{calls: &calls, groupResources: `["/vnet", "/subnet"]`}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	output := ArmStepper{}.ExecuteStepDestroy(stubExecution(fs))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, []string{"list rg-network", "delete /subnet", "delete /vnet", "delete group runiac-runiac-core-network-centralus"}, calls)
+}
+
+func TestExecuteStepDestroy_ShouldDeleteDeploymentResources(t *testing.T) {
+	calls := []string{}
+	azureCLI = stubAzureCLI{calls: &calls, deployment: `{"properties": {"outputResources": [{"id": "/rg"}]}}`}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	output := ArmStepper{}.ExecuteStepDestroy(stubExecution(afero.NewMemMapFs()))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, []string{"show sub", "delete /rg", "delete sub runiac-runiac-core-network-centralus"}, calls)
+}
+
+func TestExecuteStep_ShouldSummarizeWhatIfWhenDryRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.json"), []byte(`{"resources": []}`), 0644))
+
+	calls := []string{}
+	azureCLI = stubAzureCLI{calls: &calls, whatIf: whatIfFullResourcePayloads}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	exec := stubExecution(afero.NewOsFs())
+	exec.Dir = dir
+	exec.DryRun = true
+
+	output := ArmStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, []string{"what-if sub"}, calls)
+	require.Equal(t, map[string][]string{
+		"[create]": {"/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"},
+		"[update]": {"/subscriptions/1/resourceGroups/rg"},
+		"[delete]": {"/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"},
+	}, output.PlannedChanges)
+}
+
+func TestWriteParametersFile_ShouldApplyEnvironmentAndRingParameterFiles(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/main.json", []byte(`{
+		"parameters": {
+			"runiac_region": {"type": "string"},
+			"sku": {"type": "string"},
+			"addressSpace": {"type": "string"},
+			"adminPassword": {"type": "securestring"}
+		}
+	}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/parameters.json", []byte(`{"parameters": {
+		"sku": {"value": "Basic"},
+		"addressSpace": {"value": "10.0.0.0/16"},
+		"adminPassword": {"reference": {"keyVault": {"id": "/vault"}, "secretName": "admin"}}
+	}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/environment_prod_parameters.json", []byte(`{"parameters": {"SKU": {"value": "Standard"}}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/ring_canary_parameters.json", []byte(`{"parameters": {"addressSpace": {"value": "10.1.0.0/16"}}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/ring_prod_parameters.json", []byte(`{"parameters": {"addressSpace": {"value": "10.2.0.0/16"}}}`), 0644))
+
+	exec := stubExecution(fs)
+	exec.Environment = "Prod"
+	exec.DeploymentRing = "canary"
+
+	require.Equal(t, []string{"parameters.json", "environment_prod_parameters.json", "ring_canary_parameters.json"}, GetParameterFiles(exec))
+
+	file, err := WriteParametersFile(exec, "main.json", ".temp/parameters.json")
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, filepath.Join("/steps/network", file))
+	require.NoError(t, err)
+
+	params := struct {
+		Parameters map[string]json.RawMessage `json:"parameters"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &params))
+
+	require.Len(t, params.Parameters, 4)
+	require.JSONEq(t, `{"value": "centralus"}`, string(params.Parameters["runiac_region"]))
+	require.JSONEq(t, `{"value": "Standard"}`, string(params.Parameters["SKU"]))
+	require.JSONEq(t, `{"value": "10.1.0.0/16"}`, string(params.Parameters["addressSpace"]))
+	require.JSONEq(t, `{"reference": {"keyVault": {"id": "/vault"}, "secretName": "admin"}}`, string(params.Parameters["adminPassword"]))
+}
@@ -0,0 +1,389 @@
+package plugins_arm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/plugins/arm/pkg/arm"
+)
+
+// Results of deleting a resource
+const (
+	Deleted        = "deleted"
+	AlreadyDeleted = "already deleted"
+	DeleteFailed   = "failed"
+	DeleteSkipped  = "skipped" // a resource that had to be deleted first was not
+)
+
+// ResourceDeleteResult reports the deletion of a single resource
+type ResourceDeleteResult struct {
+	ID     string
+	Result string
+	Err    error
+}
+
+// deleteRetrySleep is how long to wait before first retrying a failed deletion, later retries wait twice as long as the
+// one before, up to maxDeleteRetrySleep
+var deleteRetrySleep = 10 * time.Second
+var maxDeleteRetrySleep = 2 * time.Minute
+
+// notFoundErrors are the error codes of resources and deployments that no longer exist
+var notFoundErrors = regexp.MustCompile(`\b(ResourceNotFound|ResourceGroupNotFound|ParentResourceNotFound|DeploymentNotFound)\b`)
+
+// transientDeleteErrors are the errors of deletions that can succeed when retried: throttling, server errors, timeouts
+// and resources that are still in use while what uses them is being deleted. Anything else, e.g. failed authorization
+// or a resource lock, fails the same way every time.
+var transientDeleteErrors = regexp.MustCompile(`\b(RetryableError|TooManyRequests|Throttled|RequestThrottled|Conflict|AnotherOperationInProgress|OperationNotAllowedInCurrentState|RetryableErrorDueToAnotherOperation|InUse\w*CannotBeDeleted|NicReservedForAnotherVm|InternalServerError|InternalError|ServiceUnavailable|BadGateway|GatewayTimeout)\b|timed out|connection reset by peer`)
+
+// deletionPriorities orders resources without dependencies between them by type, lower priorities are deleted first.
+// Resources attached to a network are deleted before it, and a network before what its subnets reference.
+var deletionPriorities = map[string]int{
+	"microsoft.compute/virtualmachinescalesets": 0,
+	"microsoft.compute/virtualmachines":         0,
+	"microsoft.network/networkinterfaces":       1,
+	"microsoft.network/privateendpoints":        1,
+	"microsoft.network/virtualnetworks":         6,
+	"microsoft.network/networksecuritygroups":   7,
+	"microsoft.network/routetables":             7,
+	"microsoft.network/natgateways":             7,
+	"microsoft.network/publicipaddresses":       8,
+}
+
+const defaultDeletionPriority = 5
+
+var resourceIDFunction = regexp.MustCompile(`(?i)^\[\s*resourceId\((.*)\)\s*]$`)
+
+// isNotFound checks if a command failed because what it targets no longer exists
+func isNotFound(out string, err error) bool {
+	return err != nil && (notFoundErrors.MatchString(out) || notFoundErrors.MatchString(err.Error()))
+}
+
+// isTransientDeleteError checks if a failed deletion can succeed when it is retried
+func isTransientDeleteError(out string, err error) bool {
+	return err != nil && (transientDeleteErrors.MatchString(out) || transientDeleteErrors.MatchString(err.Error()))
+}
+
+// deleteResources deletes resources one at a time in dependency order, retrying transient failures up to MaxRetries times.
+// Resources that no longer exist are already deleted, and resources that had to wait on a failed deletion are skipped.
+func deleteResources(exec config.StepExecution, options *arm.Options, ids []string, template map[string]interface{}) []ResourceDeleteResult {
+	order, predecessors := getDeletionOrder(ids, getTemplateDependencies(template))
+
+	results := []ResourceDeleteResult{}
+	failed := map[string]bool{}
+
+	for _, id := range order {
+		result := ResourceDeleteResult{ID: id, Result: Deleted}
+
+		for _, predecessor := range predecessors[id] {
+			if failed[predecessor] {
+				result.Result = DeleteSkipped
+				result.Err = fmt.Errorf("%s was not deleted", predecessor)
+				break
+			}
+		}
+
+		if result.Result != DeleteSkipped {
+			retryOptions := retry.Options{
+				MaxRetries: exec.MaxRetries,
+				Backoff:    retry.Exponential(deleteRetrySleep, maxDeleteRetrySleep),
+				Logger:     options.Logger,
+			}
+
+			err := retry.Do(exec.Context, fmt.Sprintf("delete %s", id), retryOptions, func(attempt int) error {
+				out, err := azureCLI.ResourceDelete(options, []string{id})
+				if isNotFound(out, err) {
+					result.Result = AlreadyDeleted
+					return nil
+				}
+
+				result.Err = err
+				if err != nil && !isTransientDeleteError(out, err) {
+					return retry.NonRetryable(err)
+				}
+
+				return err
+			})
+
+			if err != nil {
+				result.Result = DeleteFailed
+			} else {
+				result.Err = nil
+			}
+		}
+
+		if result.Err != nil {
+			failed[id] = true
+			options.Logger.WithError(result.Err).Errorf("%s %s", id, result.Result)
+		} else {
+			options.Logger.Infof("%s %s", id, result.Result)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// getDeleteResultsError joins the errors of resources that were not deleted
+func getDeleteResultsError(results []ResourceDeleteResult) error {
+	errs := []error{}
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.ID, r.Result, r.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// getDeletionOrder orders resource IDs so resources are deleted before the resources they depend on: children before
+// their parents, dependents before their template dependencies, ties broken by type. It returns the order and, for
+// every resource, the resources deleted before it because of a dependency.
+func getDeletionOrder(ids []string, dependencies [][2]string) ([]string, map[string][]string) {
+	predecessors := map[string][]string{}
+	successors := map[string][]string{}
+
+	addEdge := func(first string, then string) {
+		for _, existing := range predecessors[then] {
+			if existing == first {
+				return
+			}
+		}
+
+		predecessors[then] = append(predecessors[then], first)
+		successors[first] = append(successors[first], then)
+	}
+
+	for _, child := range ids {
+		for _, parent := range ids {
+			if child != parent && strings.HasPrefix(strings.ToLower(child), strings.ToLower(parent)+"/") {
+				addEdge(child, parent)
+			}
+		}
+	}
+
+	for _, dependency := range dependencies {
+		for _, dependent := range matchResourceIDs(ids, dependency[0]) {
+			for _, dependsOn := range matchResourceIDs(ids, dependency[1]) {
+				if dependent != dependsOn {
+					addEdge(dependent, dependsOn)
+				}
+			}
+		}
+	}
+
+	less := func(a string, b string) bool {
+		pa, pb := getDeletionPriority(a), getDeletionPriority(b)
+		if pa != pb {
+			return pa < pb
+		}
+		return a < b
+	}
+
+	remaining := map[string]int{}
+	for _, id := range ids {
+		remaining[id] = len(predecessors[id])
+	}
+
+	order := []string{}
+	for len(order) < len(ids) {
+		ready := []string{}
+		for id, count := range remaining {
+			if count == 0 {
+				ready = append(ready, id)
+			}
+		}
+
+		// dependencies that form a cycle are broken by deleting the remaining resources by type
+		if len(ready) == 0 {
+			for id := range remaining {
+				ready = append(ready, id)
+			}
+		}
+
+		sort.Slice(ready, func(i, j int) bool { return less(ready[i], ready[j]) })
+
+		next := ready[0]
+		order = append(order, next)
+		delete(remaining, next)
+
+		for _, successor := range successors[next] {
+			if _, ok := remaining[successor]; ok {
+				remaining[successor]--
+			}
+		}
+	}
+
+	return order, predecessors
+}
+
+// getDeletionPriority returns the priority of the type of a resource ID
+func getDeletionPriority(id string) int {
+	if priority, ok := deletionPriorities[getResourceType(id)]; ok {
+		return priority
+	}
+
+	return defaultDeletionPriority
+}
+
+// getResourceType returns the lowercase type of a resource ID, e.g. microsoft.network/virtualnetworks/subnets
+func getResourceType(id string) string {
+	lower := strings.ToLower(id)
+
+	i := strings.LastIndex(lower, "/providers/")
+	if i < 0 {
+		return ""
+	}
+
+	parts := strings.Split(lower[i+len("/providers/"):], "/")
+	resourceType := parts[0]
+	for j := 1; j < len(parts); j += 2 {
+		resourceType += "/" + parts[j]
+	}
+
+	return resourceType
+}
+
+// matchResourceIDs returns the IDs ending with a resource suffix, e.g. /providers/microsoft.network/virtualnetworks/vnet
+func matchResourceIDs(ids []string, suffix string) []string {
+	matches := []string{}
+	for _, id := range ids {
+		if strings.HasSuffix(strings.ToLower(id), suffix) {
+			matches = append(matches, id)
+		}
+	}
+
+	return matches
+}
+
+// getTemplateDependencies returns the dependsOn of a template's resources, including those of nested deployments, as
+// pairs of resource suffixes of the dependent resource and the resource it depends on. Resources whose type or name
+// are expressions cannot be matched to resource IDs and are left out.
+func getTemplateDependencies(template map[string]interface{}) [][2]string {
+	dependencies := [][2]string{}
+	if template == nil {
+		return dependencies
+	}
+
+	resources := map[string]map[string]interface{}{}
+	switch r := template["resources"].(type) {
+	case []interface{}:
+		for i, item := range r {
+			if resource, ok := item.(map[string]interface{}); ok {
+				resources[fmt.Sprintf("%d", i)] = resource
+			}
+		}
+	case map[string]interface{}:
+		for symbolicName, item := range r {
+			if resource, ok := item.(map[string]interface{}); ok {
+				resources[symbolicName] = resource
+			}
+		}
+	}
+
+	// dependsOn can refer to resources by symbolic name or by name alone
+	suffixes := map[string]string{}
+	for key, resource := range resources {
+		suffix := getResourceSuffix(resource)
+		if suffix == "" {
+			continue
+		}
+
+		suffixes[key] = suffix
+		if name, ok := resource["name"].(string); ok {
+			suffixes[name] = suffix
+		}
+	}
+
+	for key, resource := range resources {
+		if properties, ok := resource["properties"].(map[string]interface{}); ok {
+			if nested, ok := properties["template"].(map[string]interface{}); ok {
+				dependencies = append(dependencies, getTemplateDependencies(nested)...)
+			}
+		}
+
+		dependent, ok := suffixes[key]
+		if !ok {
+			continue
+		}
+
+		dependsOn, _ := resource["dependsOn"].([]interface{})
+		for _, item := range dependsOn {
+			reference, _ := item.(string)
+
+			if suffix, ok := suffixes[reference]; ok {
+				dependencies = append(dependencies, [2]string{dependent, suffix})
+			} else if suffix := getReferenceSuffix(reference); suffix != "" {
+				dependencies = append(dependencies, [2]string{dependent, suffix})
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// getResourceSuffix returns the suffix of the IDs of a template resource with a literal type and name
+func getResourceSuffix(resource map[string]interface{}) string {
+	resourceType, _ := resource["type"].(string)
+	name, _ := resource["name"].(string)
+
+	if resourceType == "" || name == "" || strings.HasPrefix(name, "[") || strings.HasPrefix(resourceType, "[") {
+		return ""
+	}
+
+	return buildResourceSuffix(resourceType, strings.Split(name, "/"))
+}
+
+// getReferenceSuffix returns the suffix of the IDs a dependsOn entry refers to, either a literal resourceId() call or
+// the namespace/type/name form
+func getReferenceSuffix(reference string) string {
+	match := resourceIDFunction.FindStringSubmatch(reference)
+	if match == nil {
+		if strings.HasPrefix(reference, "[") || strings.Count(reference, "/") < 2 {
+			return ""
+		}
+
+		return "/providers/" + strings.ToLower(reference)
+	}
+
+	literals := []string{}
+	for _, arg := range strings.Split(match[1], ",") {
+		arg = strings.TrimSpace(arg)
+		if len(arg) < 2 || !strings.HasPrefix(arg, "'") || !strings.HasSuffix(arg, "'") {
+			return ""
+		}
+
+		literals = append(literals, strings.Trim(arg, "'"))
+	}
+
+	// resourceId([subscriptionId], [resourceGroupName], resourceType, resourceName1, [resourceName2], ...)
+	for i, literal := range literals {
+		if strings.Contains(literal, ".") && strings.Contains(literal, "/") {
+			return buildResourceSuffix(literal, literals[i+1:])
+		}
+	}
+
+	return ""
+}
+
+// buildResourceSuffix interleaves the segments of a type with its names, e.g. Microsoft.Network/virtualNetworks/subnets
+// named vnet and default become /providers/microsoft.network/virtualnetworks/vnet/subnets/default
+func buildResourceSuffix(resourceType string, names []string) string {
+	types := strings.Split(resourceType, "/")
+	if len(types) < 2 || len(names) != len(types)-1 {
+		return ""
+	}
+
+	suffix := "/providers/" + types[0]
+	for i, name := range names {
+		suffix += "/" + types[i+1] + "/" + name
+	}
+
+	return strings.ToLower(suffix)
+}
@@ -0,0 +1,201 @@
+package plugins_arm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/arm/pkg/arm"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	vnetID    = "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+	subnetID  = "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default"
+	nsgID     = "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"
+	nicID     = "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic"
+	vaultID   = "/subscriptions/1/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/vault"
+	storageID = "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/logs"
+	groupID   = "/subscriptions/1/resourceGroups/rg"
+)
+
+func TestGetDeletionOrder_ShouldDeleteChildrenAndAttachedResourcesFirst(t *testing.T) {
+	t.Parallel()
+
+	order, predecessors := getDeletionOrder([]string{groupID, vnetID, nsgID, subnetID, nicID, vaultID}, nil)
+
+	require.Equal(t, []string{nicID, vaultID, subnetID, vnetID, nsgID, groupID}, order)
+	require.Equal(t, []string{subnetID}, predecessors[vnetID])
+	require.ElementsMatch(t, []string{vnetID, nsgID, subnetID, nicID, vaultID}, predecessors[groupID])
+}
+
+func TestGetDeletionOrder_ShouldDeleteDependentsBeforeTheirTemplateDependencies(t *testing.T) {
+	t.Parallel()
+
+	template := map[string]interface{}{
+		"resources": []interface{}{
+			map[string]interface{}{"type": "Microsoft.Network/networkSecurityGroups", "name": "nsg", "dependsOn": []interface{}{
+				"[resourceId('Microsoft.KeyVault/vaults', 'vault')]",
+			}},
+			map[string]interface{}{"type": "Microsoft.Resources/deployments", "name": "nested", "properties": map[string]interface{}{
+				"template": map[string]interface{}{"resources": map[string]interface{}{
+					"storage": map[string]interface{}{"type": "Microsoft.Storage/storageAccounts", "name": "logs"},
+					"vault": map[string]interface{}{"type": "Microsoft.KeyVault/vaults", "name": "vault", "dependsOn": []interface{}{
+						"storage",
+					}},
+				}},
+			}},
+		},
+	}
+
+	order, predecessors := getDeletionOrder([]string{storageID, vaultID, nsgID}, getTemplateDependencies(template))
+
+	// by type alone the nsg would be deleted last
+	require.Equal(t, []string{nsgID, vaultID, storageID}, order)
+	require.Equal(t, []string{nsgID}, predecessors[vaultID])
+	require.Equal(t, []string{vaultID}, predecessors[storageID])
+}
+
+func TestGetDeletionOrder_ShouldOverrideTypePriorityWithTemplateDependencies(t *testing.T) {
+	t.Parallel()
+
+	// a vnet depending on a nic is unusual, but its dependsOn decides the order
+	order, _ := getDeletionOrder([]string{nicID, vnetID}, [][2]string{
+		{"/providers/microsoft.network/virtualnetworks/vnet", "/providers/microsoft.network/networkinterfaces/nic"},
+	})
+
+	require.Equal(t, []string{vnetID, nicID}, order)
+}
+
+func TestGetDeletionOrder_ShouldBreakCycles(t *testing.T) {
+	t.Parallel()
+
+	order, _ := getDeletionOrder([]string{nsgID, vaultID}, [][2]string{
+		{"/providers/microsoft.network/networksecuritygroups/nsg", "/providers/microsoft.keyvault/vaults/vault"},
+		{"/providers/microsoft.keyvault/vaults/vault", "/providers/microsoft.network/networksecuritygroups/nsg"},
+	})
+
+	require.ElementsMatch(t, []string{nsgID, vaultID}, order)
+}
+
+func TestGetReferenceSuffix_ShouldParseLiteralReferences(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"[resourceId('Microsoft.Network/virtualNetworks/subnets', 'vnet', 'default')]": "/providers/microsoft.network/virtualnetworks/vnet/subnets/default",
+		"[resourceId('rg', 'Microsoft.Network/virtualNetworks', 'vnet')]":              "/providers/microsoft.network/virtualnetworks/vnet",
+		"Microsoft.Network/virtualNetworks/vnet":                                       "/providers/microsoft.network/virtualnetworks/vnet",
+		"[resourceId('Microsoft.Network/virtualNetworks', parameters('vnetName'))]":    "",
+		"[concat('Microsoft.Network/virtualNetworks/', parameters('vnetName'))]":       "",
+		"vnet": "",
+	}
+
+	for reference, expected := range tests {
+		require.Equal(t, expected, getReferenceSuffix(reference), reference)
+	}
+}
+
+func TestExecuteStepDestroy_ShouldRetryAndTreatAlreadyDeletedAsSuccess(t *testing.T) {
+	deleteRetrySleep = 0
+	calls := []string{}
+	azureCLI = stubAzureCLI{
+		calls:      &calls,
+		deployment: `{"properties": {"outputResources": [{"id": "` + vnetID + `"}, {"id": "` + nicID + `"}]}}`,
+		deleteFailures: map[string][]string{
+			nicID:  {"ERROR: (RetryableError) The operation timed out."},
+			vnetID: {"ERROR: (ResourceNotFound) The Resource 'vnet' under resource group 'rg' was not found."},
+		},
+	}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	exec := stubExecution(afero.NewMemMapFs())
+	exec.MaxRetries = 1
+
+	output := ArmStepper{}.ExecuteStepDestroy(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, []string{"show sub", "delete " + nicID, "delete " + nicID, "delete " + vnetID, "delete sub runiac-runiac-core-network-centralus"}, calls)
+	require.Equal(t, nicID+": deleted\n"+vnetID+": already deleted", output.StreamOutput)
+}
+
+func TestExecuteStepDestroy_ShouldSkipDependenciesOfFailedDeletesAndKeepDeployment(t *testing.T) {
+	deleteRetrySleep = 0
+	calls := []string{}
+	azureCLI = stubAzureCLI{
+		calls:      &calls,
+		deployment: `{"properties": {"outputResources": [{"id": "` + vnetID + `"}, {"id": "` + subnetID + `"}, {"id": "` + vaultID + `"}]}}`,
+		deleteFailures: map[string][]string{
+			subnetID: {"ERROR: (InUseSubnetCannotBeDeleted) Subnet default is in use."},
+		},
+	}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	output := ArmStepper{}.ExecuteStepDestroy(stubExecution(afero.NewMemMapFs()))
+
+	require.Error(t, output.Err)
+	require.Equal(t, config.Fail, output.Status)
+	require.Equal(t, []string{"show sub", "delete " + vaultID, "delete " + subnetID}, calls)
+	require.Equal(t, vaultID+": deleted\n"+subnetID+": failed\n"+vnetID+": skipped", output.StreamOutput)
+}
+
+func TestExecuteStepDestroy_ShouldOnlyRetryTransientDeleteErrors(t *testing.T) {
+	deleteRetrySleep = 0
+	calls := []string{}
+	azureCLI = stubAzureCLI{
+		calls:      &calls,
+		deployment: `{"properties": {"outputResources": [{"id": "` + vaultID + `"}]}}`,
+		deleteFailures: map[string][]string{
+			vaultID: {"ERROR: (AuthorizationFailed) The client 'runiac' does not have authorization to perform action 'Microsoft.KeyVault/vaults/delete'."},
+		},
+	}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	exec := stubExecution(afero.NewMemMapFs())
+	exec.MaxRetries = 2
+
+	output := ArmStepper{}.ExecuteStepDestroy(exec)
+
+	require.Error(t, output.Err)
+	require.Equal(t, config.Fail, output.Status)
+	require.Equal(t, []string{"show sub", "delete " + vaultID}, calls, "Authorization failures should not be retried")
+}
+
+func TestExecuteStepDestroy_ShouldStopRetryingWhenTheRunIsStopped(t *testing.T) {
+	deleteRetrySleep = time.Minute
+	defer func() { deleteRetrySleep = 0 }()
+
+	calls := []string{}
+	azureCLI = stubAzureCLI{
+		calls:      &calls,
+		deployment: `{"properties": {"outputResources": [{"id": "` + nicID + `"}]}}`,
+		deleteFailures: map[string][]string{
+			nicID: {"ERROR: (RetryableError) The operation timed out."},
+		},
+	}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	exec := stubExecution(afero.NewMemMapFs())
+	exec.MaxRetries = 2
+	exec.Context = ctx
+
+	output := ArmStepper{}.ExecuteStepDestroy(exec)
+
+	require.Error(t, output.Err)
+	require.Equal(t, []string{"show sub", "delete " + nicID}, calls)
+}
+
+func TestExecuteStepDestroy_ShouldBeNaWhenNotDeployed(t *testing.T) {
+	azureCLI = stubAzureCLI{}
+	defer func() { azureCLI = arm.AzureCLI{} }()
+
+	output := ArmStepper{}.ExecuteStepDestroy(stubExecution(afero.NewMemMapFs()))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Na, output.Status)
+}
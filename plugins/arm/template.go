@@ -0,0 +1,187 @@
+package plugins_arm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+)
+
+const mainTemplateFile = "main.json"
+
+// TempDir returns the directory, relative to the step directory, holding the files generated for an execution.
+// Each region deploy type and region has its own, so regions can be deployed concurrently.
+func TempDir(exec config.StepExecution) string {
+	return filepath.Join(".temp", fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region))
+}
+
+// parseMainTemplate writes main.json, with every linked template it references inlined, to the execution's temp
+// directory and returns its path relative to the step directory
+func parseMainTemplate(exec config.StepExecution) (string, error) {
+	template, err := inlineTemplateFile(exec.Fs, exec.Dir, mainTemplateFile, []string{})
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal interpolated template: %w", err)
+	}
+
+	err = exec.Fs.MkdirAll(filepath.Join(exec.Dir, TempDir(exec)), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(TempDir(exec), mainTemplateFile)
+	err = afero.WriteFile(exec.Fs, filepath.Join(exec.Dir, filePath), result, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// readDeployedTemplate reads the template last generated for the execution, or the step's template, returning nil when
+// neither can be read
+func readDeployedTemplate(exec config.StepExecution) map[string]interface{} {
+	for _, templateFile := range []string{filepath.Join(TempDir(exec), mainTemplateFile), mainTemplateFile} {
+		if exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, templateFile)); !exists {
+			continue
+		}
+
+		template, err := inlineTemplateFile(exec.Fs, exec.Dir, templateFile, []string{})
+		if err != nil {
+			exec.Logger.WithError(err).Warnf("Unable to read %s, resources are deleted without its dependencies", templateFile)
+			return nil
+		}
+
+		return template
+	}
+
+	return nil
+}
+
+// inlineTemplateFile reads a template, relative to dir, and inlines its linked templates. chain holds the templates
+// currently being inlined, so a template linking back to one of them is reported instead of recursing forever.
+func inlineTemplateFile(fs afero.Fs, dir string, file string, chain []string) (map[string]interface{}, error) {
+	path := filepath.Clean(filepath.Join(dir, file))
+
+	for _, linking := range chain {
+		if linking == path {
+			return nil, fmt.Errorf("linked templates form a cycle: %s -> %s", strings.Join(chain, " -> "), path)
+		}
+	}
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	template := map[string]interface{}{}
+	err = json.Unmarshal(data, &template)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal template %s: %w", path, err)
+	}
+
+	err = inlineTemplate(fs, template, filepath.Dir(path), append(chain, path))
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// inlineTemplate replaces the links of a template's nested deployments with the templates they link to. Links are
+// local, set by _templateLink.localUri, or templateLink.relativePath, both relative to the linking template.
+func inlineTemplate(fs afero.Fs, template map[string]interface{}, dir string, chain []string) error {
+	source := chain[len(chain)-1]
+
+	var resources []interface{}
+	switch r := template["resources"].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		resources = r
+	case map[string]interface{}:
+		// symbolic resource names, languageVersion 2.0
+		for _, resource := range r {
+			resources = append(resources, resource)
+		}
+	default:
+		return fmt.Errorf("resources of template %s must be an array or an object", source)
+	}
+
+	for i, item := range resources {
+		resource, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("resource %d of template %s must be an object", i, source)
+		}
+
+		if resourceType, _ := resource["type"].(string); !strings.EqualFold(resourceType, "Microsoft.Resources/deployments") {
+			continue
+		}
+
+		properties, ok := resource["properties"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		linkedFile, linkProperty, err := getLocalTemplateLink(properties, i, source)
+		if err != nil {
+			return err
+		}
+
+		if linkedFile != "" {
+			linkedTemplate, err := inlineTemplateFile(fs, dir, linkedFile, chain)
+			if err != nil {
+				return err
+			}
+
+			// replace the link with the contents of the template itself
+			delete(properties, linkProperty)
+			properties["template"] = linkedTemplate
+			continue
+		}
+
+		// nested templates can link to templates too
+		if nested, ok := properties["template"].(map[string]interface{}); ok {
+			if err := inlineTemplate(fs, nested, dir, chain); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getLocalTemplateLink returns the file a nested deployment links to and the property holding the link, or "" when the
+// deployment does not link to a local template, e.g. for templates linked by uri
+func getLocalTemplateLink(properties map[string]interface{}, index int, source string) (string, string, error) {
+	for property, key := range map[string]string{"_templateLink": "localUri", "templateLink": "relativePath"} {
+		if properties[property] == nil {
+			continue
+		}
+
+		link, ok := properties[property].(map[string]interface{})
+		if !ok {
+			return "", "", fmt.Errorf("properties.%s of resource %d of template %s must be an object", property, index, source)
+		}
+
+		if link[key] == nil {
+			continue
+		}
+
+		file, ok := link[key].(string)
+		if !ok || file == "" {
+			return "", "", fmt.Errorf("properties.%s.%s of resource %d of template %s must be a file path", property, key, index, source)
+		}
+
+		return file, property, nil
+	}
+
+	return "", "", nil
+}
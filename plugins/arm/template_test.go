@@ -0,0 +1,129 @@
+package plugins_arm
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplates(t *testing.T, fs afero.Fs, templates map[string]string) {
+	for name, template := range templates {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join("/steps/network", name), []byte(template), 0644))
+	}
+}
+
+func TestParseMainTemplate_ShouldInlineNestedLinkedTemplates(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	writeTemplates(t, fs, map[string]string{
+		"main.json": `{"resources": [
+			{"type": "Microsoft.Network/virtualNetworks", "name": "vnet"},
+			{"type": "Microsoft.Resources/deployments", "name": "subnets", "properties": {"_templateLink": {"localUri": "linked/subnets.json"}}}
+		]}`,
+		"linked/subnets.json": `{"resources": [
+			{"type": "Microsoft.Resources/deployments", "name": "nsg", "properties": {"templateLink": {"relativePath": "nsg/nsg.json"}}}
+		]}`,
+		"linked/nsg/nsg.json": `{"resources": [{"type": "Microsoft.Network/networkSecurityGroups", "name": "nsg"}]}`,
+	})
+
+	exec := stubExecution(fs)
+
+	templateFile, err := parseMainTemplate(exec)
+	require.NoError(t, err)
+	require.Equal(t, ".temp/primary-centralus/main.json", templateFile)
+
+	data, err := afero.ReadFile(fs, filepath.Join(exec.Dir, templateFile))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"resources": [
+		{"type": "Microsoft.Network/virtualNetworks", "name": "vnet"},
+		{"type": "Microsoft.Resources/deployments", "name": "subnets", "properties": {"template": {"resources": [
+			{"type": "Microsoft.Resources/deployments", "name": "nsg", "properties": {"template": {"resources": [
+				{"type": "Microsoft.Network/networkSecurityGroups", "name": "nsg"}
+			]}}}
+		]}}}
+	]}`, string(data))
+
+	// re-running with an existing temp directory succeeds
+	_, err = parseMainTemplate(exec)
+	require.NoError(t, err)
+}
+
+func TestParseMainTemplate_ShouldInlineLinksWithinNestedTemplates(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	writeTemplates(t, fs, map[string]string{
+		"main.json": `{"resources": {
+			"outer": {"type": "Microsoft.Resources/deployments", "properties": {"template": {"resources": [
+				{"type": "Microsoft.Resources/deployments", "properties": {"templateLink": {"relativePath": "inner.json"}}}
+			]}}}
+		}}`,
+		"inner.json": `{"resources": []}`,
+	})
+
+	templateFile, err := parseMainTemplate(stubExecution(fs))
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, filepath.Join("/steps/network", templateFile))
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{"resources": {
+		"outer": {"type": "Microsoft.Resources/deployments", "properties": {"template": {"resources": [
+			{"type": "Microsoft.Resources/deployments", "properties": {"template": {"resources": []}}}
+		]}}}
+	}}`, string(data))
+}
+
+func TestParseMainTemplate_ShouldDetectCycles(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	writeTemplates(t, fs, map[string]string{
+		"main.json": `{"resources": [{"type": "Microsoft.Resources/deployments", "properties": {"templateLink": {"relativePath": "a.json"}}}]}`,
+		"a.json":    `{"resources": [{"type": "Microsoft.Resources/deployments", "properties": {"templateLink": {"relativePath": "b.json"}}}]}`,
+		"b.json":    `{"resources": [{"type": "Microsoft.Resources/deployments", "properties": {"templateLink": {"relativePath": "a.json"}}}]}`,
+	})
+
+	_, err := parseMainTemplate(stubExecution(fs))
+
+	require.EqualError(t, err, "linked templates form a cycle: /steps/network/main.json -> /steps/network/a.json -> /steps/network/b.json -> /steps/network/a.json")
+}
+
+func TestParseMainTemplate_ShouldReturnErrorsForInvalidTemplates(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"resources is not an array":  `{"resources": "vnet"}`,
+		"resource is not an object":  `{"resources": ["vnet"]}`,
+		"link is not an object":      `{"resources": [{"type": "Microsoft.Resources/deployments", "properties": {"_templateLink": "linked.json"}}]}`,
+		"link path is not a string":  `{"resources": [{"type": "Microsoft.Resources/deployments", "properties": {"templateLink": {"relativePath": 1}}}]}`,
+		"linked template is missing": `{"resources": [{"type": "Microsoft.Resources/deployments", "properties": {"templateLink": {"relativePath": "missing.json"}}}]}`,
+		"template is not valid json": `{"resources": [`,
+	}
+
+	for name, template := range tests {
+		fs := afero.NewMemMapFs()
+		writeTemplates(t, fs, map[string]string{"main.json": template})
+
+		require.NotPanics(t, func() {
+			_, err := parseMainTemplate(stubExecution(fs))
+			require.Error(t, err, name)
+		}, name)
+	}
+}
+
+func TestTempDir_ShouldBeUniquePerRegion(t *testing.T) {
+	t.Parallel()
+
+	primary := TempDir(config.StepExecution{RegionDeployType: config.PrimaryRegionDeployType, Region: "centralus"})
+	regional := TempDir(config.StepExecution{RegionDeployType: config.RegionalRegionDeployType, Region: "centralus"})
+	other := TempDir(config.StepExecution{RegionDeployType: config.RegionalRegionDeployType, Region: "eastus"})
+
+	require.Equal(t, ".temp/primary-centralus", primary)
+	require.NotEqual(t, primary, regional)
+	require.NotEqual(t, regional, other)
+}
@@ -0,0 +1,77 @@
+package plugins_arm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ChangeSummary normalizes the changes a what-if predicts into the resource IDs to create, modify and delete
+type ChangeSummary struct {
+	Create []string
+	Modify []string
+	Delete []string
+}
+
+// summaryActions maps what-if change types to the actions terraform reports, so change summaries read the same
+var summaryActions = map[string]string{
+	"Create": "[create]",
+	"Modify": "[update]",
+	"Deploy": "[update]", // redeployed resources whose changes cannot be predicted
+	"Delete": "[delete]",
+}
+
+// ReadChangeSummary reads the JSON of a what-if run with --result-format FullResourcePayloads.
+// NoChange, Ignore and Unsupported changes are not part of the summary.
+func ReadChangeSummary(resp string) (ChangeSummary, error) {
+	result := whatIfResult{}
+	err := json.Unmarshal([]byte(resp), &result)
+	if err != nil {
+		return ChangeSummary{}, fmt.Errorf("unable to read template deployment what-if: %w", err)
+	}
+
+	return getChangeSummary(result), nil
+}
+
+func getChangeSummary(result whatIfResult) ChangeSummary {
+	summary := ChangeSummary{}
+
+	for _, c := range result.Changes {
+		switch c.ChangeType {
+		case "Create":
+			summary.Create = append(summary.Create, c.ResourceID)
+		case "Modify", "Deploy":
+			summary.Modify = append(summary.Modify, c.ResourceID)
+		case "Delete":
+			summary.Delete = append(summary.Delete, c.ResourceID)
+		}
+	}
+
+	sort.Strings(summary.Create)
+	sort.Strings(summary.Modify)
+	sort.Strings(summary.Delete)
+
+	return summary
+}
+
+// String returns the counts of the summary, e.g. "2 to create, 1 to modify, 0 to delete"
+func (s ChangeSummary) String() string {
+	return fmt.Sprintf("%d to create, %d to modify, %d to delete", len(s.Create), len(s.Modify), len(s.Delete))
+}
+
+// PlannedChanges returns the resource IDs by planned action, e.g. K=[create] V=[/subscriptions/.../vnet]
+func (s ChangeSummary) PlannedChanges() map[string][]string {
+	planned := map[string][]string{}
+
+	for action, ids := range map[string][]string{
+		summaryActions["Create"]: s.Create,
+		summaryActions["Modify"]: s.Modify,
+		summaryActions["Delete"]: s.Delete,
+	} {
+		if len(ids) > 0 {
+			planned[action] = ids
+		}
+	}
+
+	return planned
+}
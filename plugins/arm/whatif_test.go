@@ -0,0 +1,80 @@
+package plugins_arm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// whatIfFullResourcePayloads is the output of az deployment what-if --result-format FullResourcePayloads --no-pretty-print
+const whatIfFullResourcePayloads = `{
+  "status": "Succeeded",
+  "error": null,
+  "changes": [
+    {
+      "resourceId": "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+      "changeType": "Create",
+      "before": null,
+      "after": {"name": "vnet", "location": "centralus", "properties": {"addressSpace": {"addressPrefixes": ["10.0.0.0/16"]}}},
+      "delta": null
+    },
+    {
+      "resourceId": "/subscriptions/1/resourceGroups/rg",
+      "changeType": "Modify",
+      "before": {"tags": {"team": "core"}},
+      "after": {"tags": {"team": "runiac"}},
+      "delta": [{"path": "tags.team", "propertyChangeType": "Modify", "before": "core", "after": "runiac"}]
+    },
+    {
+      "resourceId": "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg",
+      "changeType": "Delete",
+      "before": {"name": "nsg"},
+      "after": null,
+      "delta": null
+    },
+    {
+      "resourceId": "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/logs",
+      "changeType": "NoChange",
+      "before": {"name": "logs"},
+      "after": {"name": "logs"},
+      "delta": null
+    },
+    {
+      "resourceId": "/subscriptions/1/resourceGroups/other",
+      "changeType": "Ignore",
+      "before": null,
+      "after": null,
+      "delta": null
+    }
+  ]
+}`
+
+func TestReadChangeSummary_ShouldCountChangesByType(t *testing.T) {
+	t.Parallel()
+
+	summary, err := ReadChangeSummary(whatIfFullResourcePayloads)
+
+	require.NoError(t, err)
+	require.Equal(t, ChangeSummary{
+		Create: []string{"/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"},
+		Modify: []string{"/subscriptions/1/resourceGroups/rg"},
+		Delete: []string{"/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"},
+	}, summary)
+	require.Equal(t, "1 to create, 1 to modify, 1 to delete", summary.String())
+}
+
+func TestReadChangeSummary_ShouldFailOnInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := ReadChangeSummary("Resource changes: 1 to create.")
+
+	require.Error(t, err)
+}
+
+func TestPlannedChanges_ShouldOmitActionsWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	summary := ChangeSummary{Create: []string{"/vnet"}}
+
+	require.Equal(t, map[string][]string{"[create]": {"/vnet"}}, summary.PlannedChanges())
+}
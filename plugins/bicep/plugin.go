@@ -0,0 +1,43 @@
+package plugins_bicep
+
+import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// RunnerName is the runner configuration value that selects the bicep runner
+const RunnerName = "bicep"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return BicepStepper{} },
+		Plugin:     BicepPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains a bicep file
+func Detect(fs afero.Fs, dir string) bool {
+	exists, err := afero.Exists(fs, filepath.Join(dir, mainBicepFile))
+	return err == nil && exists
+}
+
+type BicepPlugin struct{}
+
+func (info BicepPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac bicep plugin")
+	logger.Warn("The bicep runner is currently in preview and is subject to change in future runiac releases")
+
+	cmd := bicepCommand(".", logger.WithField("BicepPlugin", "info"), []string{"--version"}, []string{"bicep", "version"})
+	out, err := shell.RunShellCommandAndGetOutput(cmd)
+	if err != nil {
+		logger.Warn("Unable to print bicep version")
+	} else {
+		logger.Info("Binary: ", out)
+	}
+}
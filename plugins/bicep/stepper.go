@@ -0,0 +1,126 @@
+package plugins_bicep
+
+import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	pluginsarm "github.com/optum/runiac/plugins/arm"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const mainBicepFile = "main.bicep"
+
+// BicepStepper compiles a step's main.bicep and deploys the resulting template with the arm runner
+type BicepStepper struct{}
+
+var runBicep = shell.RunShellCommandAndGetAndStreamOutput
+var bicepInstalled = shell.CommandInstalled
+
+func (stepper BicepStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStep compiles and deploys a step, returning the deployment outputs
+func (stepper BicepStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	parametersFile, err := prepareTemplate(exec)
+	if err != nil {
+		exec.Logger.WithError(err).Error("Unable to prepare template for step execution")
+		return failedOutput(exec, err)
+	}
+
+	return pluginsarm.DeployTemplate(exec, templateFile(exec), parametersFile)
+}
+
+// ExecuteStepDestroy destroys the resources of the step's last deployment
+func (stepper BicepStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	if err := ensureTemplate(exec); err != nil {
+		return failedOutput(exec, err)
+	}
+
+	return pluginsarm.ArmStepper{}.ExecuteStepDestroy(exec)
+}
+
+// ExecuteStepOutputs reads the outputs of the step's last deployment
+func (stepper BicepStepper) ExecuteStepOutputs(exec config.StepExecution) config.StepOutput {
+	if err := ensureTemplate(exec); err != nil {
+		return failedOutput(exec, err)
+	}
+
+	return pluginsarm.ArmStepper{}.ExecuteStepOutputs(exec)
+}
+
+// ExecuteStepTests executes the tests for a step
+func (stepper BicepStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return pluginsarm.ArmStepper{}.ExecuteStepTests(exec)
+}
+
+// prepareTemplate compiles main.bicep and writes the parameters file, returning its path or "" when there are no parameters
+func prepareTemplate(exec config.StepExecution) (string, error) {
+	err := exec.Fs.MkdirAll(filepath.Join(exec.Dir, pluginsarm.TempDir(exec)), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = runBicep(bicepCommand(exec.Dir, exec.Logger.WithField("bicep", "build"),
+		[]string{"build", mainBicepFile, "--outfile", templateFile(exec)},
+		[]string{"bicep", "build", "--file", mainBicepFile, "--outfile", templateFile(exec)}))
+	if err != nil {
+		return "", err
+	}
+
+	return pluginsarm.WriteParametersFile(exec, templateFile(exec), parametersFilePath(exec))
+}
+
+// ensureTemplate compiles main.bicep when it has not been compiled yet, the compiled template declares the scope the
+// step is deployed to
+func ensureTemplate(exec config.StepExecution) error {
+	if exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, templateFile(exec))); exists {
+		return nil
+	}
+
+	_, err := prepareTemplate(exec)
+	if err != nil {
+		exec.Logger.WithError(err).Error("Unable to compile template")
+	}
+
+	return err
+}
+
+func failedOutput(exec config.StepExecution, err error) config.StepOutput {
+	return config.StepOutput{
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		Status:           config.Fail,
+		Err:              err,
+	}
+}
+
+// templateFile returns the compiled template, relative to the step directory
+func templateFile(exec config.StepExecution) string {
+	return filepath.Join(pluginsarm.TempDir(exec), "main.json")
+}
+
+// parametersFilePath returns the generated parameters, relative to the step directory
+func parametersFilePath(exec config.StepExecution) string {
+	return filepath.Join(pluginsarm.TempDir(exec), "parameters.json")
+}
+
+// bicepCommand prefers the standalone bicep binary, falling back to the one managed by the azure cli
+func bicepCommand(dir string, logger *logrus.Entry, bicepArgs []string, azArgs []string) shell.Command {
+	command, args := "bicep", bicepArgs
+	if !bicepInstalled("bicep") {
+		command, args = "az", azArgs
+	}
+
+	return shell.Command{
+		Command:        command,
+		Args:           args,
+		WorkingDir:     dir,
+		Env:            map[string]string{},
+		Logger:         logger,
+		NonInteractive: true,
+	}
+}
@@ -0,0 +1,141 @@
+package plugins_bicep
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = logrus.NewEntry(logrus.New())
+
+const compiledTemplate = `{
+  "parameters": {
+    "runiac_region": {"type": "string"},
+    "network_vnet_id": {"type": "string"},
+    "instanceCount": {"type": "int"},
+    "tags": {"type": "object"},
+    "unset": {"type": "string"}
+  }
+}`
+
+func stubExecution(fs afero.Fs) config.StepExecution {
+	return config.StepExecution{
+		Logger:   logger,
+		Fs:       fs,
+		Dir:      "/steps/network",
+		StepName: "network",
+		Region:   "centralus",
+		OptionalStepParams: map[string]string{
+			"network-vnet_id": "vnet",
+			"instancecount":   "3",
+			"tags":            `{"team":"runiac"}`,
+			"undeclared":      "ignored",
+		},
+	}
+}
+
+// stubBuild compiles main.bicep by writing compiledTemplate to the --outfile argument
+func stubBuild(fs afero.Fs, commands *[]shell.Command) func(shell.Command) (string, error) {
+	return func(cmd shell.Command) (string, error) {
+		*commands = append(*commands, cmd)
+		outfile := cmd.Args[len(cmd.Args)-1]
+		return "", afero.WriteFile(fs, filepath.Join(cmd.WorkingDir, outfile), []byte(compiledTemplate), 0644)
+	}
+}
+
+func TestPrepareTemplate_ShouldCompileAndWriteDeclaredParameters(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	commands := []shell.Command{}
+
+	runBicep = stubBuild(fs, &commands)
+	bicepInstalled = func(string) bool { return true }
+	defer func() {
+		runBicep = shell.RunShellCommandAndGetAndStreamOutput
+		bicepInstalled = shell.CommandInstalled
+	}()
+
+	parametersFile, err := prepareTemplate(stubExecution(fs))
+
+	require.NoError(t, err)
+	require.Equal(t, ".temp/primary-centralus/parameters.json", parametersFile)
+	require.Len(t, commands, 1)
+	require.Equal(t, "bicep", commands[0].Command)
+	require.Equal(t, []string{"build", mainBicepFile, "--outfile", ".temp/primary-centralus/main.json"}, commands[0].Args)
+
+	data, err := afero.ReadFile(fs, filepath.Join("/steps/network", parametersFile))
+	require.NoError(t, err)
+
+	params := struct {
+		Parameters map[string]struct {
+			Value interface{} `json:"value"`
+		} `json:"parameters"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &params))
+
+	require.Len(t, params.Parameters, 4, "Only declared parameters with values should be passed")
+	require.Equal(t, "centralus", params.Parameters["runiac_region"].Value)
+	require.Equal(t, "vnet", params.Parameters["network_vnet_id"].Value)
+	require.Equal(t, float64(3), params.Parameters["instanceCount"].Value, "Parameters should match case insensitively and be typed")
+	require.Equal(t, map[string]interface{}{"team": "runiac"}, params.Parameters["tags"].Value)
+}
+
+func TestPrepareTemplate_ShouldFallBackToAzureCLI(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	commands := []shell.Command{}
+
+	runBicep = stubBuild(fs, &commands)
+	bicepInstalled = func(string) bool { return false }
+	defer func() {
+		runBicep = shell.RunShellCommandAndGetAndStreamOutput
+		bicepInstalled = shell.CommandInstalled
+	}()
+
+	_, err := prepareTemplate(stubExecution(fs))
+
+	require.NoError(t, err)
+	require.Equal(t, "az", commands[0].Command)
+	require.Equal(t, []string{"bicep", "build", "--file", mainBicepFile, "--outfile", ".temp/primary-centralus/main.json"}, commands[0].Args)
+}
+
+func TestExecuteStep_ShouldFailWhenBuildFails(t *testing.T) {
+	runBicep = func(shell.Command) (string, error) { return "", errors.New("BCP018: Expected the \"=\" character") }
+	bicepInstalled = func(string) bool { return true }
+	defer func() {
+		runBicep = shell.RunShellCommandAndGetAndStreamOutput
+		bicepInstalled = shell.CommandInstalled
+	}()
+
+	output := BicepStepper{}.ExecuteStep(stubExecution(afero.NewMemMapFs()))
+
+	require.Equal(t, config.Fail, output.Status)
+	require.Error(t, output.Err)
+}
+
+func TestDetect_ShouldMatchMainBicep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/main.bicep", []byte{}, 0644))
+
+	require.True(t, Detect(fs, "/steps/network"))
+	require.False(t, Detect(fs, "/steps/other"))
+}
+
+func TestExecuteStepOutputs_ShouldFailWhenTemplateCannotBeCompiled(t *testing.T) {
+	runBicep = func(shell.Command) (string, error) { return "", errors.New("bicep failed") }
+	bicepInstalled = func(string) bool { return true }
+	defer func() {
+		runBicep = shell.RunShellCommandAndGetAndStreamOutput
+		bicepInstalled = shell.CommandInstalled
+	}()
+
+	output := BicepStepper{}.ExecuteStepOutputs(stubExecution(afero.NewMemMapFs()))
+
+	require.EqualError(t, output.Err, "bicep failed")
+	require.Equal(t, config.Fail, output.Status)
+}
@@ -0,0 +1,103 @@
+package plugins_cloudformation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+)
+
+var runAWS = shell.RunShellCommandAndGetAndStreamOutput
+var runAWSAndGetOutput = shell.RunShellCommandAndGetOutput
+
+type templateSummary struct {
+	Parameters []struct {
+		ParameterKey string `json:"ParameterKey"`
+	} `json:"Parameters"`
+}
+
+type parameter struct {
+	ParameterKey   string `json:"ParameterKey"`
+	ParameterValue string `json:"ParameterValue"`
+}
+
+type stacks struct {
+	Stacks []stack `json:"Stacks"`
+}
+
+type stack struct {
+	StackName   string `json:"StackName"`
+	StackStatus string `json:"StackStatus"`
+	Outputs     []struct {
+		OutputKey   string `json:"OutputKey"`
+		OutputValue string `json:"OutputValue"`
+	} `json:"Outputs"`
+}
+
+type changeSet struct {
+	Status       string `json:"Status"`
+	StatusReason string `json:"StatusReason"`
+	Changes      []struct {
+		ResourceChange struct {
+			Action            string `json:"Action"` // Add, Modify, Remove, Import or Dynamic
+			LogicalResourceId string `json:"LogicalResourceId"`
+			ResourceType      string `json:"ResourceType"`
+			Replacement       string `json:"Replacement"` // True, False or Conditional
+		} `json:"ResourceChange"`
+	} `json:"Changes"`
+}
+
+// awsCommand runs a cloudformation subcommand of the aws cli in the step's region
+func awsCommand(exec config.StepExecution, args ...string) shell.Command {
+	return shell.Command{
+		Command:    "aws",
+		Args:       append(append([]string{"cloudformation"}, args...), "--region", exec.Region, "--output", "json"),
+		WorkingDir: exec.Dir,
+		Env: map[string]string{
+			"AWS_PAGER": "",
+		},
+		Logger:         exec.Logger.WithField("cloudformation", args[0]),
+		NonInteractive: true,
+	}
+}
+
+// runAWSJSON runs a cloudformation subcommand and unmarshals its output into v
+func runAWSJSON(exec config.StepExecution, v interface{}, args ...string) error {
+	resp, err := runAWSAndGetOutput(awsCommand(exec, args...))
+	if err != nil {
+		return fmt.Errorf("aws cloudformation %s failed: %w: %s", args[0], err, strings.TrimSpace(resp))
+	}
+
+	err = json.Unmarshal([]byte(resp), v)
+	if err != nil {
+		return fmt.Errorf("unable to read aws cloudformation %s output: %w", args[0], err)
+	}
+
+	return nil
+}
+
+// describeStack returns the step's stack, or nil when it does not exist
+func describeStack(exec config.StepExecution, stackName string) (*stack, error) {
+	resp, err := runAWSAndGetOutput(awsCommand(exec, "describe-stacks", "--stack-name", stackName))
+	if err != nil {
+		if strings.Contains(resp, "does not exist") {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("aws cloudformation describe-stacks failed: %w: %s", err, strings.TrimSpace(resp))
+	}
+
+	described := stacks{}
+	err = json.Unmarshal([]byte(resp), &described)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read aws cloudformation describe-stacks output: %w", err)
+	}
+
+	if len(described.Stacks) == 0 {
+		return nil, nil
+	}
+
+	return &described.Stacks[0], nil
+}
@@ -0,0 +1,45 @@
+package plugins_cloudformation
+
+import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// RunnerName is the runner configuration value that selects the cloudformation runner
+const RunnerName = "cloudformation"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return CloudFormationStepper{} },
+		Plugin:     CloudFormationPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains a cloudformation template
+func Detect(fs afero.Fs, dir string) bool {
+	exists, err := afero.Exists(fs, filepath.Join(dir, TemplateFile))
+	return err == nil && exists
+}
+
+type CloudFormationPlugin struct{}
+
+func (info CloudFormationPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac cloudformation plugin")
+
+	out, err := shell.RunShellCommandAndGetOutput(shell.Command{
+		Command: "aws",
+		Args:    []string{"--version"},
+		Logger:  logger.WithField("CloudFormationPlugin", "info"),
+	})
+	if err != nil {
+		logger.Warn("Unable to print aws cli version")
+	} else {
+		logger.Info("Binary: ", out)
+	}
+}
@@ -0,0 +1,366 @@
+package plugins_cloudformation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+)
+
+// TemplateFile is the cloudformation template deployed by a step
+const TemplateFile = "template.yaml"
+
+// CloudFormationStepper deploys a step's template as a stack per region
+type CloudFormationStepper struct{}
+
+var invalidStackNameChars = regexp.MustCompile(`[^A-Za-z0-9-]`)
+var invalidParameterChars = regexp.MustCompile(`[^a-z0-9]`)
+
+// plannedActions maps change set actions to the actions terraform reports, so change summaries read the same
+var plannedActions = map[string]string{
+	"Add":     "[create]",
+	"Modify":  "[update]",
+	"Remove":  "[delete]",
+	"Import":  "[import]",
+	"Dynamic": "[update]",
+}
+
+func (stepper CloudFormationStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStep creates a change set for the step's stack, executing it unless this is a dry run
+func (stepper CloudFormationStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	switch exec.Action {
+	case config.ApplyAction:
+		output.Err = errors.New("plan bundles are not supported by the cloudformation runner")
+		exec.Logger.WithError(output.Err).Error("Unable to apply plan bundle")
+		return
+	case config.PlanAction:
+		exec.Logger.Warn("Plan bundles are not supported by the cloudformation runner, the change set will not be saved")
+	case config.DriftAction:
+		exec.Logger.Warn("Drift detection is not supported by the cloudformation runner")
+		output.Status = config.Na
+		return
+	case config.UnlockAction:
+		exec.Logger.Warn("State locks are not managed by the cloudformation runner")
+		output.Status = config.Na
+		return
+	}
+
+	stackName := createStackName(exec)
+
+	existing, err := describeStack(exec, stackName)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to describe stack")
+		return
+	}
+
+	// a stack left in review by a previous change set has never been created
+	changeSetType := "CREATE"
+	if existing != nil && existing.StackStatus != "REVIEW_IN_PROGRESS" {
+		changeSetType = "UPDATE"
+	}
+
+	parameters, err := getStackParameters(exec)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to read template parameters")
+		return
+	}
+
+	changeSetName := fmt.Sprintf("runiac-%d", time.Now().Unix())
+
+	_, output.Err = runAWS(awsCommand(exec, "create-change-set",
+		"--stack-name", stackName,
+		"--change-set-name", changeSetName,
+		"--change-set-type", changeSetType,
+		"--template-body", fmt.Sprintf("file://%s", TemplateFile),
+		"--parameters", parameters,
+		"--capabilities", "CAPABILITY_IAM", "CAPABILITY_NAMED_IAM", "CAPABILITY_AUTO_EXPAND"))
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Failed to create change set")
+		return
+	}
+
+	changes, err := waitForChangeSet(exec, stackName, changeSetName)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Failed to create change set")
+		return
+	}
+
+	output.PlannedChanges = getPlannedChanges(changes)
+	for action, resources := range output.PlannedChanges {
+		exec.Logger.Infof("%s: %s", action, strings.Join(resources, ", "))
+	}
+
+	noChanges := len(changes.Changes) == 0
+
+	if exec.DryRun || noChanges {
+		_, output.Err = runAWS(awsCommand(exec, "delete-change-set", "--stack-name", stackName, "--change-set-name", changeSetName))
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("Failed to delete change set")
+			return
+		}
+	}
+
+	if exec.DryRun {
+		exec.Logger.Info("---------- Skipping change set execution, this is a dry run ---------- ")
+
+		// remove the empty stack the change set was created in
+		if changeSetType == "CREATE" {
+			output.Err = deleteStack(exec, stackName)
+			if output.Err != nil {
+				return
+			}
+		}
+
+		output.Status = config.Success
+		return
+	}
+
+	if noChanges {
+		exec.Logger.Info("Stack is up to date")
+	} else {
+		_, output.Err = runAWS(awsCommand(exec, "execute-change-set", "--stack-name", stackName, "--change-set-name", changeSetName))
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("Failed to execute change set")
+			return
+		}
+
+		_, output.Err = runAWS(awsCommand(exec, "wait", fmt.Sprintf("stack-%s-complete", strings.ToLower(changeSetType)), "--stack-name", stackName))
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("Stack did not complete successfully")
+			return
+		}
+	}
+
+	output.OutputVariables, output.Err = getStackOutputs(exec, stackName)
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepDestroy deletes the step's stack
+func (stepper CloudFormationStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	stackName := createStackName(exec)
+
+	existing, err := describeStack(exec, stackName)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to describe stack")
+		return
+	}
+
+	if existing == nil {
+		exec.Logger.Warnf("Stack %s does not exist, nothing to destroy", stackName)
+		output.Status = config.Na
+		return
+	}
+
+	if exec.DryRun {
+		exec.Logger.Infof("---------- Skipping delete of stack %s, this is a dry run ---------- ", stackName)
+		output.Status = config.Success
+		return
+	}
+
+	output.Err = deleteStack(exec, stackName)
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepOutputs reads the outputs of the step's stack
+func (stepper CloudFormationStepper) ExecuteStepOutputs(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	output.OutputVariables, output.Err = getStackOutputs(exec, createStackName(exec))
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepTests executes the tests for a step
+func (stepper CloudFormationStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	output.StepName = exec.StepName
+	exec.Logger.Debug("Tests are not supported by the cloudformation runner")
+	return
+}
+
+// createStackName names the step's stack per namespace, region deploy type and region, like the terraform workspaces,
+// within the characters stack names allow
+func createStackName(exec config.StepExecution) string {
+	stack := fmt.Sprintf("%s-%s", exec.RegionDeployType.String(), exec.Region)
+
+	if exec.Namespace != "" {
+		stack = fmt.Sprintf("%s-%s", exec.Namespace, stack)
+	}
+
+	name := invalidStackNameChars.ReplaceAllString(fmt.Sprintf("runiac-%s-%s-%s-%s", exec.Project, exec.TrackName, exec.StepName, stack), "-")
+
+	if len(name) > 128 {
+		name = name[:128]
+	}
+
+	return name
+}
+
+// GetStackParams returns the runiac parameters and upstream outputs that can be passed to a template
+func GetStackParams(exec config.StepExecution) map[string]string {
+	params := map[string]string{}
+	for k, v := range exec.OptionalStepParams {
+		params[k] = v
+	}
+
+	coreAccountIDs := map[string]string{}
+	for k, v := range exec.CoreAccounts {
+		coreAccountIDs[k] = v.ID
+	}
+
+	if len(coreAccountIDs) > 0 {
+		ids, _ := json.Marshal(coreAccountIDs)
+		params["runiac_core_account_ids_map"] = string(ids)
+	}
+
+	params["runiac_account_id"] = exec.AccountID
+	params["runiac_region"] = exec.Region
+	params["runiac_app_version"] = exec.AppVersion
+	params["runiac_namespace"] = exec.Namespace
+	params["runiac_environment"] = exec.Environment
+
+	return params
+}
+
+// getPlannedChanges summarizes a change set by action, e.g. K=[create] V=[Bucket]
+func getPlannedChanges(changes changeSet) map[string][]string {
+	planned := map[string][]string{}
+
+	for _, change := range changes.Changes {
+		resource := change.ResourceChange
+
+		action, ok := plannedActions[resource.Action]
+		if !ok {
+			action = fmt.Sprintf("[%s]", strings.ToLower(resource.Action))
+		}
+
+		if resource.Action == "Modify" && resource.Replacement == "True" {
+			action = "[delete create]"
+		}
+
+		planned[action] = append(planned[action], resource.LogicalResourceId)
+	}
+
+	return planned
+}
+
+func newOutput(exec config.StepExecution) config.StepOutput {
+	return config.StepOutput{
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		Status:           config.Fail, // assume failure
+	}
+}
+
+// getStackParameters returns the parameters for the template as the JSON the aws cli accepts.
+// Parameter names may only be alphanumeric, so they match runiac parameters ignoring case and other characters,
+// e.g. the NetworkVnetId parameter receives the vnet_id output of the network step.
+func getStackParameters(exec config.StepExecution) (string, error) {
+	summary := templateSummary{}
+	err := runAWSJSON(exec, &summary, "get-template-summary", "--template-body", fmt.Sprintf("file://%s", TemplateFile))
+	if err != nil {
+		return "", err
+	}
+
+	params := map[string]string{}
+	for k, v := range GetStackParams(exec) {
+		params[invalidParameterChars.ReplaceAllString(strings.ToLower(k), "")] = v
+	}
+
+	parameters := []parameter{}
+	for _, declared := range summary.Parameters {
+		if v, ok := params[strings.ToLower(declared.ParameterKey)]; ok {
+			parameters = append(parameters, parameter{ParameterKey: declared.ParameterKey, ParameterValue: v})
+		}
+	}
+
+	data, err := json.Marshal(parameters)
+	return string(data), err
+}
+
+// waitForChangeSet waits for the change set to be created, a change set without changes is not an error
+func waitForChangeSet(exec config.StepExecution, stackName string, changeSetName string) (changeSet, error) {
+	_, waitErr := runAWS(awsCommand(exec, "wait", "change-set-create-complete", "--stack-name", stackName, "--change-set-name", changeSetName))
+
+	changes := changeSet{}
+	err := runAWSJSON(exec, &changes, "describe-change-set", "--stack-name", stackName, "--change-set-name", changeSetName)
+	if err != nil {
+		return changes, err
+	}
+
+	if changes.Status == "FAILED" {
+		if strings.Contains(changes.StatusReason, "didn't contain changes") || strings.Contains(changes.StatusReason, "No updates are to be performed") {
+			changes.Changes = nil
+			return changes, nil
+		}
+
+		return changes, fmt.Errorf("change set %s failed: %s", changeSetName, changes.StatusReason)
+	}
+
+	return changes, waitErr
+}
+
+func deleteStack(exec config.StepExecution, stackName string) error {
+	_, err := runAWS(awsCommand(exec, "delete-stack", "--stack-name", stackName))
+	if err != nil {
+		exec.Logger.WithError(err).Error("Failed to delete stack")
+		return err
+	}
+
+	_, err = runAWS(awsCommand(exec, "wait", "stack-delete-complete", "--stack-name", stackName))
+	if err != nil {
+		exec.Logger.WithError(err).Error("Stack was not deleted successfully")
+	}
+
+	return err
+}
+
+// getStackOutputs reads the outputs of the step's stack
+func getStackOutputs(exec config.StepExecution, stackName string) (map[string]interface{}, error) {
+	outputs := map[string]interface{}{}
+
+	existing, err := describeStack(exec, stackName)
+	if err == nil && existing == nil {
+		err = fmt.Errorf("stack %s does not exist", stackName)
+	}
+
+	if err != nil {
+		exec.Logger.WithError(err).Error("Unable to read stack outputs")
+		return outputs, err
+	}
+
+	for _, o := range existing.Outputs {
+		outputs[o.OutputKey] = o.OutputValue
+	}
+
+	return outputs, nil
+}
@@ -0,0 +1,175 @@
+package plugins_cloudformation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = logrus.NewEntry(logrus.New())
+
+// fakeAWS records every call in $FAKE_AWS_LOG. The stack exists when $FAKE_STACK_STATUS is set
+// and change sets are empty when $FAKE_NO_CHANGES is set.
+const fakeAWS = `#!/bin/sh
+echo "$@" >> "$FAKE_AWS_LOG"
+case "$2 $3" in
+"describe-stacks "*)
+	if [ -z "$FAKE_STACK_STATUS" ]; then
+		echo "An error occurred (ValidationError) when calling the DescribeStacks operation: Stack with id $4 does not exist" >&2
+		exit 254
+	fi
+	echo '{"Stacks":[{"StackName":"'$4'","StackStatus":"'$FAKE_STACK_STATUS'","Outputs":[{"OutputKey":"BucketName","OutputValue":"logs"}]}]}'
+	;;
+"get-template-summary "*)
+	echo '{"Parameters":[{"ParameterKey":"NetworkVnetId"},{"ParameterKey":"RuniacRegion"},{"ParameterKey":"Unset"}]}'
+	;;
+"wait change-set-create-complete")
+	[ -z "$FAKE_NO_CHANGES" ] || exit 255
+	;;
+"describe-change-set "*)
+	if [ -n "$FAKE_NO_CHANGES" ]; then
+		echo '{"Status":"FAILED","StatusReason":"The submitted information didn'"'"'t contain changes. Submit different information to create a change set."}'
+	else
+		echo '{"Status":"CREATE_COMPLETE","Changes":[{"ResourceChange":{"Action":"Add","LogicalResourceId":"Bucket"}},{"ResourceChange":{"Action":"Modify","LogicalResourceId":"Role","Replacement":"True"}},{"ResourceChange":{"Action":"Modify","LogicalResourceId":"Policy","Replacement":"False"}}]}'
+	fi
+	;;
+esac
+`
+
+// stubAWS puts the fake aws binary first on the PATH and returns a func reading the calls made to it
+func stubAWS(t *testing.T, stackStatus string, noChanges bool) func() []string {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "aws"), []byte(fakeAWS), 0755))
+
+	logFile := filepath.Join(binDir, "calls.log")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_AWS_LOG", logFile)
+	t.Setenv("FAKE_STACK_STATUS", stackStatus)
+	t.Setenv("FAKE_NO_CHANGES", "")
+	if noChanges {
+		t.Setenv("FAKE_NO_CHANGES", "true")
+	}
+
+	return func() []string {
+		data, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+// subcommands returns the cloudformation subcommand of each call, e.g. "wait stack-update-complete" or "describe-stacks"
+func subcommands(calls []string) []string {
+	commands := []string{}
+	for _, call := range calls {
+		fields := strings.Fields(call)
+		if fields[1] == "wait" {
+			commands = append(commands, strings.Join(fields[1:3], " "))
+		} else {
+			commands = append(commands, fields[1])
+		}
+	}
+
+	return commands
+}
+
+func stubExecution(t *testing.T) config.StepExecution {
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewOsFs(),
+		Dir:              t.TempDir(),
+		Project:          "runiac",
+		TrackName:        "core",
+		StepName:         "logging_bucket",
+		Region:           "us-east-1",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Action:           config.DeployAction,
+		OptionalStepParams: map[string]string{
+			"network-vnet_id": "vnet",
+		},
+	}
+}
+
+func TestCreateStackName_ShouldOnlyUseValidCharacters(t *testing.T) {
+	require.Equal(t, "runiac-runiac-core-logging-bucket-primary-us-east-1", createStackName(stubExecution(t)))
+}
+
+func TestCreateStackName_ShouldIncludeNamespaceAndRegionDeployType(t *testing.T) {
+	exec := stubExecution(t)
+	exec.Namespace = "feature_1"
+	exec.RegionDeployType = config.RegionalRegionDeployType
+
+	require.Equal(t, "runiac-runiac-core-logging-bucket-feature-1-regional-us-east-1", createStackName(exec))
+}
+
+func TestExecuteStep_ShouldExecuteChangeSetAndReturnOutputs(t *testing.T) {
+	calls := stubAWS(t, "CREATE_COMPLETE", false)
+
+	output := CloudFormationStepper{}.ExecuteStep(stubExecution(t))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, map[string]interface{}{"BucketName": "logs"}, output.OutputVariables)
+	require.Equal(t, map[string][]string{"[create]": {"Bucket"}, "[delete create]": {"Role"}, "[update]": {"Policy"}}, output.PlannedChanges)
+
+	recorded := calls()
+	require.Equal(t, []string{
+		"describe-stacks", "get-template-summary", "create-change-set", "wait change-set-create-complete",
+		"describe-change-set", "execute-change-set", "wait stack-update-complete", "describe-stacks",
+	}, subcommands(recorded))
+	require.Contains(t, recorded[2], "--change-set-type UPDATE")
+	require.Contains(t, recorded[2], `--parameters [{"ParameterKey":"NetworkVnetId","ParameterValue":"vnet"},{"ParameterKey":"RuniacRegion","ParameterValue":"us-east-1"}]`)
+	require.Contains(t, recorded[2], "--region us-east-1")
+}
+
+func TestExecuteStep_ShouldOnlyCreateChangeSetWhenDryRun(t *testing.T) {
+	calls := stubAWS(t, "", false)
+	exec := stubExecution(t)
+	exec.DryRun = true
+
+	output := CloudFormationStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Len(t, output.PlannedChanges["[create]"], 1)
+
+	recorded := calls()
+	require.Equal(t, []string{
+		"describe-stacks", "get-template-summary", "create-change-set", "wait change-set-create-complete",
+		"describe-change-set", "delete-change-set", "delete-stack", "wait stack-delete-complete",
+	}, subcommands(recorded), "The stack created for the change set should be removed")
+	require.Contains(t, recorded[2], "--change-set-type CREATE")
+}
+
+func TestExecuteStep_ShouldSucceedWithoutChanges(t *testing.T) {
+	calls := stubAWS(t, "UPDATE_COMPLETE", true)
+
+	output := CloudFormationStepper{}.ExecuteStep(stubExecution(t))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Empty(t, output.PlannedChanges)
+	require.NotContains(t, subcommands(calls()), "execute-change-set")
+}
+
+func TestExecuteStepDestroy_ShouldDeleteStack(t *testing.T) {
+	calls := stubAWS(t, "CREATE_COMPLETE", false)
+
+	output := CloudFormationStepper{}.ExecuteStepDestroy(stubExecution(t))
+
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, []string{"describe-stacks", "delete-stack", "wait stack-delete-complete"}, subcommands(calls()))
+}
+
+func TestExecuteStepDestroy_ShouldBeNaWithoutStack(t *testing.T) {
+	stubAWS(t, "", false)
+
+	output := CloudFormationStepper{}.ExecuteStepDestroy(stubExecution(t))
+
+	require.Equal(t, config.Na, output.Status)
+}
@@ -0,0 +1,45 @@
+package plugins_helm
+
+import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// RunnerName is the runner configuration value that selects the helm runner
+const RunnerName = "helm"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return HelmStepper{} },
+		Plugin:     HelmPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains a chart
+func Detect(fs afero.Fs, dir string) bool {
+	exists, err := afero.Exists(fs, filepath.Join(dir, ChartFile))
+	return err == nil && exists
+}
+
+type HelmPlugin struct{}
+
+func (info HelmPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac helm plugin")
+
+	out, err := shell.RunShellCommandAndGetOutput(shell.Command{
+		Command: "helm",
+		Args:    []string{"version", "--short"},
+		Logger:  logger.WithField("HelmPlugin", "info"),
+	})
+	if err != nil {
+		logger.Warn("Unable to print helm version")
+	} else {
+		logger.Info("Binary: ", out)
+	}
+}
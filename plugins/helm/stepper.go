@@ -0,0 +1,414 @@
+package plugins_helm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/spf13/afero"
+)
+
+const (
+	ChartFile       = "Chart.yaml"         // Marks a step directory as a chart
+	runiacValuesKey = "runiac"             // Top-level key of the generated values
+	valuesFileName  = "runiac-values.json" // Generated values, written to the execution's temp directory
+)
+
+// HelmStepper installs a chart into the cluster of each region
+type HelmStepper struct{}
+
+// StepConfig is read from the helm section of the step's runiac.yml, every setting is optional
+type StepConfig struct {
+	Chart        string            `mapstructure:"chart"`         // The chart to install, defaults to the step directory
+	Version      string            `mapstructure:"version"`       // The version of a chart from a repository
+	Release      string            `mapstructure:"release"`       // The release name, defaults to the step name, region deploy type and region
+	Namespace    string            `mapstructure:"namespace"`     // The kubernetes namespace, created when missing
+	KubeContext  string            `mapstructure:"kube_context"`  // The kubeconfig context of the clusters without their own kube_contexts entry
+	KubeContexts map[string]string `mapstructure:"kube_contexts"` // The kubeconfig context of each region's cluster, keyed by region
+}
+
+var runHelm = shell.RunShellCommandAndGetAndStreamOutput
+var runHelmAndGetOutput = shell.RunShellCommandAndGetOutput
+
+// errReleaseNotFound is returned when the step's release is not installed
+var errReleaseNotFound = errors.New("release not found")
+
+var invalidValuesKeyChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+var invalidReleaseChars = regexp.MustCompile(`[^a-z0-9-]`)
+
+func (stepper HelmStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStep installs or upgrades the step's release. Dry runs show a diff when the helm-diff plugin is installed.
+func (stepper HelmStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	switch exec.Action {
+	case config.ApplyAction:
+		output.Err = errors.New("plan bundles are not supported by the helm runner")
+		exec.Logger.WithError(output.Err).Error("Unable to apply plan bundle")
+		return
+	case config.PlanAction:
+		exec.Logger.Warn("Plan bundles are not supported by the helm runner, the dry run will not be saved")
+	case config.DriftAction:
+		exec.Logger.Warn("Drift detection is not supported by the helm runner")
+		output.Status = config.Na
+		return
+	case config.UnlockAction:
+		exec.Logger.Warn("State locks are not managed by the helm runner")
+		output.Status = config.Na
+		return
+	}
+
+	stepConfig, err := ReadStepConfig(exec)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to read helm step configuration")
+		return
+	}
+
+	valuesFiles, err := getValuesFiles(exec)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to write values file")
+		return
+	}
+
+	args := []string{"upgrade", stepConfig.Release, stepConfig.Chart, "--install"}
+	for _, file := range valuesFiles {
+		args = append(args, "--values", file)
+	}
+
+	if stepConfig.Version != "" {
+		args = append(args, "--version", stepConfig.Version)
+	}
+
+	args = append(args, stepConfig.args()...)
+
+	if exec.DryRun {
+		if helmDiffInstalled(exec) {
+			args = append([]string{"diff"}, args...)
+		} else {
+			exec.Logger.Info("Install the helm-diff plugin to see the changes of a dry run")
+			args = append(args, "--dry-run")
+		}
+
+		output.StreamOutput, output.Err = runHelm(helmCommand(exec, "dry-run", args...))
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("Error during helm dry run")
+			return
+		}
+
+		output.Status = config.Success
+		return
+	}
+
+	if stepConfig.Namespace != "" {
+		args = append(args, "--create-namespace")
+	}
+
+	output.StreamOutput, output.Err = runHelm(helmCommand(exec, "upgrade", append(args, "--wait")...))
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error during helm upgrade")
+		return
+	}
+
+	output.OutputVariables, output.Err = getReleaseOutputs(exec, stepConfig)
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepDestroy uninstalls the step's release
+func (stepper HelmStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	stepConfig, err := ReadStepConfig(exec)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to read helm step configuration")
+		return
+	}
+
+	if _, err := getReleaseOutputs(exec, stepConfig); errors.Is(err, errReleaseNotFound) {
+		exec.Logger.Warnf("Release %s is not installed, nothing to destroy", stepConfig.Release)
+		output.Status = config.Na
+		return
+	} else if err != nil {
+		output.Err = err
+		return
+	}
+
+	if exec.DryRun {
+		exec.Logger.Infof("---------- Skipping uninstall of release %s, this is a dry run ---------- ", stepConfig.Release)
+		output.Status = config.Success
+		return
+	}
+
+	args := append([]string{"uninstall", stepConfig.Release, "--wait"}, stepConfig.args()...)
+
+	output.StreamOutput, output.Err = runHelm(helmCommand(exec, "uninstall", args...))
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error during helm uninstall")
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepOutputs returns the name, namespace and revision of the step's release
+func (stepper HelmStepper) ExecuteStepOutputs(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	stepConfig, err := ReadStepConfig(exec)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to read helm step configuration")
+		return
+	}
+
+	output.OutputVariables, output.Err = getReleaseOutputs(exec, stepConfig)
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepTests runs the chart's tests against the step's release
+func (stepper HelmStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	output.StepName = exec.StepName
+
+	stepConfig, err := ReadStepConfig(exec)
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to read helm step configuration")
+		return
+	}
+
+	args := append([]string{"test", stepConfig.Release, "--logs"}, stepConfig.args()...)
+
+	_ = retry.DoWithRetry(fmt.Sprintf("execute tests: %s", exec.Dir), exec.MaxTestRetries, 20*time.Second, exec.Logger, func(retryCount int) error {
+		cmd := helmCommand(exec, "test", args...)
+		cmd.Logger = cmd.Logger.WithField("retryCount", retryCount)
+
+		output.StreamOutput, output.Err = runHelm(cmd)
+
+		return output.Err
+	})
+
+	return
+}
+
+// ReadStepConfig reads the helm section of the step's runiac.yml and applies the defaults
+func ReadStepConfig(exec config.StepExecution) (StepConfig, error) {
+	stepConfig := StepConfig{}
+
+	v, err := config.ReadStepConfig(exec.Fs, exec.Dir)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	err = v.UnmarshalKey("helm", &stepConfig)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	if stepConfig.Chart == "" {
+		stepConfig.Chart = "."
+	}
+
+	if stepConfig.Release == "" {
+		stepConfig.Release = createReleaseName(exec)
+	}
+
+	if kubeContext, ok := stepConfig.KubeContexts[strings.ToLower(exec.Region)]; ok {
+		stepConfig.KubeContext = kubeContext
+	}
+
+	return stepConfig, nil
+}
+
+// GetValues returns the runiac parameters and upstream outputs, which are available to templates as .Values.runiac,
+// e.g. the cluster_endpoint output of the cluster step becomes .Values.runiac.cluster_cluster_endpoint
+func GetValues(exec config.StepExecution) map[string]interface{} {
+	params := map[string]string{}
+	for k, v := range exec.OptionalStepParams {
+		params[invalidValuesKeyChars.ReplaceAllString(k, "_")] = v
+	}
+
+	coreAccountIDs := map[string]string{}
+	for k, v := range exec.CoreAccounts {
+		coreAccountIDs[k] = v.ID
+	}
+
+	params["runiac_account_id"] = exec.AccountID
+	params["runiac_region"] = exec.Region
+	params["runiac_app_version"] = exec.AppVersion
+	params["runiac_namespace"] = exec.Namespace
+	params["runiac_environment"] = exec.Environment
+
+	values := map[string]interface{}{}
+	for k, v := range params {
+		values[k] = v
+	}
+
+	if len(coreAccountIDs) > 0 {
+		values["runiac_core_account_ids_map"] = coreAccountIDs
+	}
+
+	return map[string]interface{}{runiacValuesKey: values}
+}
+
+// args returns the flags selecting the release's cluster and namespace
+func (c StepConfig) args() []string {
+	args := []string{}
+
+	if c.Namespace != "" {
+		args = append(args, "--namespace", c.Namespace)
+	}
+
+	if c.KubeContext != "" {
+		args = append(args, "--kube-context", c.KubeContext)
+	}
+
+	return args
+}
+
+// createReleaseName names the release after the step, region deploy type and region, within the characters release names allow.
+// Long step names are shortened, so releases of different regions keep distinct names.
+func createReleaseName(exec config.StepExecution) string {
+	name := exec.StepName
+	if exec.Namespace != "" {
+		name = fmt.Sprintf("%s-%s", exec.Namespace, name)
+	}
+
+	suffix := fmt.Sprintf("-%s-%s", exec.RegionDeployType.String(), exec.Region)
+
+	name = invalidReleaseChars.ReplaceAllString(strings.ToLower(name), "-")
+	suffix = invalidReleaseChars.ReplaceAllString(strings.ToLower(suffix), "-")
+
+	if len(name)+len(suffix) > 53 {
+		name = name[:max(53-len(suffix), 0)]
+	}
+
+	return strings.Trim(name+suffix, "-")
+}
+
+func newOutput(exec config.StepExecution) config.StepOutput {
+	return config.StepOutput{
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		Status:           config.Fail, // assume failure
+	}
+}
+
+func helmCommand(exec config.StepExecution, operation string, args ...string) shell.Command {
+	return shell.Command{
+		Command:        "helm",
+		Args:           args,
+		WorkingDir:     exec.Dir,
+		Env:            map[string]string{},
+		Logger:         exec.Logger.WithField("helm", operation),
+		NonInteractive: true,
+	}
+}
+
+// valuesFilePath returns where the generated values of the execution are written, relative to the step directory. Each
+// region has its own, as the regions of a step deploy concurrently from the same directory.
+func valuesFilePath(exec config.StepExecution) string {
+	return filepath.Join(".temp", fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region), valuesFileName)
+}
+
+// getValuesFiles writes the generated values and returns it followed by the step's values files, which take precedence:
+// values.yaml, values-<environment>.yaml and values-<region>.yaml
+func getValuesFiles(exec config.StepExecution) ([]string, error) {
+	valuesFile := valuesFilePath(exec)
+
+	err := exec.Fs.MkdirAll(filepath.Join(exec.Dir, filepath.Dir(valuesFile)), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, so the generated values need no YAML encoder
+	data, err := json.MarshalIndent(GetValues(exec), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = afero.WriteFile(exec.Fs, filepath.Join(exec.Dir, valuesFile), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{valuesFile}
+	for _, file := range []string{"values.yaml", fmt.Sprintf("values-%s.yaml", exec.Environment), fmt.Sprintf("values-%s.yaml", exec.Region)} {
+		if exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, file)); exists {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// helmDiffInstalled reports whether the helm-diff plugin is installed
+func helmDiffInstalled(exec config.StepExecution) bool {
+	out, err := runHelmAndGetOutput(helmCommand(exec, "plugin", "plugin", "list"))
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "diff" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getReleaseOutputs reads the name, namespace and revision of the step's release
+func getReleaseOutputs(exec config.StepExecution, stepConfig StepConfig) (map[string]interface{}, error) {
+	args := append([]string{"status", stepConfig.Release, "--output", "json"}, stepConfig.args()...)
+
+	resp, err := runHelmAndGetOutput(helmCommand(exec, "status", args...))
+	if err != nil && strings.Contains(resp, "release: not found") {
+		return nil, fmt.Errorf("%w: %s: %w", errReleaseNotFound, stepConfig.Release, err)
+	} else if err != nil {
+		exec.Logger.WithError(err).Errorf("Error during helm status: %s", resp)
+		return nil, err
+	}
+
+	status := struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		Version   int    `json:"version"`
+	}{}
+
+	err = json.Unmarshal([]byte(resp), &status)
+	if err != nil {
+		err = fmt.Errorf("unable to read helm status: %w", err)
+		exec.Logger.WithError(err).Error(err)
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"release_name": status.Name,
+		"namespace":    status.Namespace,
+		"revision":     status.Version,
+	}, nil
+}
@@ -0,0 +1,223 @@
+package plugins_helm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = logrus.NewEntry(logrus.New())
+
+// fakeHelm records every call in $FAKE_HELM_LOG. Releases are installed when $FAKE_HELM_INSTALLED is set,
+// the helm-diff plugin is installed when $FAKE_HELM_DIFF is set and the cluster is unreachable when $FAKE_HELM_UNREACHABLE is set.
+const fakeHelm = `#!/bin/sh
+echo "$@" >> "$FAKE_HELM_LOG"
+case "$1" in
+status)
+	[ -z "$FAKE_HELM_UNREACHABLE" ] || { echo "Error: Kubernetes cluster unreachable: connection refused" >&2; exit 1; }
+	[ -n "$FAKE_HELM_INSTALLED" ] || { echo "Error: release: not found" >&2; exit 1; }
+	echo '{"name":"'$2'","namespace":"ingress","version":4}'
+	;;
+plugin)
+	echo "NAME	VERSION	DESCRIPTION"
+	[ -z "$FAKE_HELM_DIFF" ] || echo "diff	3.9.0	Preview helm upgrade changes as a diff"
+	;;
+esac
+`
+
+// stubHelm puts the fake helm binary first on the PATH and returns a func reading the calls made to it
+func stubHelm(t *testing.T, installed bool, diff bool) func() []string {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "helm"), []byte(fakeHelm), 0755))
+
+	logFile := filepath.Join(binDir, "calls.log")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_HELM_LOG", logFile)
+	t.Setenv("FAKE_HELM_INSTALLED", "")
+	t.Setenv("FAKE_HELM_DIFF", "")
+	t.Setenv("FAKE_HELM_UNREACHABLE", "")
+
+	if installed {
+		t.Setenv("FAKE_HELM_INSTALLED", "true")
+	}
+
+	if diff {
+		t.Setenv("FAKE_HELM_DIFF", "true")
+	}
+
+	return func() []string {
+		data, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func stubExecution(t *testing.T, files map[string]string) config.StepExecution {
+	dir := t.TempDir()
+
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewOsFs(),
+		Dir:              dir,
+		StepName:         "ingress_controller",
+		Namespace:        "pr-1",
+		Environment:      "dev",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Action:           config.DeployAction,
+		OptionalStepParams: map[string]string{
+			"cluster-endpoint": "https://cluster",
+		},
+	}
+}
+
+func TestExecuteStep_ShouldUpgradeWithValuesFiles(t *testing.T) {
+	calls := stubHelm(t, true, false)
+	exec := stubExecution(t, map[string]string{
+		ChartFile:         "name: ingress",
+		"values-dev.yaml": "replicas: 1",
+		"runiac.yml":      "runner: helm\nhelm:\n  namespace: ingress\n  kube_context: centralus\n",
+	})
+
+	output := HelmStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, map[string]interface{}{"release_name": "pr-1-ingress-controller-primary-centralus", "namespace": "ingress", "revision": 4}, output.OutputVariables)
+
+	recorded := calls()
+	require.Equal(t, []string{
+		"upgrade pr-1-ingress-controller-primary-centralus . --install --values .temp/primary-centralus/runiac-values.json --values values-dev.yaml --namespace ingress --kube-context centralus --create-namespace --wait",
+		"status pr-1-ingress-controller-primary-centralus --output json --namespace ingress --kube-context centralus",
+	}, recorded)
+
+	data, err := os.ReadFile(filepath.Join(exec.Dir, valuesFilePath(exec)))
+	require.NoError(t, err)
+
+	values := map[string]map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &values))
+	require.Equal(t, "https://cluster", values["runiac"]["cluster_endpoint"])
+	require.Equal(t, "centralus", values["runiac"]["runiac_region"])
+}
+
+func TestExecuteStep_ShouldDiffWhenDryRun(t *testing.T) {
+	calls := stubHelm(t, false, true)
+	exec := stubExecution(t, map[string]string{ChartFile: "name: ingress"})
+	exec.DryRun = true
+
+	output := HelmStepper{}.ExecuteStep(exec)
+
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "diff upgrade pr-1-ingress-controller-primary-centralus . --install --values .temp/primary-centralus/runiac-values.json", calls()[1])
+}
+
+func TestExecuteStep_ShouldDryRunWithoutDiffPlugin(t *testing.T) {
+	calls := stubHelm(t, false, false)
+	exec := stubExecution(t, map[string]string{ChartFile: "name: ingress"})
+	exec.DryRun = true
+
+	output := HelmStepper{}.ExecuteStep(exec)
+
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "upgrade pr-1-ingress-controller-primary-centralus . --install --values .temp/primary-centralus/runiac-values.json --dry-run", calls()[1])
+}
+
+func TestExecuteStepDestroy_ShouldUninstallRelease(t *testing.T) {
+	calls := stubHelm(t, true, false)
+
+	output := HelmStepper{}.ExecuteStepDestroy(stubExecution(t, map[string]string{}))
+
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "uninstall pr-1-ingress-controller-primary-centralus --wait", calls()[1])
+}
+
+func TestExecuteStepDestroy_ShouldBeNaWithoutRelease(t *testing.T) {
+	calls := stubHelm(t, false, false)
+
+	output := HelmStepper{}.ExecuteStepDestroy(stubExecution(t, map[string]string{}))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Na, output.Status)
+	require.Equal(t, []string{"status pr-1-ingress-controller-primary-centralus --output json"}, calls())
+}
+
+func TestExecuteStepDestroy_ShouldFailWhenReleaseStatusIsUnknown(t *testing.T) {
+	calls := stubHelm(t, true, false)
+	t.Setenv("FAKE_HELM_UNREACHABLE", "true")
+
+	output := HelmStepper{}.ExecuteStepDestroy(stubExecution(t, map[string]string{}))
+
+	require.Error(t, output.Err)
+	require.Equal(t, config.Fail, output.Status)
+	require.Len(t, calls(), 1, "The release should not be uninstalled")
+}
+
+func TestGetValuesFiles_ShouldWriteValuesOfEachRegion(t *testing.T) {
+	exec := stubExecution(t, map[string]string{})
+	regional := exec
+	regional.Region = "eastus"
+	regional.RegionDeployType = config.RegionalRegionDeployType
+
+	primaryFiles, err := getValuesFiles(exec)
+	require.NoError(t, err)
+	regionalFiles, err := getValuesFiles(regional)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{".temp/primary-centralus/runiac-values.json"}, primaryFiles)
+	require.Equal(t, []string{".temp/regional-eastus/runiac-values.json"}, regionalFiles)
+
+	data, err := os.ReadFile(filepath.Join(exec.Dir, primaryFiles[0]))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"runiac_region": "centralus"`)
+}
+
+func TestExecuteStepTests_ShouldRunHelmTest(t *testing.T) {
+	calls := stubHelm(t, true, false)
+	exec := stubExecution(t, map[string]string{
+		"runiac.yml": "helm:\n  release: ingress\n",
+	})
+
+	output := HelmStepper{}.ExecuteStepTests(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, []string{"test ingress --logs"}, calls())
+}
+
+func TestReadStepConfig_ShouldUseKubeContextOfRegion(t *testing.T) {
+	exec := stubExecution(t, map[string]string{
+		"runiac.yml": "helm:\n  kube_context: default\n  kube_contexts:\n    eastus: aks-eastus\n",
+	})
+
+	stepConfig, err := ReadStepConfig(exec)
+	require.NoError(t, err)
+	require.Equal(t, "default", stepConfig.KubeContext)
+
+	exec.Region = "eastus"
+	exec.RegionDeployType = config.RegionalRegionDeployType
+
+	stepConfig, err = ReadStepConfig(exec)
+	require.NoError(t, err)
+	require.Equal(t, "aks-eastus", stepConfig.KubeContext)
+	require.Equal(t, "pr-1-ingress-controller-regional-eastus", stepConfig.Release)
+}
+
+func TestCreateReleaseName_ShouldKeepRegionWhenShortened(t *testing.T) {
+	exec := stubExecution(t, map[string]string{})
+	exec.StepName = strings.Repeat("a", 60)
+
+	name := createReleaseName(exec)
+
+	require.Len(t, name, 53)
+	require.True(t, strings.HasSuffix(name, "-primary-centralus"))
+}
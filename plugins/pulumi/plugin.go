@@ -0,0 +1,51 @@
+package plugins_pulumi
+
+import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// RunnerName is the runner configuration value that selects the pulumi runner
+const RunnerName = "pulumi"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return PulumiStepper{} },
+		Plugin:     PulumiPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains a pulumi project
+func Detect(fs afero.Fs, dir string) bool {
+	for _, file := range []string{ProjectFile, "Pulumi.yml"} {
+		if exists, err := afero.Exists(fs, filepath.Join(dir, file)); err == nil && exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+type PulumiPlugin struct{}
+
+func (info PulumiPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac pulumi plugin")
+
+	out, err := shell.RunShellCommandAndGetOutput(shell.Command{
+		Command: "pulumi",
+		Args:    []string{"version"},
+		Logger:  logger.WithField("PulumiPlugin", "info"),
+		Env:     map[string]string{"PULUMI_SKIP_UPDATE_CHECK": "true"},
+	})
+	if err != nil {
+		logger.Warn("Unable to print pulumi version")
+	} else {
+		logger.Info("Binary: ", out)
+	}
+}
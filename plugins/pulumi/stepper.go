@@ -0,0 +1,245 @@
+package plugins_pulumi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+)
+
+// ProjectFile marks a step directory as a pulumi project
+const ProjectFile = "Pulumi.yaml"
+
+// PulumiStepper deploys a step's pulumi project to a stack per region
+type PulumiStepper struct{}
+
+var runPulumi = shell.RunShellCommandAndGetAndStreamOutput
+var runPulumiAndGetOutput = shell.RunShellCommandAndGetOutput
+
+func (stepper PulumiStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStep previews the step's stack for dry runs, otherwise updates it and returns the stack outputs
+func (stepper PulumiStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	switch exec.Action {
+	case config.ApplyAction:
+		output.Err = errors.New("plan bundles are not supported by the pulumi runner")
+		exec.Logger.WithError(output.Err).Error("Unable to apply plan bundle")
+		return
+	case config.PlanAction:
+		exec.Logger.Warn("Plan bundles are not supported by the pulumi runner, the preview will not be saved")
+	case config.DriftAction:
+		exec.Logger.Warn("Drift detection is not supported by the pulumi runner")
+		output.Status = config.Na
+		return
+	case config.UnlockAction:
+		exec.Logger.Warn("State locks are not managed by the pulumi runner")
+		output.Status = config.Na
+		return
+	}
+
+	stack, err := initStack(exec)
+	if err != nil {
+		output.Err = err
+		return
+	}
+
+	if exec.DryRun {
+		output.StreamOutput, output.Err = runPulumi(pulumiCommand(exec, "preview", "preview", "--stack", stack, "--diff"))
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("Error during pulumi preview")
+			return
+		}
+
+		output.Status = config.Success
+		return
+	}
+
+	output.StreamOutput, output.Err = runPulumi(pulumiCommand(exec, "up", "up", "--stack", stack, "--yes", "--skip-preview"))
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error during pulumi up")
+		return
+	}
+
+	output.OutputVariables, output.Err = getStackOutputs(exec, stack)
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepDestroy destroys the resources of the step's stack
+func (stepper PulumiStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	stack, err := initStack(exec)
+	if err != nil {
+		output.Err = err
+		return
+	}
+
+	args := []string{"destroy", "--stack", stack, "--yes"}
+	if exec.DryRun {
+		args = []string{"destroy", "--stack", stack, "--preview-only"}
+	}
+
+	output.StreamOutput, output.Err = runPulumi(pulumiCommand(exec, "destroy", args...))
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error during pulumi destroy")
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepOutputs reads the outputs of the step's stack
+func (stepper PulumiStepper) ExecuteStepOutputs(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	stack, err := initStack(exec)
+	if err != nil {
+		output.Err = err
+		return
+	}
+
+	output.OutputVariables, output.Err = getStackOutputs(exec, stack)
+	if output.Err != nil {
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepTests executes the tests for a step
+func (stepper PulumiStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	output.StepName = exec.StepName
+	exec.Logger.Debug("Tests are not supported by the pulumi runner")
+	return
+}
+
+// GetStackName returns the step's stack, named like the terraform workspaces, e.g. namespace-primary-centralus
+func GetStackName(exec config.StepExecution) string {
+	stack := fmt.Sprintf("%s-%s", exec.RegionDeployType.String(), exec.Region)
+
+	if exec.Namespace != "" {
+		stack = fmt.Sprintf("%s-%s", exec.Namespace, stack)
+	}
+
+	return stack
+}
+
+// GetStackConfig returns the runiac parameters and upstream outputs that are set as stack config
+func GetStackConfig(exec config.StepExecution) map[string]string {
+	params := map[string]string{}
+	for k, v := range exec.OptionalStepParams {
+		params[k] = v
+	}
+
+	for k, v := range exec.RequiredStepParams {
+		params[k] = fmt.Sprintf("%v", v)
+	}
+
+	coreAccountIDs := map[string]string{}
+	for k, v := range exec.CoreAccounts {
+		coreAccountIDs[k] = v.ID
+	}
+
+	if len(coreAccountIDs) > 0 {
+		ids, _ := json.Marshal(coreAccountIDs)
+		params["runiac_core_account_ids_map"] = string(ids)
+	}
+
+	params["runiac_account_id"] = exec.AccountID
+	params["runiac_region"] = exec.Region
+	params["runiac_app_version"] = exec.AppVersion
+	params["runiac_namespace"] = exec.Namespace
+	params["runiac_environment"] = exec.Environment
+
+	return params
+}
+
+func newOutput(exec config.StepExecution) config.StepOutput {
+	return config.StepOutput{
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		Status:           config.Fail, // assume failure
+	}
+}
+
+func pulumiCommand(exec config.StepExecution, operation string, args ...string) shell.Command {
+	return shell.Command{
+		Command:    "pulumi",
+		Args:       append(args, "--non-interactive"),
+		WorkingDir: exec.Dir,
+		Env: map[string]string{
+			"PULUMI_SKIP_UPDATE_CHECK": "true",
+		},
+		Logger:         exec.Logger.WithField("pulumi", operation),
+		NonInteractive: true,
+	}
+}
+
+// initStack selects the step's stack, creating it on first deploy, and sets its config
+func initStack(exec config.StepExecution) (string, error) {
+	stack := GetStackName(exec)
+
+	_, err := runPulumi(pulumiCommand(exec, "stack", "stack", "select", stack, "--create"))
+	if err != nil {
+		exec.Logger.WithError(err).Error("Error during pulumi stack select")
+		return stack, err
+	}
+
+	params := GetStackConfig(exec)
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := []string{"config", "set-all", "--stack", stack}
+	for _, k := range keys {
+		args = append(args, "--plaintext", fmt.Sprintf("%s=%s", k, params[k]))
+	}
+
+	cmd := pulumiCommand(exec, "config", args...)
+	cmd.SensitiveArgs = true
+
+	_, err = runPulumi(cmd)
+	if err != nil {
+		exec.Logger.WithError(err).Error("Error during pulumi config set-all")
+		return stack, err
+	}
+
+	return stack, nil
+}
+
+// getStackOutputs reads the stack outputs, preserving their types
+func getStackOutputs(exec config.StepExecution, stack string) (map[string]interface{}, error) {
+	outputs := map[string]interface{}{}
+
+	resp, err := runPulumiAndGetOutput(pulumiCommand(exec, "output", "stack", "output", "--stack", stack, "--json"))
+	if err != nil {
+		exec.Logger.WithError(err).Error("Error during pulumi stack output")
+		return outputs, err
+	}
+
+	err = json.Unmarshal([]byte(resp), &outputs)
+	if err != nil {
+		err = fmt.Errorf("unable to read pulumi stack outputs: %w", err)
+		exec.Logger.WithError(err).Error(err)
+	}
+
+	return outputs, err
+}
@@ -0,0 +1,130 @@
+package plugins_pulumi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = logrus.NewEntry(logrus.New())
+
+// fakePulumi records every call in $FAKE_PULUMI_LOG, prints stack outputs and fails the command named by $FAKE_PULUMI_FAIL
+const fakePulumi = `#!/bin/sh
+echo "$@" >> "$FAKE_PULUMI_LOG"
+if [ "$1" = "$FAKE_PULUMI_FAIL" ]; then
+	echo "error: $1 failed" >&2
+	exit 1
+fi
+if [ "$1 $2" = "stack output" ]; then
+	echo '{"vnet_id":"vnet","instances":3,"tags":{"team":"runiac"}}'
+fi
+`
+
+// stubPulumi puts the fake pulumi binary first on the PATH and returns a func reading the calls made to it
+func stubPulumi(t *testing.T, fail string) func() []string {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "pulumi"), []byte(fakePulumi), 0755))
+
+	logFile := filepath.Join(binDir, "calls.log")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_PULUMI_LOG", logFile)
+	t.Setenv("FAKE_PULUMI_FAIL", fail)
+
+	return func() []string {
+		data, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func stubExecution(t *testing.T) config.StepExecution {
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewOsFs(),
+		Dir:              t.TempDir(),
+		StepName:         "network",
+		Namespace:        "pr-1",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Action:           config.DeployAction,
+		OptionalStepParams: map[string]string{
+			"core-vnet_id": "vnet",
+		},
+	}
+}
+
+func TestGetStackName_ShouldMatchTerraformWorkspaces(t *testing.T) {
+	exec := stubExecution(t)
+
+	require.Equal(t, "pr-1-primary-centralus", GetStackName(exec))
+
+	exec.Namespace = ""
+	exec.RegionDeployType = config.RegionalRegionDeployType
+	require.Equal(t, "regional-centralus", GetStackName(exec))
+}
+
+func TestExecuteStep_ShouldUpdateStackAndReturnOutputs(t *testing.T) {
+	calls := stubPulumi(t, "")
+
+	output := PulumiStepper{}.ExecuteStep(stubExecution(t))
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, map[string]interface{}{"vnet_id": "vnet", "instances": float64(3), "tags": map[string]interface{}{"team": "runiac"}}, output.OutputVariables)
+
+	recorded := calls()
+	require.Len(t, recorded, 4)
+	require.Equal(t, "stack select pr-1-primary-centralus --create --non-interactive", recorded[0])
+	require.Contains(t, recorded[1], "config set-all --stack pr-1-primary-centralus")
+	require.Contains(t, recorded[1], "--plaintext core-vnet_id=vnet")
+	require.Contains(t, recorded[1], "--plaintext runiac_region=centralus")
+	require.Equal(t, "up --stack pr-1-primary-centralus --yes --skip-preview --non-interactive", recorded[2])
+	require.Equal(t, "stack output --stack pr-1-primary-centralus --json --non-interactive", recorded[3])
+}
+
+func TestExecuteStep_ShouldPreviewWhenDryRun(t *testing.T) {
+	calls := stubPulumi(t, "")
+	exec := stubExecution(t)
+	exec.DryRun = true
+
+	output := PulumiStepper{}.ExecuteStep(exec)
+
+	require.Equal(t, config.Success, output.Status)
+
+	recorded := calls()
+	require.Len(t, recorded, 3, "Dry runs should not update the stack")
+	require.Equal(t, "preview --stack pr-1-primary-centralus --diff --non-interactive", recorded[2])
+}
+
+func TestExecuteStep_ShouldFailWhenUpFails(t *testing.T) {
+	stubPulumi(t, "up")
+
+	output := PulumiStepper{}.ExecuteStep(stubExecution(t))
+
+	require.Error(t, output.Err)
+	require.Equal(t, config.Fail, output.Status)
+	require.Contains(t, output.StreamOutput, "error: up failed")
+}
+
+func TestExecuteStepDestroy_ShouldDestroyStack(t *testing.T) {
+	calls := stubPulumi(t, "")
+
+	output := PulumiStepper{}.ExecuteStepDestroy(stubExecution(t))
+
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "destroy --stack pr-1-primary-centralus --yes --non-interactive", calls()[2])
+}
+
+func TestDetect_ShouldMatchPulumiProject(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/Pulumi.yaml", []byte("name: network"), 0644))
+
+	require.True(t, Detect(fs, "/steps/network"))
+	require.False(t, Detect(fs, "/steps/other"))
+}
@@ -0,0 +1,33 @@
+package plugins_shell
+
+import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// RunnerName is the runner configuration value that selects the shell runner
+const RunnerName = "shell"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return ShellStepper{} },
+		Plugin:     ShellPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains a deploy script
+func Detect(fs afero.Fs, dir string) bool {
+	exists, err := afero.Exists(fs, filepath.Join(dir, DeployScript))
+	return err == nil && exists
+}
+
+type ShellPlugin struct{}
+
+func (info ShellPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac shell plugin")
+}
@@ -0,0 +1,251 @@
+package plugins_shell
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/spf13/afero"
+)
+
+const (
+	DeployScript  = "deploy.sh"  // Deploys the step
+	DestroyScript = "destroy.sh" // Destroys the step, optional
+	TestScript    = "test.sh"    // Tests the step, optional
+	OutputsScript = "outputs.sh" // Writes the outputs of a previously deployed step without changing anything, optional
+
+	// OutputFileEnv is the environment variable holding the path of the JSON file a script writes its outputs to
+	OutputFileEnv = "RUNIAC_OUTPUT_FILE"
+)
+
+// ShellStepper executes the scripts within a step directory
+type ShellStepper struct{}
+
+var runScript = shell.RunShellCommandAndGetAndStreamOutput
+
+var invalidEnvChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+func (stepper ShellStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStep runs deploy.sh
+func (stepper ShellStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	switch exec.Action {
+	case config.ApplyAction:
+		output.Err = errors.New("plan bundles are not supported by the shell runner")
+		exec.Logger.WithError(output.Err).Error("Unable to apply plan bundle")
+		return
+	case config.PlanAction:
+		exec.Logger.Warn("Plan bundles are not supported by the shell runner, nothing will be saved")
+	case config.DriftAction:
+		exec.Logger.Warn("Drift detection is not supported by the shell runner")
+		output.Status = config.Na
+		return
+	case config.UnlockAction:
+		exec.Logger.Warn("State locks are not managed by the shell runner")
+		output.Status = config.Na
+		return
+	}
+
+	if exec.DryRun {
+		exec.Logger.Infof("---------- Skipping %s, this is a dry run ---------- ", DeployScript)
+		output.Status = config.Success
+		return
+	}
+
+	return runStepScript(exec, DeployScript, output)
+}
+
+// ExecuteStepDestroy runs destroy.sh, steps without one have nothing to destroy
+func (stepper ShellStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	if !scriptExists(exec, DestroyScript) {
+		exec.Logger.Warnf("Step has no %s, nothing to destroy", DestroyScript)
+		output.Status = config.Na
+		return
+	}
+
+	if exec.DryRun {
+		exec.Logger.Infof("---------- Skipping %s, this is a dry run ---------- ", DestroyScript)
+		output.Status = config.Success
+		return
+	}
+
+	return runStepScript(exec, DestroyScript, output)
+}
+
+// ExecuteStepOutputs runs outputs.sh, steps without one have no outputs
+func (stepper ShellStepper) ExecuteStepOutputs(exec config.StepExecution) (output config.StepOutput) {
+	output = newOutput(exec)
+
+	if !scriptExists(exec, OutputsScript) {
+		exec.Logger.Warnf("Step has no %s, outputs of previous deployments are not available", OutputsScript)
+		output.Status = config.Success
+		output.OutputVariables = map[string]interface{}{}
+		return
+	}
+
+	return runStepScript(exec, OutputsScript, output)
+}
+
+// ExecuteStepTests runs test.sh
+func (stepper ShellStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	output.StepName = exec.StepName
+
+	if !scriptExists(exec, TestScript) {
+		exec.Logger.Debugf("Step has no %s", TestScript)
+		return
+	}
+
+	_ = retry.DoWithRetry(fmt.Sprintf("execute tests: %s", exec.Dir), exec.MaxTestRetries, 20*time.Second, exec.Logger, func(retryCount int) error {
+		cmd := scriptCommand(exec, TestScript, GetShellEnvVars(exec))
+		cmd.Logger = exec.Logger.WithField("retryCount", retryCount)
+
+		output.StreamOutput, output.Err = runScript(cmd)
+
+		return output.Err
+	})
+
+	return
+}
+
+// GetShellEnvVars returns the step parameters as environment variables, e.g. runiac_project becomes RUNIAC_PROJECT
+// and the output id of step network becomes NETWORK_ID
+func GetShellEnvVars(exec config.StepExecution) map[string]string {
+	params := map[string]string{}
+	for k, v := range exec.OptionalStepParams {
+		params[k] = v
+	}
+
+	for k, v := range exec.RequiredStepParams {
+		params[k] = fmt.Sprintf("%v", v)
+	}
+
+	coreAccountIDs := map[string]string{}
+	for k, v := range exec.CoreAccounts {
+		coreAccountIDs[k] = v.ID
+	}
+
+	if len(coreAccountIDs) > 0 {
+		ids, _ := json.Marshal(coreAccountIDs)
+		params["runiac_core_account_ids_map"] = string(ids)
+	}
+
+	params["runiac_account_id"] = exec.AccountID
+	params["runiac_region"] = exec.Region
+	params["runiac_app_version"] = exec.AppVersion
+	params["runiac_namespace"] = exec.Namespace
+	params["runiac_environment"] = exec.Environment
+	params["runiac_action"] = exec.Action
+	params["runiac_dry_run"] = fmt.Sprintf("%v", exec.DryRun)
+
+	envVars := map[string]string{}
+	for k, v := range params {
+		envVars[invalidEnvChars.ReplaceAllString(strings.ToUpper(k), "_")] = v
+	}
+
+	return envVars
+}
+
+func newOutput(exec config.StepExecution) config.StepOutput {
+	return config.StepOutput{
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		Status:           config.Fail, // assume failure
+	}
+}
+
+func scriptExists(exec config.StepExecution, script string) bool {
+	exists, err := afero.Exists(exec.Fs, filepath.Join(exec.Dir, script))
+	return err == nil && exists
+}
+
+// scriptCommand runs executable scripts directly so their shebang is honored, others are run with sh
+func scriptCommand(exec config.StepExecution, script string, envVars map[string]string) shell.Command {
+	cmd := shell.Command{
+		Command:        "sh",
+		Args:           []string{script},
+		WorkingDir:     exec.Dir,
+		Env:            envVars,
+		Logger:         exec.Logger.WithField("shell", script),
+		NonInteractive: true,
+	}
+
+	if info, err := exec.Fs.Stat(filepath.Join(exec.Dir, script)); err == nil && info.Mode()&0111 != 0 {
+		cmd.Command = fmt.Sprintf("./%s", script)
+		cmd.Args = []string{}
+	}
+
+	return cmd
+}
+
+// runStepScript runs a script and reads the outputs it writes to $RUNIAC_OUTPUT_FILE
+func runStepScript(exec config.StepExecution, script string, output config.StepOutput) config.StepOutput {
+	if !scriptExists(exec, script) {
+		output.Err = fmt.Errorf("step has no %s", script)
+		exec.Logger.WithError(output.Err).Error(output.Err)
+		return output
+	}
+
+	outputFile, err := afero.TempFile(exec.Fs, "", "runiac-output-*.json")
+	if err != nil {
+		output.Err = err
+		exec.Logger.WithError(err).Error("Unable to create output file")
+		return output
+	}
+	_ = outputFile.Close()
+	defer func() { _ = exec.Fs.Remove(outputFile.Name()) }()
+
+	envVars := GetShellEnvVars(exec)
+	envVars[OutputFileEnv] = outputFile.Name()
+
+	cmd := scriptCommand(exec, script, envVars)
+	output.StreamOutput, output.Err = runScript(cmd)
+
+	if output.Err != nil {
+		cmd.Logger.WithError(output.Err).Errorf("Error running %s", script)
+		return output
+	}
+
+	output.OutputVariables, output.Err = readOutputFile(exec.Fs, outputFile.Name())
+
+	if output.Err != nil {
+		cmd.Logger.WithError(output.Err).Errorf("Unable to read the outputs written by %s to $%s", script, OutputFileEnv)
+		return output
+	}
+
+	output.Status = config.Success
+
+	return output
+}
+
+// readOutputFile reads the JSON object of outputs written by a script, scripts writing nothing have no outputs
+func readOutputFile(fs afero.Fs, path string) (map[string]interface{}, error) {
+	outputs := map[string]interface{}{}
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil && !os.IsNotExist(err) {
+		return outputs, err
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return outputs, nil
+	}
+
+	err = json.Unmarshal(data, &outputs)
+
+	return outputs, err
+}
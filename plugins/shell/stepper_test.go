@@ -0,0 +1,116 @@
+package plugins_shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var logger = logrus.NewEntry(logrus.New())
+
+func stubExecution(t *testing.T, scripts map[string]string) config.StepExecution {
+	dir := t.TempDir()
+
+	for name, script := range scripts {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0644))
+	}
+
+	return config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewOsFs(),
+		Dir:              dir,
+		StepName:         "seed",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Action:           config.DeployAction,
+		OptionalStepParams: map[string]string{
+			"runiac_project":  "runiac",
+			"network-vnet_id": "vnet",
+		},
+	}
+}
+
+func TestGetShellEnvVars_ShouldUppercaseParams(t *testing.T) {
+	t.Parallel()
+
+	vars := GetShellEnvVars(config.StepExecution{
+		Environment:        "fun",
+		OptionalStepParams: map[string]string{"network-vnet_id": "vnet"},
+		CoreAccounts:       map[string]config.Account{"core": {ID: "1"}},
+	})
+
+	require.Equal(t, "fun", vars["RUNIAC_ENVIRONMENT"])
+	require.Equal(t, "vnet", vars["NETWORK_VNET_ID"])
+	require.Equal(t, `{"core":"1"}`, vars["RUNIAC_CORE_ACCOUNT_IDS_MAP"])
+}
+
+func TestExecuteStep_ShouldReadOutputsFromOutputFile(t *testing.T) {
+	t.Parallel()
+
+	exec := stubExecution(t, map[string]string{
+		DeployScript: `printf '{"project":"%s","vnet":"%s"}' "$RUNIAC_PROJECT" "$NETWORK_VNET_ID" > "$RUNIAC_OUTPUT_FILE"`,
+	})
+
+	output := ShellStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, map[string]interface{}{"project": "runiac", "vnet": "vnet"}, output.OutputVariables)
+}
+
+func TestExecuteStep_ShouldFailWhenScriptFails(t *testing.T) {
+	t.Parallel()
+
+	exec := stubExecution(t, map[string]string{
+		DeployScript: `echo "seeding failed" && exit 3`,
+	})
+
+	output := ShellStepper{}.ExecuteStep(exec)
+
+	require.Error(t, output.Err)
+	require.Equal(t, config.Fail, output.Status)
+	require.Contains(t, output.StreamOutput, "seeding failed")
+}
+
+func TestExecuteStep_ShouldNotRunDeployScriptWhenDryRun(t *testing.T) {
+	t.Parallel()
+
+	exec := stubExecution(t, map[string]string{
+		DeployScript: `touch deployed`,
+	})
+	exec.DryRun = true
+
+	output := ShellStepper{}.ExecuteStep(exec)
+
+	require.Equal(t, config.Success, output.Status)
+	require.NoFileExists(t, filepath.Join(exec.Dir, "deployed"))
+}
+
+func TestExecuteStepDestroy_ShouldBeNaWithoutDestroyScript(t *testing.T) {
+	t.Parallel()
+
+	exec := stubExecution(t, map[string]string{DeployScript: `true`})
+
+	output := ShellStepper{}.ExecuteStepDestroy(exec)
+
+	require.Equal(t, config.Na, output.Status)
+}
+
+func TestExecuteStepTests_ShouldRunTestScript(t *testing.T) {
+	t.Parallel()
+
+	exec := stubExecution(t, map[string]string{
+		TestScript: "#!/bin/sh\necho \"testing $RUNIAC_PROJECT\"\n",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(exec.Dir, TestScript), 0755))
+
+	output := ShellStepper{}.ExecuteStepTests(exec)
+
+	require.NoError(t, output.Err)
+	require.Contains(t, output.StreamOutput, "testing runiac")
+}
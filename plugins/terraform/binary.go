@@ -0,0 +1,139 @@
+package plugins_terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/sirupsen/logrus"
+)
+
+// binaryVersionPattern matches the version printed by terraform version and tofu version, e.g. Terraform v1.5.7
+var binaryVersionPattern = regexp.MustCompile(`v(\d+\.\d+\.\d+\S*)`)
+
+type resolvedBinary struct {
+	path string
+	err  error
+}
+
+var (
+	resolvedBinariesMutex sync.Mutex
+	resolvedBinaries      = map[string]resolvedBinary{}
+)
+
+// ResolveBinary returns the binary a step runs, e.g. tofu or <cache dir>/terraform/1.5.7/terraform, after verifying it
+// satisfies the step's version constraint. Each binary is only resolved and verified once.
+func ResolveBinary(logger *logrus.Entry, binary string, constraint string, cacheDir string) (string, error) {
+	if binary == "" {
+		binary = TerraformBinary
+	}
+
+	key := strings.Join([]string{binary, constraint, cacheDir}, "\x00")
+
+	resolvedBinariesMutex.Lock()
+	defer resolvedBinariesMutex.Unlock()
+
+	if resolved, ok := resolvedBinaries[key]; ok {
+		return resolved.path, resolved.err
+	}
+
+	path, err := resolveBinary(logger, binary, constraint, cacheDir)
+	resolvedBinaries[key] = resolvedBinary{path: path, err: err}
+
+	return path, err
+}
+
+func resolveBinary(logger *logrus.Entry, binary string, constraint string, cacheDir string) (string, error) {
+	var constraints version.Constraints
+	if constraint != "" {
+		var err error
+		constraints, err = version.NewConstraint(constraint)
+		if err != nil {
+			return binary, fmt.Errorf("terraform.version %q is not a valid version constraint: %w", constraint, err)
+		}
+	}
+
+	path := binary
+	if cacheDir != "" {
+		if cached, ok := findCachedBinary(binary, constraints, cacheDir); ok {
+			path = cached
+		} else {
+			logger.Warnf("No %s binary satisfying %q was found in %s, using %s from the PATH", binary, constraint, cacheDir, binary)
+		}
+	}
+
+	if constraints == nil {
+		return path, nil
+	}
+
+	binaryVersion, err := getBinaryVersion(logger, path)
+	if err != nil {
+		return path, err
+	}
+
+	if !constraints.Check(binaryVersion) {
+		return path, fmt.Errorf("%s is version %s, which does not satisfy the required version %s", path, binaryVersion, constraint)
+	}
+
+	return path, nil
+}
+
+// findCachedBinary returns the newest <cacheDir>/<binary>/<version>/<binary> satisfying constraints
+func findCachedBinary(binary string, constraints version.Constraints, cacheDir string) (string, bool) {
+	entries, err := os.ReadDir(filepath.Join(cacheDir, binary))
+	if err != nil {
+		return "", false
+	}
+
+	var newest *version.Version
+	path := ""
+
+	for _, entry := range entries {
+		v, err := version.NewVersion(entry.Name())
+		if err != nil || !entry.IsDir() || (constraints != nil && !constraints.Check(v)) {
+			continue
+		}
+
+		candidate := filepath.Join(cacheDir, binary, entry.Name(), binary)
+		if info, err := os.Stat(candidate); err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+			path = candidate
+		}
+	}
+
+	return path, newest != nil
+}
+
+// getBinaryVersion runs the binary's version command and parses the version it prints
+func getBinaryVersion(logger *logrus.Entry, binary string) (*version.Version, error) {
+	resp, err := terraformer.Version(&terraform.Options{
+		TerraformBinary: binary,
+		TerraformDir:    ".",
+		EnvVars: map[string]string{
+			"CHECKPOINT_DISABLE": "true",
+		},
+		Logger:             logger.WithField("terraform", "version"),
+		NoColor:            true,
+		MaxRetries:         1,
+		TimeBetweenRetries: 0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to run %s version: %w", binary, err)
+	}
+
+	match := binaryVersionPattern.FindStringSubmatch(resp)
+	if match == nil {
+		return nil, fmt.Errorf("unable to find the version of %s in: %s", binary, resp)
+	}
+
+	return version.NewVersion(match[1])
+}
@@ -0,0 +1,63 @@
+package plugins_terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCachedBinary writes a fake tofu printing binaryVersion to <cacheDir>/tofu/<binaryVersion>/tofu
+func stubCachedBinary(t *testing.T, cacheDir string, binaryVersion string) string {
+	dir := filepath.Join(cacheDir, "tofu", binaryVersion)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	path := filepath.Join(dir, "tofu")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho \"OpenTofu v"+binaryVersion+"\"\necho \"on linux_amd64\"\n"), 0755))
+
+	return path
+}
+
+func TestResolveBinary_ShouldUseNewestCachedVersionSatisfyingConstraint(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	cacheDir := t.TempDir()
+
+	stubCachedBinary(t, cacheDir, "1.5.7")
+	expected := stubCachedBinary(t, cacheDir, "1.6.2")
+	stubCachedBinary(t, cacheDir, "1.7.0")
+
+	binary, err := ResolveBinary(logger, "tofu", ">= 1.6, < 1.7", cacheDir)
+
+	require.NoError(t, err)
+	require.Equal(t, expected, binary)
+}
+
+func TestResolveBinary_ShouldFailWhenVersionIsNotSatisfied(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	binDir := t.TempDir()
+	stubCachedBinary(t, binDir, "1.5.7")
+	t.Setenv("PATH", filepath.Join(binDir, "tofu", "1.5.7"))
+
+	_, err := ResolveBinary(logger, "tofu", ">= 1.6", "")
+
+	require.EqualError(t, err, "tofu is version 1.5.7, which does not satisfy the required version >= 1.6")
+}
+
+func TestResolveBinary_ShouldRejectInvalidConstraints(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+
+	_, err := ResolveBinary(logger, "", "not a version", "")
+
+	require.ErrorContains(t, err, `terraform.version "not a version" is not a valid version constraint`)
+}
+
+func TestResolveBinary_ShouldDefaultToTerraformWithoutConstraint(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+
+	binary, err := ResolveBinary(logger, "", "", "")
+
+	require.NoError(t, err)
+	require.Equal(t, "terraform", binary, "The binary should not be run without a version constraint")
+}
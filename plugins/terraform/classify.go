@@ -0,0 +1,52 @@
+package plugins_terraform
+
+import (
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/sirupsen/logrus"
+)
+
+// classifyError records the classification of a failed terraform command's error in the step output
+func classifyError(tfOptions *terraform.Options, out string, err error, output *config.StepOutput) terraform.ErrorClassification {
+	classification := terraform.ClassifyError(tfOptions, out, err)
+
+	output.ErrorClass = &config.ErrorClassification{
+		Class:    string(classification.Class),
+		Category: classification.Category,
+		Pattern:  classification.Pattern,
+	}
+
+	return classification
+}
+
+// retryIfTransient classifies the error of a failed terraform command, returning it so the command is retried when the
+// error is transient and marking it non-retryable to stop retrying otherwise
+func retryIfTransient(tfOptions *terraform.Options, out string, err error, output *config.StepOutput) error {
+	classification := classifyError(tfOptions, out, err, output)
+
+	logger := tfOptions.Logger.WithFields(logrus.Fields{
+		"errorClass":    classification.Class,
+		"errorCategory": classification.Category,
+	})
+
+	if !classification.Retryable() {
+		logger.Warnf("Not retrying %s error", classification.Class)
+		return retry.NonRetryable(err)
+	}
+
+	logger.Infof("Retrying transient %s error", classification.Category)
+
+	return err
+}
+
+// toTerraformErrors converts configured errors, a regexp by category, to terraform options' errors, a category by regexp
+func toTerraformErrors(errs map[string]string) map[string]string {
+	terraformErrors := map[string]string{}
+
+	for category, expr := range errs {
+		terraformErrors[expr] = category
+	}
+
+	return terraformErrors
+}
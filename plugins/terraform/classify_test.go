@@ -0,0 +1,115 @@
+package plugins_terraform
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+type stubInitTerraformer struct {
+	terraform.Terraform
+	failures []string
+	inits    int
+}
+
+func (s *stubInitTerraformer) Init(options *terraform.Options) (string, error) {
+	s.inits++
+
+	if len(s.failures) >= s.inits {
+		return s.failures[s.inits-1], errors.New("exit status 1")
+	}
+
+	return "", nil
+}
+
+func (s *stubInitTerraformer) WorkspaceSelect(options *terraform.Options, workspace string) (string, error) {
+	return "", nil
+}
+
+func TestInitTerraformWorkspace_ShouldOnlyRetryTransientErrors(t *testing.T) {
+	retrySleep = 0
+	defer func() {
+		terraformer = terraform.Terraform{}
+	}()
+
+	exec := config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewMemMapFs(),
+		Dir:              "/network",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		MaxRetries:       3,
+	}
+	require.NoError(t, afero.WriteFile(exec.Fs, "/network/runiac.yml", []byte("terraform:\n  permanent_errors:\n    quota: QuotaExceeded\n"), 0644))
+
+	stepConfig, err := ReadStepConfig(exec)
+	require.NoError(t, err)
+
+	// provider downloads are retried
+	stub := &stubInitTerraformer{failures: []string{"Error: Failed to install provider\n\nconnection reset by peer"}}
+	terraformer = stub
+
+	output := config.StepOutput{}
+	_, err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	require.NoError(t, err)
+	require.Equal(t, 2, stub.inits)
+	require.Nil(t, output.ErrorClass, "The classification of a retried error should not be reported once init succeeds")
+
+	// invalid credentials are not
+	stub = &stubInitTerraformer{failures: []string{"Error: error configuring S3 Backend: InvalidClientTokenId", "", ""}}
+	terraformer = stub
+
+	output = config.StepOutput{}
+	_, err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	require.Error(t, err)
+	require.Equal(t, 1, stub.inits)
+	require.Equal(t, &config.ErrorClassification{Class: "permanent", Category: "authentication", Pattern: output.ErrorClass.Pattern}, output.ErrorClass)
+
+	// nor are errors configured as permanent
+	stub = &stubInitTerraformer{failures: []string{"Error: creating Storage Account: QuotaExceeded, StatusCode=503"}}
+	terraformer = stub
+
+	output = config.StepOutput{}
+	_, err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	require.Error(t, err)
+	require.Equal(t, 1, stub.inits)
+	require.Equal(t, &config.ErrorClassification{Class: "permanent", Category: "quota", Pattern: "QuotaExceeded"}, output.ErrorClass)
+}
+
+func TestInitTerraformWorkspace_ShouldStopRetryingWhenTheRunIsStopped(t *testing.T) {
+	retrySleep = maxRetrySleep
+	defer func() {
+		retrySleep = 0
+		terraformer = terraform.Terraform{}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	exec := config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewMemMapFs(),
+		Dir:              "/network",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		MaxRetries:       3,
+		Context:          ctx,
+	}
+
+	stub := &stubInitTerraformer{failures: []string{"Error: Failed to install provider\n\nconnection reset by peer", "", ""}}
+	terraformer = stub
+
+	output := config.StepOutput{}
+	_, err := initTerraformWorkspace(exec, StepConfig{}, &output)
+
+	require.Error(t, err)
+	require.Equal(t, 1, stub.inits, "Transient errors should not be retried once the run is stopped")
+}
@@ -0,0 +1,110 @@
+package plugins_terraform
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+)
+
+const (
+	TerraformBinary = "terraform" // Run steps with Terraform
+	TofuBinary      = "tofu"      // Run steps with OpenTofu
+)
+
+// StepConfig is the terraform section of the project's runiac.yml, which the terraform section of a step's runiac.yml
+// overrides. Every setting is optional.
+type StepConfig struct {
+	Binary          string            `mapstructure:"binary"`            // The binary run by the terraform runner (terraform or tofu), defaults to terraform
+	Version         string            `mapstructure:"version"`           // A constraint the binary must satisfy, e.g. ">= 1.5, < 2.0"
+	CacheDir        string            `mapstructure:"cache_dir"`         // Binaries are resolved from <dir>/<binary>/<version>/<binary> before the PATH
+	LockTimeout     time.Duration     `mapstructure:"lock_timeout"`      // How long terraform waits for a held state lock before failing, fails immediately when zero
+	UnlockOlderThan time.Duration     `mapstructure:"unlock_older_than"` // The unlock action only releases runiac state locks held longer than this
+	RetryableErrors map[string]string `mapstructure:"retryable_errors"`  // Additional errors that are retried, a regexp by category, e.g. dns: "Temporary failure in name resolution"
+	PermanentErrors map[string]string `mapstructure:"permanent_errors"`  // Additional errors that are never retried, a regexp by category
+
+	// VarFiles replaces the default var files, see GetVarFiles. Paths are relative to the step directory and can
+	// reference ${var.runiac_environment}, ${var.runiac_deployment_ring}, ${var.runiac_region} and the other runiac variables.
+	VarFiles []string `mapstructure:"var_files"`
+}
+
+var (
+	projectConfigMutex sync.RWMutex
+	projectConfig      = defaultProjectConfig()
+)
+
+func defaultProjectConfig() StepConfig {
+	return StepConfig{
+		UnlockOlderThan: time.Hour,
+	}
+}
+
+// ReadProjectConfig reads the terraform section of the project's runiac.yml, which can also be set by environment
+// variables, e.g. RUNIAC_TERRAFORM_LOCK_TIMEOUT
+func ReadProjectConfig() (StepConfig, error) {
+	stepConfig := defaultProjectConfig()
+
+	err := config.ReadRunnerConfig(RunnerName, &stepConfig)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	return stepConfig, stepConfig.validate()
+}
+
+// setProjectConfig sets the settings every step starts from
+func setProjectConfig(stepConfig StepConfig) {
+	projectConfigMutex.Lock()
+	defer projectConfigMutex.Unlock()
+
+	projectConfig = stepConfig
+}
+
+// ReadStepConfig reads the terraform section of the step's runiac.yml on top of the project's
+func ReadStepConfig(exec config.StepExecution) (StepConfig, error) {
+	projectConfigMutex.RLock()
+	stepConfig := projectConfig
+	projectConfigMutex.RUnlock()
+
+	// the step's errors are added to the project's without changing them
+	stepConfig.RetryableErrors = maps.Clone(stepConfig.RetryableErrors)
+	stepConfig.PermanentErrors = maps.Clone(stepConfig.PermanentErrors)
+
+	v, err := config.ReadStepConfig(exec.Fs, exec.Dir)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	err = v.UnmarshalKey(RunnerName, &stepConfig)
+	if err != nil {
+		return stepConfig, err
+	}
+
+	return stepConfig, stepConfig.validate()
+}
+
+// validate reports the first setting that is not supported
+func (c StepConfig) validate() error {
+	switch c.Binary {
+	case "", TerraformBinary, TofuBinary:
+	default:
+		return fmt.Errorf("terraform.binary %q is not supported, use %s or %s", c.Binary, TerraformBinary, TofuBinary)
+	}
+
+	if c.UnlockOlderThan <= 0 {
+		return fmt.Errorf("terraform.unlock_older_than must be positive, got %s", c.UnlockOlderThan)
+	}
+
+	for setting, errs := range map[string]map[string]string{"retryable_errors": c.RetryableErrors, "permanent_errors": c.PermanentErrors} {
+		for category, expr := range errs {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("terraform.%s %s is not a valid regexp: %w", setting, category, err)
+			}
+		}
+	}
+
+	return nil
+}
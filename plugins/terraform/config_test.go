@@ -0,0 +1,57 @@
+package plugins_terraform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStepConfig_ShouldOverrideProjectConfig(t *testing.T) {
+	setProjectConfig(StepConfig{
+		Binary:          TerraformBinary,
+		LockTimeout:     5 * time.Minute,
+		UnlockOlderThan: time.Hour,
+		PermanentErrors: map[string]string{"quota": "QuotaExceeded"},
+	})
+	defer setProjectConfig(defaultProjectConfig())
+
+	exec := config.StepExecution{Logger: logger, Fs: afero.NewMemMapFs(), Dir: "/network"}
+	require.NoError(t, afero.WriteFile(exec.Fs, "/network/runiac.yml", []byte(`terraform:
+  binary: tofu
+  version: "~> 1.6"
+  permanent_errors:
+    sku: SkuNotAvailable
+`), 0644))
+
+	stepConfig, err := ReadStepConfig(exec)
+
+	require.NoError(t, err)
+	require.Equal(t, TofuBinary, stepConfig.Binary, "Steps should override the binary")
+	require.Equal(t, "~> 1.6", stepConfig.Version)
+	require.Equal(t, 5*time.Minute, stepConfig.LockTimeout, "Steps should inherit settings they don't set")
+	require.Equal(t, map[string]string{"quota": "QuotaExceeded", "sku": "SkuNotAvailable"}, stepConfig.PermanentErrors)
+
+	stepConfig, err = ReadStepConfig(config.StepExecution{Logger: logger, Fs: exec.Fs, Dir: "/other"})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"quota": "QuotaExceeded"}, stepConfig.PermanentErrors, "Steps should not change the project's settings")
+}
+
+func TestReadStepConfig_ShouldRejectUnsupportedSettings(t *testing.T) {
+	for name, section := range map[string]string{
+		"binary":            "binary: terragrunt",
+		"unlock threshold":  "unlock_older_than: 0s",
+		"retryable pattern": "retryable_errors:\n    dns: \"Temporary(\"",
+		"permanent pattern": "permanent_errors:\n    quota: \"QuotaExceeded(\"",
+	} {
+		exec := config.StepExecution{Logger: logger, Fs: afero.NewMemMapFs(), Dir: "/network"}
+		require.NoError(t, afero.WriteFile(exec.Fs, "/network/runiac.yml", []byte("terraform:\n  "+section+"\n"), 0644))
+
+		_, err := ReadStepConfig(exec)
+
+		require.Error(t, err, name)
+	}
+}
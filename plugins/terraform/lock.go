@@ -0,0 +1,159 @@
+package plugins_terraform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/sirupsen/logrus"
+)
+
+// unlockTerraformStateInDir releases the state lock of a step's workspace when it was left behind by an interrupted
+// runiac execution and has been held longer than the unlock threshold
+var unlockTerraformStateInDir = func(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail // assume failure
+	var tfOptions *terraform.Options
+
+	var stepConfig StepConfig
+	stepConfig, output.Err = ReadStepConfig(exec)
+
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error reading terraform step configuration")
+		return
+	}
+
+	// terraform init and workspace
+	tfOptions, output.Err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	if output.Err != nil {
+		return
+	}
+
+	tfOptions, output.Err = getCommonTfOptions2(exec, stepConfig)
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("Error resolving the terraform binary for checking the state lock")
+		return
+	}
+
+	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "plan")
+
+	// the lock is checked with a plan, which needs the step's variables
+	for k, v := range GetTerraformEnvVars(exec) {
+		tfOptions.EnvVars[fmt.Sprintf("TF_VAR_%s", k)] = v
+	}
+
+	tfOptions.Vars = GetTerraformCLIVars(exec)
+	tfOptions.VarFiles = GetVarFiles(exec, stepConfig)
+
+	var lock *terraform.LockInfo
+	lock, output.StreamOutput, output.Err = terraformer.GetStateLock(tfOptions)
+
+	if output.Err != nil {
+		classifyError(tfOptions, output.StreamOutput, output.Err, &output)
+		tfOptions.Logger.WithError(output.Err).Error("Error checking the state lock")
+		return
+	}
+
+	if lock == nil {
+		tfOptions.Logger.Info("State is not locked")
+	} else {
+		lockLogger := withStateLockFields(tfOptions.Logger, *lock)
+
+		output.Err = checkStaleLock(*lock, getWorkspace(exec), stepConfig.UnlockOlderThan)
+
+		if output.Err != nil {
+			lockLogger.WithError(output.Err).Error("Refusing to release the state lock")
+			return
+		}
+
+		tfOptions.Logger = tfOptions.Logger.WithField("terraform", "force-unlock")
+		var resp string
+		resp, output.Err = terraformer.ForceUnlock(tfOptions, lock.ID)
+
+		if output.Err != nil {
+			classifyError(tfOptions, resp, output.Err, &output)
+			lockLogger.WithError(output.Err).Error("Error running terraform force-unlock")
+			return
+		}
+
+		lockLogger.Warnf("Released the state lock held by %s for %s", lock.Who, lock.Age().Round(time.Second))
+	}
+
+	// parse terraform output from state for downstream steps
+	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "output")
+
+	output.OutputVariables, output.Err = terraformer.OutputAll(tfOptions)
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("Error running terraform output")
+	}
+
+	output.Status = config.Success
+
+	return
+}
+
+// checkStaleLock returns an error unless the lock is held by a runiac container, belongs to the workspace and is older
+// than the threshold, the only locks that are safe to release without knowing whether their holder is still running
+func checkStaleLock(lock terraform.LockInfo, workspace string, olderThan time.Duration) error {
+	if host := lock.Who[strings.LastIndex(lock.Who, "@")+1:]; host != config.LockHolderHostname {
+		return fmt.Errorf("lock %s is held by %s, only locks held by runiac containers (*@%s) are released", lock.ID, lock.Who, config.LockHolderHostname)
+	}
+
+	if !lockOnWorkspace(lock.Path, workspace) {
+		return fmt.Errorf("lock %s is on %s, not the state of workspace %s", lock.ID, lock.Path, workspace)
+	}
+
+	if lock.Created.IsZero() {
+		return fmt.Errorf("lock %s has no creation time, only locks of a known age are released", lock.ID)
+	}
+
+	if age := lock.Age(); age < olderThan {
+		return fmt.Errorf("lock %s was created %s ago, only locks older than %s are released", lock.ID, age.Round(time.Second), olderThan)
+	}
+
+	return nil
+}
+
+// lockOnWorkspace reports whether a segment of the lock's path, e.g. tfstate-bucket/env:/<workspace>/network.tfstate,
+// is the workspace itself rather than a workspace that contains its name, e.g. the workspace of another namespace
+func lockOnWorkspace(path string, workspace string) bool {
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == ':' })
+
+	for _, segment := range segments {
+		segment = strings.TrimSuffix(strings.TrimSuffix(segment, ".tflock"), ".tfstate")
+
+		if segment == workspace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withStateLockFields adds the details of a state lock to the logger
+func withStateLockFields(logger *logrus.Entry, lock terraform.LockInfo) *logrus.Entry {
+	return logger.WithFields(logrus.Fields{
+		"lockID":        lock.ID,
+		"lockHolder":    lock.Who,
+		"lockOperation": lock.Operation,
+		"lockAge":       lock.Age().Round(time.Second).String(),
+	})
+}
+
+// withStateLockError adds the details of the state lock to the logger when a terraform command failed to acquire it
+func withStateLockError(logger *logrus.Entry, err error) *logrus.Entry {
+	var lockErr *terraform.StateLockError
+	if !errors.As(err, &lockErr) {
+		return logger
+	}
+
+	return withStateLockFields(logger, lockErr.Lock)
+}
@@ -0,0 +1,159 @@
+package plugins_terraform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+type stubLockTerraformer struct {
+	terraform.Terraform
+	lock       *terraform.LockInfo
+	unlockedID string
+}
+
+func (s *stubLockTerraformer) Init(options *terraform.Options) (string, error) {
+	return "", nil
+}
+
+func (s *stubLockTerraformer) WorkspaceSelect(options *terraform.Options, workspace string) (string, error) {
+	return "", nil
+}
+
+func (s *stubLockTerraformer) GetStateLock(options *terraform.Options) (*terraform.LockInfo, string, error) {
+	return s.lock, "", nil
+}
+
+func (s *stubLockTerraformer) ForceUnlock(options *terraform.Options, lockID string) (string, error) {
+	s.unlockedID = lockID
+	return "", nil
+}
+
+func (s *stubLockTerraformer) OutputAll(options *terraform.Options) (map[string]interface{}, error) {
+	return map[string]interface{}{"vnet_id": "vnet"}, nil
+}
+
+func TestCheckStaleLock_ShouldOnlyAllowOldRuniacLocksOfTheWorkspace(t *testing.T) {
+	t.Parallel()
+
+	stale := terraform.LockInfo{
+		ID:      "8f1e2a6c",
+		Path:    "tfstate-bucket/env:/dev-primary-centralus/network.tfstate",
+		Who:     "root@runiac",
+		Created: time.Now().Add(-2 * time.Hour),
+	}
+
+	require.NoError(t, checkStaleLock(stale, "dev-primary-centralus", time.Hour))
+
+	tests := map[string]func(lock *terraform.LockInfo){
+		"other holder":    func(lock *terraform.LockInfo) { lock.Who = "jdoe@laptop" },
+		"other workspace": func(lock *terraform.LockInfo) { lock.Path = "tfstate-bucket/env:/dev-regional-eastus/network.tfstate" },
+		"recent":          func(lock *terraform.LockInfo) { lock.Created = time.Now().Add(-10 * time.Minute) },
+		"unknown age":     func(lock *terraform.LockInfo) { lock.Created = time.Time{} },
+		"namespaced workspace": func(lock *terraform.LockInfo) {
+			lock.Path = "tfstate-bucket/env:/pr-1-dev-primary-centralus/network.tfstate"
+		},
+	}
+
+	for name, modify := range tests {
+		lock := stale
+		modify(&lock)
+
+		require.Error(t, checkStaleLock(lock, "dev-primary-centralus", time.Hour), name)
+	}
+}
+
+func TestCheckStaleLock_ShouldMatchTheWorkspaceOfEachBackend(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{
+		"tfstate-bucket/env:/dev-primary-centralus/network.tfstate",
+		"tfstate/network.tfstateenv:dev-primary-centralus",
+		"terraform.tfstate.d/dev-primary-centralus/terraform.tfstate",
+		"gs://tfstate-bucket/network/dev-primary-centralus.tflock",
+	}
+
+	for _, path := range paths {
+		lock := terraform.LockInfo{ID: "8f1e2a6c", Path: path, Who: "root@runiac", Created: time.Now().Add(-2 * time.Hour)}
+
+		require.NoError(t, checkStaleLock(lock, "dev-primary-centralus", time.Hour), path)
+		require.Error(t, checkStaleLock(lock, "primary-centralus", time.Hour), path)
+	}
+}
+
+func TestParseLockInfo_ShouldNotReportLocksOfUnknownAge(t *testing.T) {
+	t.Parallel()
+
+	out := `Error: Error acquiring the state lock
+
+Lock Info:
+  ID:        8f1e2a6c
+  Path:      tfstate-bucket/env:/dev-primary-centralus/network.tfstate
+  Operation: OperationTypeApply
+  Who:       root@runiac
+  Version:   1.5.7
+  Created:   yesterday
+`
+
+	lock, ok := terraform.ParseLockInfo(out)
+
+	require.False(t, ok, "A lock whose age is unknown should not be released")
+	require.True(t, lock.Created.IsZero())
+	require.Error(t, checkStaleLock(lock, "dev-primary-centralus", time.Hour))
+}
+
+func TestExecuteStep_ShouldReleaseStaleLocksWhenUnlocking(t *testing.T) {
+	stub := &stubLockTerraformer{
+		lock: &terraform.LockInfo{
+			ID:      "8f1e2a6c",
+			Path:    "tfstate-bucket/env:/dev-primary-centralus/network.tfstate",
+			Who:     "root@runiac",
+			Created: time.Now().Add(-2 * time.Hour),
+		},
+	}
+	terraformer = stub
+	defer func() {
+		terraformer = terraform.Terraform{}
+	}()
+
+	exec := config.StepExecution{
+		Logger:           logger,
+		Fs:               afero.NewMemMapFs(),
+		Dir:              "/network",
+		StepName:         "network",
+		Namespace:        "dev",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		Action:           config.UnlockAction,
+	}
+
+	output := TerraformStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "8f1e2a6c", stub.unlockedID)
+	require.Equal(t, "vnet", output.OutputVariables["vnet_id"])
+
+	// locks of executions that may still be running are left alone
+	stub.unlockedID = ""
+	require.NoError(t, afero.WriteFile(exec.Fs, "/network/runiac.yml", []byte("terraform:\n  unlock_older_than: 3h\n"), 0644))
+
+	output = TerraformStepper{}.ExecuteStep(exec)
+
+	require.Error(t, output.Err)
+	require.Equal(t, config.Fail, output.Status)
+	require.Empty(t, stub.unlockedID)
+
+	// steps without a lock have nothing to release
+	stub.lock = nil
+
+	output = TerraformStepper{}.ExecuteStep(exec)
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Empty(t, stub.unlockedID)
+}
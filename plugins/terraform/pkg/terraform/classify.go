@@ -0,0 +1,108 @@
+package terraform
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrorClass is whether a failed terraform command can succeed when it runs again
+type ErrorClass string
+
+const (
+	TransientError ErrorClass = "transient" // The command can succeed when it runs again, e.g. after throttling
+	PermanentError ErrorClass = "permanent" // The command fails until the configuration or credentials are fixed
+	UnknownError   ErrorClass = "unknown"   // The error matched neither transient nor permanent errors
+)
+
+// ErrorClassification describes why a terraform command failed
+type ErrorClassification struct {
+	Class    ErrorClass
+	Category string // The kind of error, e.g. throttling, or the message of a matching configured error
+	Pattern  string // The regexp that matched the command's output
+}
+
+// Retryable reports whether running the command again can succeed
+func (c ErrorClassification) Retryable() bool {
+	return c.Class == TransientError
+}
+
+type errorPattern struct {
+	category string
+	regexp   *regexp.Regexp
+}
+
+// transientErrors are known errors of cloud APIs, providers and the registry that go away on their own
+var transientErrors = []errorPattern{
+	{"throttling", regexp.MustCompile(`Throttling|TooManyRequests|Too Many Requests|RequestLimitExceeded|Rate exceeded|rateLimitExceeded|SlowDown|(?i:status ?code[=: ]*429)`)},
+	{"eventual consistency", regexp.MustCompile(`PrincipalNotFound|does not exist in the directory|cannot be assumed by|InvalidInstanceID\.NotFound|InvalidGroup\.NotFound|OperationInProgress|AnotherOperationInProgress|If access was recently granted`)},
+	{"provider download", regexp.MustCompile(`Failed to install provider|Failed to query available provider packages|Could not retrieve the list of available versions|Failed to download module|TLS handshake timeout|connection reset by peer|i/o timeout`)},
+	{"server error", regexp.MustCompile(`InternalError|InternalServerError|Internal Server Error|ServiceUnavailable|Service Unavailable|Bad Gateway|Gateway Timeout|(?i:status ?code[=: ]*5\d\d)`)},
+}
+
+// permanentErrors are known errors that fail again until the configuration or credentials are fixed
+var permanentErrors = []errorPattern{
+	{"syntax", regexp.MustCompile(`Error: (Argument or block definition required|Invalid (block|expression|character|reference|function argument|resource type|multi-line string)|Unsupported (argument|block type|attribute)|Missing required argument|Unclosed configuration block|Reference to undeclared|Duplicate (resource|variable|output))`)},
+	{"invalid variables", regexp.MustCompile(`Error: (No value for required variable|Invalid value for (input )?variable|Value for undeclared variable)`)},
+	{"authentication", regexp.MustCompile(`InvalidClientTokenId|ExpiredToken|UnrecognizedClientException|SignatureDoesNotMatch|invalid_grant|AADSTS\d+|No valid credential sources found|could not find default credentials|Unable to locate credentials`)},
+}
+
+// ClassifyError matches the output of a failed terraform command against the errors configured in the options and the
+// known transient and permanent errors, in that order. Failures to acquire the state lock are permanent, since
+// terraform already waited for the configured lock timeout.
+func ClassifyError(options *Options, out string, err error) ErrorClassification {
+	var lockErr *StateLockError
+	if errors.As(err, &lockErr) {
+		return ErrorClassification{Class: PermanentError, Category: "state lock"}
+	}
+
+	// only the diagnostics are matched, the rest of the output may contain any resource attribute
+	if i := strings.Index(out, "Error:"); i >= 0 {
+		out = out[i:]
+	}
+
+	if err != nil {
+		out += "\n" + err.Error()
+	}
+
+	catalogue := []struct {
+		class    ErrorClass
+		patterns []errorPattern
+	}{
+		{PermanentError, toErrorPatterns(options.PermanentTerraformErrors)},
+		{TransientError, toErrorPatterns(options.RetryableTerraformErrors)},
+		{PermanentError, permanentErrors},
+		{TransientError, transientErrors},
+	}
+
+	for _, c := range catalogue {
+		for _, p := range c.patterns {
+			if p.regexp.MatchString(out) {
+				return ErrorClassification{Class: c.class, Category: p.category, Pattern: p.regexp.String()}
+			}
+		}
+	}
+
+	return ErrorClassification{Class: UnknownError}
+}
+
+// toErrorPatterns compiles configured errors, keyed by regexp, in a stable order. Invalid regexps are ignored.
+func toErrorPatterns(errs map[string]string) []errorPattern {
+	patterns := []errorPattern{}
+
+	for expr, message := range errs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+
+		patterns = append(patterns, errorPattern{category: message, regexp: re})
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].regexp.String() < patterns[j].regexp.String()
+	})
+
+	return patterns
+}
@@ -4,6 +4,7 @@ package terraform
 
 import (
 	"fmt"
+	"time"
 )
 
 // OutputKeyNotFound occurs when terraform output does not contain a value for the key
@@ -50,3 +51,17 @@ type UnexpectedOutputType struct {
 func (err UnexpectedOutputType) Error() string {
 	return fmt.Sprintf("Expected output '%s' to be of type '%s' but got '%s'", err.Key, err.ExpectedType, err.ActualType)
 }
+
+// StateLockError occurs when terraform cannot acquire the state lock because it is held by another operation
+type StateLockError struct {
+	Lock LockInfo
+	Err  error
+}
+
+func (err *StateLockError) Error() string {
+	return fmt.Sprintf("state is locked by %s (lock ID %s, operation %s, age %s)", err.Lock.Who, err.Lock.ID, err.Lock.Operation, err.Lock.Age().Round(time.Second))
+}
+
+func (err *StateLockError) Unwrap() error {
+	return err.Err
+}
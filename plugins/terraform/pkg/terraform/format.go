@@ -13,8 +13,9 @@ import (
 func FormatArgs(options *Options, args ...string) []string {
 	var terraformArgs []string
 	terraformArgs = append(terraformArgs, args...)
-	terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(options.Vars)...)
+	// the last definition of a variable wins, so vars take precedence over var files
 	terraformArgs = append(terraformArgs, FormatTerraformArgs("-var-file", options.VarFiles)...)
+	terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(options.Vars)...)
 	terraformArgs = append(terraformArgs, FormatTerraformArgs("-target", options.Targets)...)
 	return terraformArgs
 }
@@ -2,28 +2,15 @@ package terraform
 
 import (
 	"strings"
-	"time"
-
-	"github.com/optum/runiac/pkg/retry"
 )
 
-// Init calls terraform init and return stdout/stderr.
+// Init calls terraform init and return stdout/stderr. Callers are responsible for retrying transient errors.
 func Init(options *Options) (out string, err error) {
 	args := []string{"init", "-force-copy"}
 	backendArgs := FormatTerraformBackendConfigAsArgs(options.BackendConfig)
 	args = append(args, backendArgs...)
 
 	options.Logger.Infof("BackendConfig: %v", strings.Join(backendArgs, " "))
-	retryErr := retry.DoWithRetry("terraform init", 3, 10*time.Second, options.Logger, func(attempt int) error {
-		out, err = RunTerraformCommand(true, options, args...)
-
-		return err
-	})
-
-	if retryErr != nil {
-		options.Logger.WithError(retryErr).Error("Error attempting retryable action")
-	}
-
-	return
 
+	return RunTerraformCommand(true, options, args...)
 }
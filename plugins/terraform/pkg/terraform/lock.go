@@ -0,0 +1,107 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// lockCreatedLayout is the format terraform prints the creation time of a lock in
+const lockCreatedLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// lockInfoFields matches the lock info terraform prints, with or without the gutter of its colored diagnostics
+var lockInfoFields = regexp.MustCompile(`(?m)^[\s│]*(ID|Path|Operation|Who|Version|Created):\s*(.*?)\s*$`)
+
+// LockInfo describes a state lock as reported by terraform when it fails to acquire the lock
+type LockInfo struct {
+	ID        string
+	Path      string
+	Operation string
+	Who       string // The user and hostname of the process holding the lock, e.g. root@runiac
+	Version   string
+	Created   time.Time
+}
+
+// Age returns how long the lock has been held
+func (lock LockInfo) Age() time.Duration {
+	return time.Since(lock.Created)
+}
+
+// ParseLockInfo reads the lock info from the output of a terraform command that failed to acquire the state lock. The
+// lock is not reported when its creation time cannot be read, as its age would be unknown.
+func ParseLockInfo(out string) (lock LockInfo, ok bool) {
+	i := strings.Index(out, "Error acquiring the state lock")
+	if i < 0 {
+		return lock, false
+	}
+
+	j := strings.Index(out[i:], "Lock Info:")
+	if j < 0 {
+		return lock, false
+	}
+
+	var err error
+	for _, m := range lockInfoFields.FindAllStringSubmatch(out[i+j:], -1) {
+		switch m[1] {
+		case "ID":
+			lock.ID = m[2]
+		case "Path":
+			lock.Path = m[2]
+		case "Operation":
+			lock.Operation = m[2]
+		case "Who":
+			lock.Who = m[2]
+		case "Version":
+			lock.Version = m[2]
+		case "Created":
+			lock.Created, err = time.Parse(lockCreatedLayout, m[2])
+		}
+	}
+
+	return lock, lock.ID != "" && err == nil && !lock.Created.IsZero()
+}
+
+// checkStateLock returns a StateLockError when a failed command could not acquire the state lock
+func checkStateLock(out string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if lock, ok := ParseLockInfo(out); ok {
+		return &StateLockError{Lock: lock, Err: err}
+	}
+
+	return err
+}
+
+// lockArgs returns the arguments that make terraform wait for the state lock
+func lockArgs(options *Options) []string {
+	if options.LockTimeout <= 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("-lock-timeout=%s", options.LockTimeout)}
+}
+
+// GetStateLock checks whether the state is locked by attempting a plan that does not wait for the lock. The lock is
+// nil when the state was not locked.
+func GetStateLock(options *Options) (*LockInfo, string, error) {
+	args := []string{"plan", "-refresh=false", "-lock-timeout=0s", "-input=false", "-no-color"}
+
+	out, err := RunTerraformCommand(true, options, FormatArgs(options, args...)...)
+
+	var lockErr *StateLockError
+	if errors.As(checkStateLock(out, err), &lockErr) {
+		return &lockErr.Lock, out, nil
+	}
+
+	return nil, out, err
+}
+
+// ForceUnlock releases the state lock with the given ID without waiting for its holder
+func ForceUnlock(options *Options, lockID string) (string, error) {
+	args := []string{"force-unlock", "-force", "-no-color", lockID}
+	return RunTerraformCommand(true, options, args...)
+}
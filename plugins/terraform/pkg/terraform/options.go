@@ -17,7 +17,8 @@ type Options struct {
 	EnvVars                  map[string]string      // Environment variables to set when running Terraform
 	BackendConfig            map[string]interface{} // The vars to pass to the terraform init command for extra configuration for the backend
 	RetryableTerraformErrors map[string]string      // If Terraform apply fails with one of these (transient) errors, retry. The keys are a regexp to match against the error and the message is what to display to a user if that error is matched.
-	MaxRetries               int                    // Maximum number of times to retry errors matching RetryableTerraformErrors
+	PermanentTerraformErrors map[string]string      // If Terraform fails with one of these (permanent) errors, do not retry, even when a known transient error also matches. The keys are a regexp to match against the error and the message is what to display to a user if that error is matched.
+	MaxRetries               int                    // Maximum number of times to retry errors matching RetryableTerraformErrors or known transient errors
 	TimeBetweenRetries       time.Duration          // The amount of time to wait between retries
 	Upgrade                  bool                   // Whether the -upgrade flag of the terraform init command should be set to true or not
 	NoColor                  bool                   // Whether the -no-color flag will be set for any Terraform command or not
@@ -25,4 +26,5 @@ type Options struct {
 	OutputMaxLineSize        int                    // The max size of one line in stdout and stderr (in bytes)
 	Logger                   *logrus.Entry
 	PluginCacheDir           string
+	LockTimeout              time.Duration // How long plan and apply wait for the state lock, they fail immediately when zero
 }
@@ -1,6 +1,10 @@
 package terraform
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/optum/runiac/pkg/shell"
+)
 
 // Plan runs terraform plan with the given options and returns stdout/stderr.
 func Plan(options *Options, tfplan string, destroy bool) (string, error) {
@@ -10,5 +14,30 @@ func Plan(options *Options, tfplan string, destroy bool) (string, error) {
 		args = append(args, "-destroy")
 	}
 
-	return RunTerraformCommand(true, options, FormatArgs(options, args...)...)
+	args = append(args, lockArgs(options)...)
+
+	out, err := RunTerraformCommand(true, options, FormatArgs(options, args...)...)
+
+	return out, checkStateLock(out, err)
+}
+
+// PlanRefreshOnly runs terraform plan in refresh-only mode with detailed exit codes and returns stdout/stderr.
+// changes is true when terraform detected differences between the state and the real resources.
+func PlanRefreshOnly(options *Options, tfplan string) (changes bool, out string, err error) {
+	args := []string{"plan", "-refresh-only", "-detailed-exitcode", fmt.Sprintf("-out=%s", tfplan), "-input=false", "-no-color"}
+	args = append(args, lockArgs(options)...)
+
+	out, err = RunTerraformCommand(true, options, FormatArgs(options, args...)...)
+
+	if err != nil {
+		exitCode, exitCodeErr := shell.GetExitCodeForRunCommandError(err)
+
+		if exitCodeErr == nil && exitCode == TerraformPlanChangesPresentExitCode {
+			return true, out, nil
+		}
+
+		return false, out, checkStateLock(out, err)
+	}
+
+	return false, out, nil
 }
@@ -18,12 +18,16 @@ type Terraformer interface {
 	Version(options *Options) (out string, err error)
 	Show(options *Options, tfplan string) (string, error)
 	Plan(options *Options, tfplan string, destroy bool) (string, error)
+	PlanRefreshOnly(options *Options, tfplan string) (bool, string, error)
 	OutputAll(options *Options) (map[string]interface{}, error)
 	OutputForKeysE(options *Options, keys []string) (map[string]interface{}, error)
 	OutputToString(value interface{}) string
 	Init(options *Options) (out string, err error)
 	Apply(options *Options, tfplan string) (string, error)
 	WorkspaceSelect(options *Options, workspace string) (string, error)
+	Test(options *Options, junitFile string) (string, error)
+	GetStateLock(options *Options) (*LockInfo, string, error)
+	ForceUnlock(options *Options, lockID string) (string, error)
 }
 
 type Terraform struct{}
@@ -40,6 +44,10 @@ func (t Terraform) Plan(options *Options, tfplan string, destroy bool) (string,
 	return Plan(options, tfplan, destroy)
 }
 
+func (t Terraform) PlanRefreshOnly(options *Options, tfplan string) (bool, string, error) {
+	return PlanRefreshOnly(options, tfplan)
+}
+
 func (t Terraform) OutputAll(options *Options) (map[string]interface{}, error) {
 	return OutputAll(options)
 }
@@ -63,3 +71,15 @@ func (t Terraform) OutputToString(value interface{}) string {
 func (t Terraform) WorkspaceSelect(options *Options, workspace string) (string, error) {
 	return WorkspaceSelect(options, workspace)
 }
+
+func (t Terraform) Test(options *Options, junitFile string) (string, error) {
+	return Test(options, junitFile)
+}
+
+func (t Terraform) GetStateLock(options *Options) (*LockInfo, string, error) {
+	return GetStateLock(options)
+}
+
+func (t Terraform) ForceUnlock(options *Options, lockID string) (string, error) {
+	return ForceUnlock(options, lockID)
+}
@@ -1,10 +1,12 @@
 package terraform
 
 import (
+	"errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"time"
 )
 
 var tfOptions Options
@@ -95,3 +97,147 @@ func TestOutputToString(t *testing.T) {
 		assert.Equal(t, tc.ExpectedString, result)
 	}
 }
+
+func TestFormatArgs_ShouldPassVarsAfterVarFiles(t *testing.T) {
+	options := Options{
+		Vars:     map[string]interface{}{"runiac_region": "centralus"},
+		VarFiles: []string{"common.tfvars", "ring_prod.tfvars"},
+	}
+
+	args := FormatArgs(&options, "plan")
+
+	assert.Equal(t, []string{"plan", "-var-file", "common.tfvars", "-var-file", "ring_prod.tfvars", "-var", "runiac_region=centralus"}, args)
+}
+
+const stateLockOutput = `Acquiring state lock. This may take a few moments...
+╷
+│ Error: Error acquiring the state lock
+│ 
+│ Error message: ConditionalCheckFailedException: The conditional request failed
+│ Lock Info:
+│   ID:        8f1e2a6c-54d3-9b7a-1c2e-3f4a5b6c7d8e
+│   Path:      tfstate-bucket/env:/dev-primary-centralus/network.tfstate
+│   Operation: OperationTypeApply
+│   Who:       root@runiac
+│   Version:   1.5.7
+│   Created:   2023-09-12 14:03:27.184523611 +0000 UTC
+│   Info:      
+│ 
+│ 
+│ Terraform acquires a state lock to protect the state from being written
+│ by multiple users at the same time. Please resolve the issue above and try
+│ again. For most commands, you can disable locking with the "-lock=false"
+│ flag, but this is not recommended.
+╵`
+
+func TestParseLockInfo_ShouldReadLockHolder(t *testing.T) {
+	lock, ok := ParseLockInfo(stateLockOutput)
+
+	assert.True(t, ok)
+	assert.Equal(t, "8f1e2a6c-54d3-9b7a-1c2e-3f4a5b6c7d8e", lock.ID)
+	assert.Equal(t, "tfstate-bucket/env:/dev-primary-centralus/network.tfstate", lock.Path)
+	assert.Equal(t, "OperationTypeApply", lock.Operation)
+	assert.Equal(t, "root@runiac", lock.Who)
+	assert.Equal(t, "1.5.7", lock.Version)
+	assert.Equal(t, time.Date(2023, 9, 12, 14, 3, 27, 184523611, time.UTC), lock.Created.UTC())
+
+	_, ok = ParseLockInfo("Error: No value for required variable")
+	assert.False(t, ok)
+}
+
+func TestCheckStateLock_ShouldReturnStateLockError(t *testing.T) {
+	cmdErr := errors.New("exit status 1")
+
+	err := checkStateLock(stateLockOutput, cmdErr)
+
+	var lockErr *StateLockError
+	assert.True(t, errors.As(err, &lockErr))
+	assert.Equal(t, "root@runiac", lockErr.Lock.Who)
+	assert.ErrorIs(t, err, cmdErr)
+	assert.Contains(t, err.Error(), "state is locked by root@runiac (lock ID 8f1e2a6c-54d3-9b7a-1c2e-3f4a5b6c7d8e, operation OperationTypeApply, age")
+
+	assert.Equal(t, cmdErr, checkStateLock("Error: Invalid reference", cmdErr))
+	assert.Nil(t, checkStateLock(stateLockOutput, nil))
+}
+
+func TestLockArgs_ShouldSetLockTimeout(t *testing.T) {
+	assert.Empty(t, lockArgs(&Options{}))
+	assert.Equal(t, []string{"-lock-timeout=5m0s"}, lockArgs(&Options{LockTimeout: 5 * time.Minute}))
+}
+
+func TestClassifyError_ShouldMatchKnownErrors(t *testing.T) {
+	tests := []struct {
+		Out              string
+		ExpectedClass    ErrorClass
+		ExpectedCategory string
+	}{
+		{
+			Out:              "Error: creating S3 Bucket (logs): operation error S3: CreateBucket, https response error StatusCode: 503, RequestID: 1, api error SlowDown: Please reduce your request rate.",
+			ExpectedClass:    TransientError,
+			ExpectedCategory: "throttling",
+		},
+		{
+			Out:              "Error: authorization.RoleAssignmentsClient#Create: Failure responding to request: StatusCode=400 -- Original Error: autorest/azure: Service returned an error. Status=400 Code=\"PrincipalNotFound\"",
+			ExpectedClass:    TransientError,
+			ExpectedCategory: "eventual consistency",
+		},
+		{
+			Out:              "Error: Failed to install provider\n\nError while installing hashicorp/aws v5.17.0: read tcp 172.17.0.2:43210->13.32.1.1:443: read: connection reset by peer",
+			ExpectedClass:    TransientError,
+			ExpectedCategory: "provider download",
+		},
+		{
+			Out:              "Error: retrieving Storage Account: unexpected status 502 (502 Bad Gateway)",
+			ExpectedClass:    TransientError,
+			ExpectedCategory: "server error",
+		},
+		{
+			Out:              "Error: Unsupported argument\n\n  on main.tf line 12, in resource \"aws_s3_bucket\" \"logs\":\n  12:   acl_policy = \"private\"",
+			ExpectedClass:    PermanentError,
+			ExpectedCategory: "syntax",
+		},
+		{
+			Out:              "Error: No value for required variable\n\n  on variables.tf line 1:\n   1: variable \"vnet_id\" {",
+			ExpectedClass:    PermanentError,
+			ExpectedCategory: "invalid variables",
+		},
+		{
+			Out:              "Error: configuring Terraform AWS Provider: validating provider credentials: retrieving caller identity from STS: api error InvalidClientTokenId: The security token included in the request is invalid.",
+			ExpectedClass:    PermanentError,
+			ExpectedCategory: "authentication",
+		},
+		{
+			Out:           "Error: creating EC2 Instance: InsufficientInstanceCapacity",
+			ExpectedClass: UnknownError,
+		},
+	}
+
+	for _, tc := range tests {
+		classification := ClassifyError(&Options{}, tc.Out, errors.New("exit status 1"))
+
+		assert.Equal(t, tc.ExpectedClass, classification.Class, tc.Out)
+		assert.Equal(t, tc.ExpectedCategory, classification.Category, tc.Out)
+		assert.Equal(t, tc.ExpectedClass == TransientError, classification.Retryable(), tc.Out)
+	}
+}
+
+func TestClassifyError_ShouldPreferConfiguredErrors(t *testing.T) {
+	options := &Options{
+		RetryableTerraformErrors: map[string]string{"InsufficientInstanceCapacity": "capacity"},
+		PermanentTerraformErrors: map[string]string{"BucketAlreadyExists": "bucket name taken"},
+	}
+
+	classification := ClassifyError(options, "Error: creating EC2 Instance: InsufficientInstanceCapacity", errors.New("exit status 1"))
+	assert.Equal(t, ErrorClassification{Class: TransientError, Category: "capacity", Pattern: "InsufficientInstanceCapacity"}, classification)
+
+	classification = ClassifyError(options, "Error: creating S3 Bucket: BucketAlreadyExists, StatusCode: 503", errors.New("exit status 1"))
+	assert.Equal(t, PermanentError, classification.Class)
+	assert.Equal(t, "bucket name taken", classification.Category)
+
+	// resource attributes printed before the diagnostics are not matched
+	classification = ClassifyError(&Options{}, "  + description = \"Throttling alarm\"\n\nError: creating Alarm: InvalidParameterCombination", errors.New("exit status 1"))
+	assert.Equal(t, UnknownError, classification.Class)
+
+	classification = ClassifyError(&Options{}, stateLockOutput, checkStateLock(stateLockOutput, errors.New("exit status 1")))
+	assert.Equal(t, ErrorClassification{Class: PermanentError, Category: "state lock"}, classification)
+}
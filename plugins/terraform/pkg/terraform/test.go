@@ -0,0 +1,14 @@
+package terraform
+
+import "fmt"
+
+// Test runs terraform test with the given options and returns stdout/stderr. A JUnit report is written to junitFile when set.
+func Test(options *Options, junitFile string) (string, error) {
+	args := []string{"test"}
+
+	if junitFile != "" {
+		args = append(args, fmt.Sprintf("-junit-xml=%s", junitFile))
+	}
+
+	return RunTerraformCommand(true, options, FormatArgs(options, args...)...)
+}
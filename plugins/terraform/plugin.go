@@ -1,19 +1,58 @@
 package plugins_terraform
 
 import (
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
 	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
+// RunnerName is the runner configuration value that selects the terraform runner
+const RunnerName = "terraform"
+
+func init() {
+	config.RegisterRunner(config.Runner{
+		Name:       RunnerName,
+		NewStepper: func() config.Stepper { return TerraformStepper{} },
+		Plugin:     TerraformPlugin{},
+		Detect:     Detect,
+	})
+}
+
+// Detect reports whether dir contains terraform configuration
+func Detect(fs afero.Fs, dir string) bool {
+	matches, err := afero.Glob(fs, filepath.Join(dir, "*.tf"))
+	return err == nil && len(matches) > 0
+}
+
 type TerraformPlugin struct{}
 
 func (info TerraformPlugin) Initialize(logger *logrus.Entry) {
 	logger.Info("Initializing runiac Terraform plugin")
+}
+
+// Configure reads the terraform section of the project's runiac.yml and resolves the configured binary, failing when it
+// does not satisfy the configured version constraint
+func (info TerraformPlugin) Configure(cfg config.Config, logger *logrus.Entry) error {
+	projectConfig, err := ReadProjectConfig()
+	if err != nil {
+		return err
+	}
+
+	setProjectConfig(projectConfig)
+
+	binary, err := ResolveBinary(logger, projectConfig.Binary, projectConfig.Version, projectConfig.CacheDir)
+	if err != nil {
+		return err
+	}
 
 	// display terraform binary information
 	// disable checkpoints since we just want to print the version string alone
 	tfOptions := &terraform.Options{
-		TerraformDir: ".",
+		TerraformBinary: binary,
+		TerraformDir:    ".",
 		EnvVars: map[string]string{
 			"CHECKPOINT_DISABLE": "true",
 		},
@@ -23,11 +62,12 @@ func (info TerraformPlugin) Initialize(logger *logrus.Entry) {
 		TimeBetweenRetries: 0,
 	}
 
-	terraformer := &terraform.Terraform{}
 	resp, err := terraformer.Version(tfOptions)
 	if err != nil {
 		tfOptions.Logger.WithError(err).Error("Error running terraform version")
 	} else {
 		tfOptions.Logger.Info("Binary: ", resp)
 	}
+
+	return nil
 }
@@ -1,7 +1,9 @@
 package plugins_terraform
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,20 +12,48 @@ import (
 	"time"
 
 	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/planbundle"
 	"github.com/optum/runiac/pkg/retry"
 	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/pkg/steps"
 	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
 type TerraformStepper struct{}
 
 var terraformer terraform.Terraformer = terraform.Terraform{}
 
+var runTests = shell.RunShellCommandAndGetAndStreamOutput
+
+// retrySleep is how long to wait before first running a terraform command again after a transient error, later
+// retries wait a random, growing delay up to maxRetrySleep so steps throttled together don't retry together
+var retrySleep = 10 * time.Second
+var maxRetrySleep = 2 * time.Minute
+
+// retryOptions retries a terraform command with jittered backoff
+func retryOptions(maxRetries int, logger *logrus.Entry) retry.Options {
+	return retry.Options{
+		MaxRetries: maxRetries,
+		Backoff:    retry.DecorrelatedJitter(retrySleep, maxRetrySleep),
+		Logger:     logger,
+	}
+}
+
+// runContext returns the context of the run executing the step, retries stop waiting once the run is stopped
+func runContext(exec config.StepExecution) context.Context {
+	if exec.Context == nil {
+		return context.Background()
+	}
+
+	return exec.Context
+}
+
 func (stepper TerraformStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
 	HandleDeployOverrides(exec.Logger, exec.Dir, exec.DeploymentRing)
 
-	if exec.SelfDestroy {
+	if exec.SelfDestroy || exec.Action == config.DestroyAction {
 		HandleDestroyOverrides(exec.Logger, exec.Dir, exec.DeploymentRing)
 	}
 
@@ -37,9 +67,56 @@ func (stepper TerraformStepper) ExecuteStepDestroy(exec config.StepExecution) co
 
 // ExecuteStep deploys a step
 func (stepper TerraformStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	if exec.Action == config.DriftAction {
+		return executeTerraformDriftInDir(exec)
+	}
+
+	if exec.Action == config.ApplyAction {
+		return applyTerraformPlanBundleInDir(exec)
+	}
+
+	if exec.Action == config.UnlockAction {
+		return unlockTerraformStateInDir(exec)
+	}
+
 	return executeTerraformInDir(exec, false)
 }
 
+// ExecuteStepOutputs reads the outputs of a step from its terraform state
+func (stepper TerraformStepper) ExecuteStepOutputs(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail // assume failure
+	var tfOptions *terraform.Options
+
+	var stepConfig StepConfig
+	stepConfig, output.Err = ReadStepConfig(exec)
+
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error reading terraform step configuration")
+		return
+	}
+
+	tfOptions, output.Err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	if output.Err != nil {
+		return
+	}
+
+	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "output")
+	output.OutputVariables, output.Err = terraformer.OutputAll(tfOptions)
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("Error running terraform output")
+		return
+	}
+
+	output.Status = config.Success
+
+	return
+}
+
 // ExecuteStepTests executes the tests for a step
 func (stepper TerraformStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
 	HandleDeployOverrides(exec.Logger, exec.Dir, exec.DeploymentRing)
@@ -54,40 +131,66 @@ func (stepper TerraformStepper) ExecuteStepTests(exec config.StepExecution) (out
 		envVars[fmt.Sprintf("TF_VAR_%s", k)] = fmt.Sprintf("%v", v)
 	}
 
-	testDir := fmt.Sprintf("%s/tests", exec.Dir)
+	testDir := filepath.Join(exec.Dir, "tests")
 
-	// ensure output directory exists for test reporting
-	outputDir := filepath.Join("/", "output", "junit")
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
-		err = os.MkdirAll(outputDir, os.ModePerm)
+	streamOutputs := []string{}
+	errs := []error{}
 
-		if err != nil {
-			exec.Logger.WithError(err).Warn("Failed to create output directory for test results")
-		}
+	if steps.GoTestsExist(exec.Fs, exec.Dir) {
+		goTestOutput := steps.ExecuteGoTests(exec, envVars, runTests)
+
+		streamOutputs = append(streamOutputs, goTestOutput.StreamOutput)
+		errs = append(errs, goTestOutput.Err)
 	}
 
-	_ = retry.DoWithRetry(fmt.Sprintf("execute tests: %s", testDir), exec.MaxTestRetries, 20*time.Second, exec.Logger, func(retryCount int) error {
-		retryLogger := exec.Logger.WithField("retryCount", retryCount)
-		stepDeployID := fmt.Sprintf("%s-%s-%s-%s-%s", exec.Project, exec.TrackName, exec.StepName, exec.RegionDeployType, exec.Region)
-		cmd := shell.Command{
-			Command: "gotestsum",
-			//Command:        "/bin/bash",
-			Args:           []string{"--format", "standard-verbose", "--junitfile", fmt.Sprintf("/output/junit/%s.xml", stepDeployID), "--raw-command", "--", "test2json", "-p", stepDeployID, "./tests.test", "-test.v"},
-			Logger:         retryLogger,
-			SensitiveArgs:  false,
-			NonInteractive: true,
-			Env:            envVars,
-			WorkingDir:     testDir,
+	if globExists(exec.Fs, filepath.Join(exec.Dir, "*.tftest.hcl")) || globExists(exec.Fs, filepath.Join(testDir, "*.tftest.hcl")) {
+		stepConfig, err := ReadStepConfig(exec)
+		if err != nil {
+			exec.Logger.WithError(err).Error("Error reading terraform step configuration")
+			output.StreamOutput = strings.Join(streamOutputs, "\n")
+			output.Err = errors.Join(append(errs, err)...)
+			return
 		}
 
-		output.StreamOutput, output.Err = shell.RunShellCommandAndGetAndStreamOutput(cmd)
+		_ = retry.DoWithRetry(fmt.Sprintf("execute native tests: %s", exec.Dir), exec.MaxTestRetries, 20*time.Second, exec.Logger, func(retryCount int) error {
+			tfOptions, err := getCommonTfOptions2(exec, stepConfig)
+			if err != nil {
+				output.StreamOutput, output.Err = "", err
+				return err
+			}
 
-		return output.Err
-	})
+			tfOptions.EnvVars = envVars
+			tfOptions.Logger = exec.Logger.WithFields(logrus.Fields{"retryCount": retryCount, "terraform": "test"})
+			tfOptions.VarFiles = GetVarFiles(exec, stepConfig)
+
+			// only terraform writes JUnit reports for native tests
+			junitFile := steps.JUnitFile(exec, "-tftest")
+			if filepath.Base(tfOptions.TerraformBinary) != TerraformBinary {
+				tfOptions.Logger.Warnf("%s test does not write JUnit reports", tfOptions.TerraformBinary)
+				junitFile = ""
+			}
+
+			output.StreamOutput, output.Err = terraformer.Test(tfOptions, junitFile)
+
+			return output.Err
+		})
+
+		streamOutputs = append(streamOutputs, output.StreamOutput)
+		errs = append(errs, output.Err)
+	}
+
+	output.StreamOutput = strings.Join(streamOutputs, "\n")
+	output.Err = errors.Join(errs...)
 
 	return
 }
 
+// globExists checks if any file matches pattern
+func globExists(fs afero.Fs, pattern string) bool {
+	matches, err := afero.Glob(fs, pattern)
+	return err == nil && len(matches) > 0
+}
+
 func GetTerraformCLIVars(exec config.StepExecution) map[string]interface{} {
 	vars := map[string]interface{}{
 		"runiac_account_id": exec.AccountID,
@@ -186,51 +289,47 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 	output.Status = config.Fail // assume failure
 	var resp string
 	var tfOptions *terraform.Options
+	var sourceChecksum string
 
-	// terraform init
-	tfOptions, output.Err = getCommonTfOptions2(exec)
+	// checksum the working tree before terraform init adds to it
+	if exec.Action == config.PlanAction && !destroy {
+		sourceChecksum, output.Err = getSourceChecksum(exec)
 
-	if output.Err != nil {
-		tfOptions.Logger.WithError(output.Err).Error("unable to retrieve credentials for terraform init")
-		return
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("Error calculating step checksum for plan bundle")
+			return
+		}
 	}
 
-	tfOptions.BackendConfig = GetBackendConfig(exec, ParseTFBackend).Config
-	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "init")
-	resp, output.Err = terraformer.Init(tfOptions)
+	var stepConfig StepConfig
+	stepConfig, output.Err = ReadStepConfig(exec)
 
 	if output.Err != nil {
-		tfOptions.Logger.WithError(output.Err).Error("Error during terraform init")
+		exec.Logger.WithError(output.Err).Error("Error reading terraform step configuration")
 		return
 	}
 
-	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "workspace")
-
-	workspace := fmt.Sprintf("%s-%s", exec.RegionDeployType.String(), exec.Region)
-
-	if exec.Namespace != "" {
-		workspace = fmt.Sprintf("%s-%s", exec.Namespace, workspace)
-	}
-	resp, output.Err = terraformer.WorkspaceSelect(tfOptions, workspace)
+	// terraform init and workspace
+	tfOptions, output.Err = initTerraformWorkspace(exec, stepConfig, &output)
 
 	if output.Err != nil {
-		tfOptions.Logger.WithError(output.Err).Error("Error during terraform init")
 		return
 	}
 
 	// terraform plan
-	_ = retry.DoWithRetry("terraform plan and apply", tfOptions.MaxRetries, 10*time.Second, tfOptions.Logger, func(attempt int) error {
+	_ = retry.Do(runContext(exec), "terraform plan and apply", retryOptions(tfOptions.MaxRetries, tfOptions.Logger), func(attempt int) error {
 
 		retryLogger := tfOptions.Logger.WithField("retryCount", attempt)
+		output.ErrorClass = nil
 
 		tfplan := fmt.Sprintf("%s%s%stfplan", exec.StepName, exec.RegionDeployType, exec.Region)
 
 		// terraform plan
-		tfOptions, output.Err = getCommonTfOptions2(exec)
+		tfOptions, output.Err = getCommonTfOptions2(exec, stepConfig)
 
 		if output.Err != nil {
-			tfOptions.Logger.WithError(output.Err).Error("Error running terraform plan")
-			return output.Err
+			tfOptions.Logger.WithError(output.Err).Error("Error resolving the terraform binary for terraform plan")
+			return retryIfTransient(tfOptions, "", output.Err, &output)
 		}
 
 		tfOptions.Logger = retryLogger.WithField("terraform", "plan")
@@ -242,20 +341,23 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 		}
 
 		tfOptions.Vars = GetTerraformCLIVars(exec)
+		tfOptions.VarFiles = GetVarFiles(exec, stepConfig)
 
 		resp, output.Err = terraformer.Plan(tfOptions, tfplan, destroy)
 
 		if output.Err != nil {
-			tfOptions.Logger.WithError(output.Err).Error("Error running terraform plan")
-			return output.Err
+			withStateLockError(tfOptions.Logger, output.Err).WithError(output.Err).Error("Error running terraform plan")
+			return retryIfTransient(tfOptions, resp, output.Err, &output)
 		}
 
 		// validate terraform plan
 		// new options to reset variables
-		baseOptions, err := getCommonTfOptions2(exec)
+		baseOptions, err := getCommonTfOptions2(exec, stepConfig)
 
 		if err != nil {
-			retryLogger.WithError(output.Err).Error("Error retrieving tf options for terraform show")
+			output.Err = err
+			retryLogger.WithError(output.Err).Error("Error resolving the terraform binary for terraform show")
+			return retryIfTransient(baseOptions, "", output.Err, &output)
 		}
 
 		baseOptions.Logger = retryLogger.WithField("terraform", "show")
@@ -263,7 +365,7 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 
 		if output.Err != nil {
 			baseOptions.Logger.WithError(output.Err).Errorf("Error during terraform show:\n%s", resp)
-			return output.Err
+			return retryIfTransient(baseOptions, resp, output.Err, &output)
 		}
 
 		plan := plan{}
@@ -271,7 +373,7 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 
 		if output.Err != nil {
 			tfOptions.Logger.WithError(output.Err).Error("Error unmarshalling terraform show")
-			return output.Err
+			return retryIfTransient(tfOptions, "", output.Err, &output)
 		}
 		// aws_cloudtrail.central_logging_trail, aws_cloudtrail, central_logging_trail: [no-op]
 
@@ -286,6 +388,25 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 
 			tfOptions.Logger.Info(fmt.Sprintf("%s, %s, %s: %s", c.Address, c.Type, c.Name, c.Change.Actions))
 		}
+
+		output.PlannedChanges = map[string][]string{}
+		for action, addresses := range resourceChangesByAction {
+			if action != "[no-op]" && action != "[read]" {
+				output.PlannedChanges[action] = addresses
+			}
+		}
+
+		if exec.Action == config.PlanAction && !destroy {
+			var entry planbundle.Entry
+			entry, output.Err = planbundle.SavePlan(exec.Fs, exec.PlanBundleDir, exec, filepath.Join(exec.Dir, tfplan), []byte(resp), sourceChecksum, getInputsChecksum(exec))
+
+			if output.Err != nil {
+				tfOptions.Logger.WithError(output.Err).Error("Error saving plan to plan bundle")
+				return retryIfTransient(tfOptions, "", output.Err, &output)
+			}
+
+			tfOptions.Logger.Infof("Saved plan to plan bundle: %s", entry.PlanFile)
+		}
 		applyChanges := true
 		//noChanges := len(resourceChangesByAction["[no-op]"]) == len(plan.ResourceChanges)
 
@@ -306,17 +427,17 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 			resp, output.Err = terraformer.Apply(baseOptions, tfplan)
 
 			if output.Err != nil {
-				baseOptions.Logger.WithError(output.Err).Error("Error running terraform apply")
-				return output.Err
+				withStateLockError(baseOptions.Logger, output.Err).WithError(output.Err).Error("Error running terraform apply")
+				return retryIfTransient(baseOptions, resp, output.Err, &output)
 			}
 		}
 
 		// parse terraform output
-		baseOptions, output.Err = getCommonTfOptions2(exec)
+		baseOptions, output.Err = getCommonTfOptions2(exec, stepConfig)
 
 		if output.Err != nil {
-			retryLogger.WithError(output.Err).Error("unable to retrieve credentials for terraform output")
-			return output.Err
+			retryLogger.WithError(output.Err).Error("Error resolving the terraform binary for terraform output")
+			return retryIfTransient(tfOptions, "", output.Err, &output)
 		}
 
 		baseOptions.Logger = retryLogger.WithField("terraform", "output")
@@ -335,6 +456,296 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 	return
 }
 
+// applyTerraformPlanBundleInDir applies the plan saved for a step in the plan bundle, refusing when the step's working tree
+// or inputs changed since it was planned
+var applyTerraformPlanBundleInDir = func(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail // assume failure
+	var tfOptions *terraform.Options
+	var sourceChecksum string
+
+	// checksum the working tree before terraform init adds to it
+	sourceChecksum, output.Err = getSourceChecksum(exec)
+
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error calculating step checksum for plan bundle")
+		return
+	}
+
+	var stepConfig StepConfig
+	stepConfig, output.Err = ReadStepConfig(exec)
+
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error reading terraform step configuration")
+		return
+	}
+
+	// terraform init and workspace
+	tfOptions, output.Err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	if output.Err != nil {
+		return
+	}
+
+	tfplan := fmt.Sprintf("%s%s%stfplan", exec.StepName, exec.RegionDeployType, exec.Region)
+
+	var entry planbundle.Entry
+	entry, output.Err = planbundle.LoadPlan(exec.Fs, exec.PlanBundleDir, exec, filepath.Join(exec.Dir, tfplan), sourceChecksum, getInputsChecksum(exec))
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("Refusing to apply plan from plan bundle")
+		return
+	}
+
+	// a saved plan cannot be applied again after a partial apply, so it is not retried
+	tfOptions, output.Err = getCommonTfOptions2(exec, stepConfig)
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("Error resolving the terraform binary for terraform apply")
+		return
+	}
+
+	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "apply")
+	tfOptions.Logger.Infof("Applying plan from plan bundle: %s", entry.PlanFile)
+
+	var resp string
+	resp, output.Err = terraformer.Apply(tfOptions, tfplan)
+
+	if output.Err != nil {
+		classifyError(tfOptions, resp, output.Err, &output)
+		withStateLockError(tfOptions.Logger, output.Err).WithError(output.Err).Error("Error running terraform apply")
+		return
+	}
+
+	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "output")
+	output.OutputVariables, output.Err = terraformer.OutputAll(tfOptions)
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("Error running terraform output")
+	}
+
+	output.Status = config.Success
+
+	return
+}
+
+// getSourceChecksum returns a checksum of a step's working tree, ignoring files created by terraform and runiac during execution
+func getSourceChecksum(exec config.StepExecution) (string, error) {
+	return planbundle.ChecksumDir(exec.Fs, exec.Dir, func(relPath string, info os.FileInfo) bool {
+		if info.IsDir() {
+			return info.Name() == ".terraform" || info.Name() == ".temp" || strings.HasPrefix(info.Name(), "regional-")
+		}
+
+		return info.Name() == ".terraform.lock.hcl" || strings.HasSuffix(info.Name(), "tfplan") || strings.HasSuffix(info.Name(), "tfdriftplan")
+	})
+}
+
+// getInputsChecksum returns a checksum of the variables passed to terraform for a step, including upstream step outputs
+func getInputsChecksum(exec config.StepExecution) string {
+	inputs := map[string]string{}
+
+	for k, v := range GetTerraformEnvVars(exec) {
+		inputs[fmt.Sprintf("TF_VAR_%s", k)] = v
+	}
+
+	for k, v := range GetTerraformCLIVars(exec) {
+		inputs[k] = fmt.Sprintf("%v", v)
+	}
+
+	return planbundle.ChecksumInputs(inputs)
+}
+
+// executeTerraformDriftInDir runs a refresh-only plan for a step and reports the resources that drifted without applying
+var executeTerraformDriftInDir = func(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail // assume failure
+	var resp string
+	var tfOptions *terraform.Options
+
+	var stepConfig StepConfig
+	stepConfig, output.Err = ReadStepConfig(exec)
+
+	if output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error reading terraform step configuration")
+		return
+	}
+
+	// terraform init and workspace
+	tfOptions, output.Err = initTerraformWorkspace(exec, stepConfig, &output)
+
+	if output.Err != nil {
+		return
+	}
+
+	_ = retry.Do(runContext(exec), "terraform refresh-only plan", retryOptions(tfOptions.MaxRetries, tfOptions.Logger), func(attempt int) error {
+		retryLogger := tfOptions.Logger.WithField("retryCount", attempt)
+		output.ErrorClass = nil
+
+		tfplan := fmt.Sprintf("%s%s%stfdriftplan", exec.StepName, exec.RegionDeployType, exec.Region)
+
+		// terraform plan
+		tfOptions, output.Err = getCommonTfOptions2(exec, stepConfig)
+
+		if output.Err != nil {
+			tfOptions.Logger.WithError(output.Err).Error("Error resolving the terraform binary for terraform refresh-only plan")
+			return retryIfTransient(tfOptions, "", output.Err, &output)
+		}
+
+		tfOptions.Logger = retryLogger.WithField("terraform", "plan")
+
+		// Set all step parameters as terraform env variables
+		for k, v := range GetTerraformEnvVars(exec) {
+			tfOptions.Logger.Debugf("Adding parameter to TF_VARs: %s", k)
+			tfOptions.EnvVars[fmt.Sprintf("TF_VAR_%s", k)] = v
+		}
+
+		tfOptions.Vars = GetTerraformCLIVars(exec)
+		tfOptions.VarFiles = GetVarFiles(exec, stepConfig)
+
+		var changes bool
+		changes, resp, output.Err = terraformer.PlanRefreshOnly(tfOptions, tfplan)
+
+		if output.Err != nil {
+			withStateLockError(tfOptions.Logger, output.Err).WithError(output.Err).Error("Error running terraform refresh-only plan")
+			return retryIfTransient(tfOptions, resp, output.Err, &output)
+		}
+
+		output.Drift = &config.StepDrift{}
+
+		if changes {
+			// new options to reset variables
+			baseOptions, err := getCommonTfOptions2(exec, stepConfig)
+
+			if err != nil {
+				output.Err = err
+				retryLogger.WithError(output.Err).Error("Error resolving the terraform binary for terraform show")
+				return retryIfTransient(baseOptions, "", output.Err, &output)
+			}
+
+			baseOptions.Logger = retryLogger.WithField("terraform", "show")
+			resp, output.Err = terraformer.Show(baseOptions, tfplan)
+
+			if output.Err != nil {
+				baseOptions.Logger.WithError(output.Err).Errorf("Error during terraform show:\n%s", resp)
+				return retryIfTransient(baseOptions, resp, output.Err, &output)
+			}
+
+			plan := plan{}
+			output.Err = json.Unmarshal([]byte(resp), &plan)
+
+			if output.Err != nil {
+				tfOptions.Logger.WithError(output.Err).Error("Error unmarshalling terraform show")
+				return retryIfTransient(tfOptions, "", output.Err, &output)
+			}
+
+			output.Drift = getStepDrift(plan)
+
+			for _, r := range output.Drift.Resources {
+				tfOptions.Logger.Warn(fmt.Sprintf("Drift detected %s: %s", r.Address, r.Actions))
+			}
+		}
+
+		// parse terraform output from state for downstream steps
+		tfOptions.Logger = retryLogger.WithField("terraform", "output")
+
+		output.OutputVariables, output.Err = terraformer.OutputAll(tfOptions)
+
+		if output.Err != nil {
+			tfOptions.Logger.WithError(output.Err).Error("Error running terraform output")
+		}
+
+		output.Status = config.Success
+
+		return nil
+	})
+
+	return
+}
+
+// getStepDrift summarizes the drifted resources of a refresh-only plan
+func getStepDrift(p plan) *config.StepDrift {
+	drift := &config.StepDrift{}
+
+	for _, c := range p.ResourceDrift {
+		if c.Mode == "data" || contains(c.Change.Actions, "no-op") || contains(c.Change.Actions, "read") {
+			continue
+		}
+
+		severity := config.DriftChanged
+		if contains(c.Change.Actions, "delete") {
+			severity = config.DriftDeleted
+		}
+
+		drift.Add(config.DriftedResource{
+			Address:  c.Address,
+			Actions:  c.Change.Actions,
+			Severity: severity,
+		})
+	}
+
+	return drift
+}
+
+// initTerraformWorkspace runs terraform init, retrying transient errors, and selects the step's workspace, returning
+// the options used. The classification of an init error is recorded in the step output.
+func initTerraformWorkspace(exec config.StepExecution, stepConfig StepConfig, output *config.StepOutput) (tfOptions *terraform.Options, err error) {
+	tfOptions, err = getCommonTfOptions2(exec, stepConfig)
+
+	if err != nil {
+		tfOptions.Logger.WithError(err).Error("Error resolving the terraform binary for terraform init")
+		return
+	}
+
+	tfOptions.BackendConfig = GetBackendConfig(exec, ParseTFBackend).Config
+	initLogger := tfOptions.Logger.WithField("terraform", "init")
+
+	_ = retry.Do(runContext(exec), "terraform init", retryOptions(tfOptions.MaxRetries, initLogger), func(attempt int) error {
+		tfOptions.Logger = initLogger.WithField("retryCount", attempt)
+
+		var resp string
+		resp, err = terraformer.Init(tfOptions)
+
+		if err != nil {
+			tfOptions.Logger.WithError(err).Error("Error during terraform init")
+			return retryIfTransient(tfOptions, resp, err, output)
+		}
+
+		output.ErrorClass = nil
+
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	tfOptions.Logger = initLogger.WithField("terraform", "workspace")
+
+	_, err = terraformer.WorkspaceSelect(tfOptions, getWorkspace(exec))
+
+	if err != nil {
+		tfOptions.Logger.WithError(err).Error("Error during terraform workspace select")
+		return
+	}
+
+	return
+}
+
+// getWorkspace returns the terraform workspace of a step's region, prefixed with the namespace when set
+func getWorkspace(exec config.StepExecution) string {
+	workspace := fmt.Sprintf("%s-%s", exec.RegionDeployType.String(), exec.Region)
+
+	if exec.Namespace != "" {
+		workspace = fmt.Sprintf("%s-%s", exec.Namespace, workspace)
+	}
+
+	return workspace
+}
+
 // GetBackendConfig parses a backend.tf file
 // TODO, replace this with a cleaner hcl2json2struct merge where backend.tf configurations take priority over defined defaults here
 func GetBackendConfig(exec config.StepExecution, backendParser TFBackendParser) TerraformBackend {
@@ -507,16 +918,23 @@ func interpolateString(exec config.StepExecution, s string) string {
 	return s
 }
 
-func getCommonTfOptions2(exec config.StepExecution) (tfOptions *terraform.Options, err error) {
+// getCommonTfOptions2 returns the options every terraform command of a step runs with, resolving the binary of the
+// step's configuration
+func getCommonTfOptions2(exec config.StepExecution, stepConfig StepConfig) (tfOptions *terraform.Options, err error) {
 	tfOptions = &terraform.Options{
-		TerraformDir:             exec.Dir,
-		EnvVars:                  map[string]string{},
-		Logger:                   exec.Logger,
-		NoColor:                  true,
-		RetryableTerraformErrors: map[string]string{".*": "General Terraform error occurred."},
-		MaxRetries:               exec.MaxRetries,
-		TimeBetweenRetries:       5 * time.Second,
+		TerraformBinary:    TerraformBinary,
+		TerraformDir:       exec.Dir,
+		EnvVars:            map[string]string{},
+		Logger:             exec.Logger,
+		NoColor:            true,
+		MaxRetries:         exec.MaxRetries,
+		TimeBetweenRetries: 5 * time.Second,
 	}
 
+	tfOptions.TerraformBinary, err = ResolveBinary(exec.Logger, stepConfig.Binary, stepConfig.Version, stepConfig.CacheDir)
+	tfOptions.RetryableTerraformErrors = toTerraformErrors(stepConfig.RetryableErrors)
+	tfOptions.PermanentTerraformErrors = toTerraformErrors(stepConfig.PermanentErrors)
+	tfOptions.LockTimeout = stepConfig.LockTimeout
+
 	return
 }
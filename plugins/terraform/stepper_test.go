@@ -1,11 +1,16 @@
 package plugins_terraform
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -38,6 +43,38 @@ func TestGetTerraformEnvArgs_ShouldIncludeEnvironment(t *testing.T) {
 	require.Equal(t, "fun", vars["runiac_environment"])
 }
 
+func TestGetStepDrift_ShouldReportHighestSeverity(t *testing.T) {
+	t.Parallel()
+
+	stubPlan := plan{}
+	err := json.Unmarshal([]byte(`{
+		"resource_drift": [
+			{"address": "azurerm_resource_group.rg", "mode": "managed", "change": {"actions": ["update"]}},
+			{"address": "azurerm_storage_account.sa", "mode": "managed", "change": {"actions": ["delete"]}},
+			{"address": "data.azurerm_client_config.current", "mode": "data", "change": {"actions": ["update"]}},
+			{"address": "azurerm_key_vault.kv", "mode": "managed", "change": {"actions": ["no-op"]}}
+		]
+	}`), &stubPlan)
+	require.NoError(t, err)
+
+	drift := getStepDrift(stubPlan)
+
+	require.Equal(t, config.DriftDeleted, drift.Severity)
+	require.Len(t, drift.Resources, 2, "Data sources and no-op changes should not be reported as drift")
+	require.Equal(t, "azurerm_resource_group.rg", drift.Resources[0].Address)
+	require.Equal(t, config.DriftChanged, drift.Resources[0].Severity)
+	require.Equal(t, config.DriftDeleted, drift.Resources[1].Severity)
+}
+
+func TestGetStepDrift_ShouldReportNoDriftWhenEmpty(t *testing.T) {
+	t.Parallel()
+
+	drift := getStepDrift(plan{})
+
+	require.Equal(t, config.DriftNone, drift.Severity)
+	require.Empty(t, drift.Resources)
+}
+
 func TestGetBackendConfig_ShouldParseAssumeRoleCoreAccountIDMapCorrectly(t *testing.T) {
 	t.Parallel()
 	fs := afero.NewMemMapFs()
@@ -557,3 +594,56 @@ func TestStringToBackendType(t *testing.T) {
 		require.Equal(t, tc.errorExists, err != nil, "The error result should match the expected")
 	}
 }
+
+type stubTestTerraformer struct {
+	terraform.Terraform
+	options   *terraform.Options
+	junitFile string
+}
+
+func (s *stubTestTerraformer) Test(options *terraform.Options, junitFile string) (string, error) {
+	s.options = options
+	s.junitFile = junitFile
+	return "native", nil
+}
+
+func TestExecuteStepTests_ShouldRunGoAndNativeTests(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tests"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tests", "bucket_test.go"), []byte("package tests"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tests", "bucket.tftest.hcl"), []byte{}, 0644))
+
+	commands := []shell.Command{}
+	runTests = func(cmd shell.Command) (string, error) {
+		commands = append(commands, cmd)
+		return "go", nil
+	}
+	stub := &stubTestTerraformer{}
+	terraformer = stub
+	defer func() {
+		runTests = shell.RunShellCommandAndGetAndStreamOutput
+		terraformer = terraform.Terraform{}
+	}()
+
+	output := TerraformStepper{}.ExecuteStepTests(config.StepExecution{
+		Logger:             logger,
+		Fs:                 afero.NewOsFs(),
+		Dir:                dir,
+		Project:            "runiac",
+		TrackName:          "core",
+		StepName:           "bucket",
+		Region:             "centralus",
+		RegionDeployType:   config.PrimaryRegionDeployType,
+		OptionalStepParams: map[string]string{"network-vnet_id": "vnet"},
+	})
+
+	require.NoError(t, output.Err)
+	require.Equal(t, "go\nnative", output.StreamOutput)
+
+	require.Len(t, commands, 1)
+	require.Equal(t, []string{"--format", "standard-verbose", "--junitfile", "/output/junit/runiac-core-bucket-primary-centralus.xml", "--", "-v", "-count=1", "."}, commands[0].Args)
+	require.Equal(t, "vnet", commands[0].Env["TF_VAR_network-vnet_id"])
+
+	require.Equal(t, "/output/junit/runiac-core-bucket-primary-centralus-tftest.xml", stub.junitFile)
+	require.Equal(t, commands[0].Env, stub.options.EnvVars, "Native tests should receive the same TF_VAR_ environment")
+}
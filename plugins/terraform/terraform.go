@@ -180,10 +180,13 @@ type plan struct {
 	//PlannedValues    stateValues `json:"planned_values,omitempty"`
 	// ResourceChanges are sorted in a user-friendly order that is undefined at
 	// this time, but consistent.
-	ResourceChanges []resourceChange  `json:"resource_changes,omitempty"`
-	OutputChanges   map[string]change `json:"output_changes,omitempty"`
-	PriorState      json.RawMessage   `json:"prior_state,omitempty"`
-	Config          json.RawMessage   `json:"configuration,omitempty"`
+	ResourceChanges []resourceChange `json:"resource_changes,omitempty"`
+	// ResourceDrift are the changes terraform detected between the prior state
+	// and the real resources while refreshing.
+	ResourceDrift []resourceChange  `json:"resource_drift,omitempty"`
+	OutputChanges map[string]change `json:"output_changes,omitempty"`
+	PriorState    json.RawMessage   `json:"prior_state,omitempty"`
+	Config        json.RawMessage   `json:"configuration,omitempty"`
 }
 
 // resourceChange is a description of an individual change action that Terraform
@@ -0,0 +1,49 @@
+package plugins_terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+)
+
+// GetDefaultVarFiles returns the var files applied to steps that do not configure their own, from lowest to highest
+// precedence: common.tfvars, env_<environment>.tfvars, ring_<ring>.tfvars and region_<region>.tfvars
+func GetDefaultVarFiles(exec config.StepExecution) []string {
+	return []string{
+		"common.tfvars",
+		fmt.Sprintf("env_%s.tfvars", strings.ToLower(exec.Environment)),
+		fmt.Sprintf("ring_%s.tfvars", strings.ToLower(exec.DeploymentRing)),
+		fmt.Sprintf("region_%s.tfvars", strings.ToLower(exec.Region)),
+	}
+}
+
+// GetVarFiles returns the var files of a step that exist, in the order they are passed to terraform, so later files
+// take precedence over earlier ones
+func GetVarFiles(exec config.StepExecution, stepConfig StepConfig) []string {
+	candidates := GetDefaultVarFiles(exec)
+	if len(stepConfig.VarFiles) > 0 {
+		candidates = []string{}
+		for _, file := range stepConfig.VarFiles {
+			candidates = append(candidates, interpolateString(exec, file))
+		}
+	}
+
+	varFiles := []string{}
+	for _, file := range candidates {
+		exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, file))
+		if !exists {
+			if len(stepConfig.VarFiles) > 0 {
+				exec.Logger.Warnf("Var file %s does not exist, skipping it", file)
+			}
+			continue
+		}
+
+		exec.Logger.Infof("Applying var file %s", file)
+		varFiles = append(varFiles, file)
+	}
+
+	return varFiles
+}
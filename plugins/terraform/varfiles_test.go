@@ -0,0 +1,71 @@
+package plugins_terraform
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func stubVarFilesExecution(fs afero.Fs, files ...string) config.StepExecution {
+	for _, file := range files {
+		_ = afero.WriteFile(fs, filepath.Join("/steps/network", file), []byte{}, 0644)
+	}
+
+	return config.StepExecution{
+		Logger:         logger,
+		Fs:             fs,
+		Dir:            "/steps/network",
+		Environment:    "Prod",
+		DeploymentRing: "Canary",
+		Region:         "centralus",
+	}
+}
+
+func TestGetVarFiles_ShouldApplyPresentDefaultsInPrecedenceOrder(t *testing.T) {
+	t.Parallel()
+
+	exec := stubVarFilesExecution(afero.NewMemMapFs(), "region_centralus.tfvars", "ring_canary.tfvars", "common.tfvars", "env_dev.tfvars")
+
+	stepConfig, err := ReadStepConfig(exec)
+	require.NoError(t, err)
+
+	varFiles := GetVarFiles(exec, stepConfig)
+
+	require.Equal(t, []string{"common.tfvars", "ring_canary.tfvars", "region_centralus.tfvars"}, varFiles)
+}
+
+func TestGetVarFiles_ShouldUseStepConfiguredVarFiles(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/steps/network/runiac.yml", []byte(`terraform:
+  var_files:
+    - vars/${var.runiac_environment}.tfvars
+    - vars/missing.tfvars
+    - vars/${var.runiac_region}.tfvars
+`), 0644))
+
+	exec := stubVarFilesExecution(fs, "common.tfvars", "vars/Prod.tfvars", "vars/centralus.tfvars")
+
+	stepConfig, err := ReadStepConfig(exec)
+	require.NoError(t, err)
+
+	varFiles := GetVarFiles(exec, stepConfig)
+
+	require.Equal(t, []string{"vars/Prod.tfvars", "vars/centralus.tfvars"}, varFiles)
+}
+
+func TestGetVarFiles_ShouldBeEmptyWithoutVarFiles(t *testing.T) {
+	t.Parallel()
+
+	exec := stubVarFilesExecution(afero.NewMemMapFs())
+	stepConfig, err := ReadStepConfig(exec)
+	require.NoError(t, err)
+
+	varFiles := GetVarFiles(exec, stepConfig)
+
+	require.Empty(t, varFiles)
+}